@@ -1,23 +1,49 @@
 package main
 
 import (
+	"context"
+	"io"
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
+	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 
+	"github.com/stasBigunenko/monorepa/model"
 	pb "github.com/stasBigunenko/monorepa/pkg/accountGRPC/proto"
 	accountgrpcserver "github.com/stasBigunenko/monorepa/pkg/accountGRPC/server"
+	pbaudit "github.com/stasBigunenko/monorepa/pkg/auditGRPC/proto"
+	auditgrpcserver "github.com/stasBigunenko/monorepa/pkg/auditGRPC/server"
 	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	usercontroller "github.com/stasBigunenko/monorepa/pkg/userGRPC/controller"
+	pbusers "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
 	"github.com/stasBigunenko/monorepa/service/account"
+	"github.com/stasBigunenko/monorepa/service/account/currency"
+	"github.com/stasBigunenko/monorepa/service/audit"
+	"github.com/stasBigunenko/monorepa/service/health"
+	"github.com/stasBigunenko/monorepa/service/interceptor"
 	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
+	"github.com/stasBigunenko/monorepa/service/tracing"
 )
 
 type Config struct {
 	accountGRPCServAddress string
+	userGRPCAddress        string
+	storageType            string
+	storageDSN             string
+	auditType              string
+	auditDSN               string
+	onePerCurrency         bool
+	exchangeRatesFile      string
+	logLevel               string
+	logFormat              string
+	traceExporter          string
+	metricsAddress         string
 }
 
 func getConfig() Config {
@@ -26,41 +52,128 @@ func getConfig() Config {
 		accountGrpcServAddr = "127.0.0.1:50053"
 	}
 
-	return Config{
-		accountGRPCServAddress: accountGrpcServAddr,
+	userGrpcAddr := os.Getenv("GRPC_USERS_ADDRESS")
+	if userGrpcAddr == "" {
+		userGrpcAddr = "127.0.0.1:50052"
+	}
+
+	// a malformed value keeps the default of any number of accounts per currency
+	onePerCurrency, _ := strconv.ParseBool(os.Getenv("ONE_ACCOUNT_PER_CURRENCY"))
+
+	storageType := os.Getenv("STORAGE_TYPE")
+	if storageType == "" {
+		storageType = newStorage.MemoryStorage
+	}
+
+	// AUDIT_STORAGE_DSN keeps the audit log in postgres also when the rest is in memory
+	auditType, auditDSN := storageType, os.Getenv("STORAGE_DSN")
+	if dsn := os.Getenv("AUDIT_STORAGE_DSN"); dsn != "" {
+		auditType, auditDSN = newStorage.PostgresStorage, dsn
 	}
-}
 
-func init() {
-	// Log as JSON instead of the default ASCII formatter.
-	log.SetFormatter(&log.JSONFormatter{})
+	metricsAddr := os.Getenv("METRICS_ADDRESS")
+	if metricsAddr == "" {
+		metricsAddr = "127.0.0.1:9093"
+	}
 
-	// Output to stdout instead of the default stderr
-	// Can be any io.Writer, see below for File example
-	log.SetOutput(os.Stdout)
+	return Config{
+		accountGRPCServAddress: accountGrpcServAddr,
+		userGRPCAddress:        userGrpcAddr,
+		storageType:            storageType,
+		storageDSN:             os.Getenv("STORAGE_DSN"),
+		auditType:              auditType,
+		auditDSN:               auditDSN,
+		onePerCurrency:         onePerCurrency,
+		exchangeRatesFile:      os.Getenv("EXCHANGE_RATES_FILE"),
+		logLevel:               os.Getenv("LOG_LEVEL"),
+		logFormat:              os.Getenv("LOG_FORMAT"),
+		traceExporter:          os.Getenv("OTEL_TRACES_EXPORTER"),
+		metricsAddress:         metricsAddr,
+	}
 }
 
 func main() {
 	config := getConfig()
+	logger := loggingservice.New(loggingservice.Config{Service: "account", Level: config.logLevel, Format: config.logFormat})
+
+	// without OTEL_TRACES_EXPORTER traces are passed on but not exported
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{Service: "account", Exporter: config.traceExporter})
+	if err != nil {
+		log.Fatal("failed to set up tracing: ", err)
+	}
+	defer shutdownTracing(context.Background()) //nolint:errcheck
+
+	reg := metrics.NewRegistry()
 
 	lis, err := net.Listen("tcp", config.accountGRPCServAddress)
 	if err != nil {
 		log.Fatal("failed to listen: ", err)
 	}
 
-	loggingService := loggingservice.New()
+	dbInt, err := newStorage.NewAccountRepository(config.storageType, config.storageDSN, logger)
+	if err != nil {
+		log.Fatal("failed to open storage: ", err)
+	}
+	if closer, ok := dbInt.(io.Closer); ok {
+		defer closer.Close() //nolint:errcheck
+	}
+
+	auditLog, err := newStorage.NewAuditLog(config.auditType, config.auditDSN, logger)
+	if err != nil {
+		log.Fatal("failed to open audit log: ", err)
+	}
+	if config.auditType != newStorage.PostgresStorage {
+		logger.Warn(context.Background(), "the audit log is kept in memory and lost on restart")
+	}
+	if closer, ok := auditLog.(io.Closer); ok {
+		defer closer.Close() //nolint:errcheck
+	}
+	metrics.RegisterStore(reg, "accounts", dbInt)
+	metrics.RegisterStore(reg, "audit", auditLog)
+	auditRecorder := audit.NewRecorder(auditLog, model.AuditResourceAccount, logger, audit.WithMetrics(reg))
 
-	db := newStorage.NewDB(loggingService)
-	dbInt := newStorage.NewStore(db)
-	asi := account.NewAccService(dbInt, loggingService)
+	// owners of new accounts are checked against the user service
+	connUser, err := grpc.Dial(config.userGRPCAddress, append(interceptor.ClientOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		log.Fatal("did not connect to grpc: ", err)
+	}
+	defer connUser.Close()
+	userService := usercontroller.New(pbusers.NewUserGRPCServiceClient(connUser), logger)
+
+	opts := []account.Option{account.WithAudit(auditRecorder)}
+	if config.onePerCurrency {
+		opts = append(opts, account.OneAccountPerCurrency())
+	}
+	// without a rate table only accounts in the same currency can transfer to each other
+	if config.exchangeRatesFile != "" {
+		rates, err := currency.LoadRates(config.exchangeRatesFile)
+		if err != nil {
+			log.Fatal("failed to load exchange rates: ", err)
+		}
+		opts = append(opts, account.WithExchangeRates(rates))
+	}
+	asi := account.NewAccService(dbInt, userService, logger, opts...)
+
+	s := grpc.NewServer(interceptor.ServerOptions(logger, metrics.NewGRPCMetrics(reg))...)
+	pb.RegisterAccountGRPCServiceServer(s, accountgrpcserver.NewAccountGRPCServer(asi, logger))
+	pbaudit.RegisterAuditGRPCServiceServer(s, auditgrpcserver.NewAuditGRPCServer(auditRecorder, logger))
+	healthServer := health.NewGRPCServer(s, pb.AccountGRPCService_ServiceDesc.ServiceName, pbaudit.AuditGRPCService_ServiceDesc.ServiceName)
 
-	s := grpc.NewServer()
-	pb.RegisterAccountGRPCServiceServer(s, accountgrpcserver.NewAccountGRPCServer(asi, loggingService))
+	// the metrics are scraped from their own listener, the gRPC port speaks gRPC only
+	metricsCtx, stopMetrics := context.WithCancel(context.Background())
+	defer stopMetrics()
+	go func() {
+		if err := metrics.Serve(metricsCtx, config.metricsAddress, reg); err != nil {
+			log.Error("error: metrics server failed: ", err)
+		}
+	}()
 
 	sigC := make(chan os.Signal, 1)
 	defer close(sigC)
 	go func() {
 		<-sigC
+		// callers checking health stop sending requests while the running ones finish
+		healthServer.Shutdown()
 		s.GracefulStop()
 	}()
 	signal.Notify(sigC, syscall.SIGINT, syscall.SIGTERM)
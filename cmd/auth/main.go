@@ -3,23 +3,27 @@ package main
 import (
 	"context"
 	"flag"
+	"io"
 	"os"
 	"os/signal"
 
+	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 
 	"github.com/stasBigunenko/monorepa/pkg/auth"
+	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	userscontroller "github.com/stasBigunenko/monorepa/pkg/userGRPC/controller"
+	pbusers "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
+	authService "github.com/stasBigunenko/monorepa/service/auth"
+	"github.com/stasBigunenko/monorepa/service/auth/jwt"
+	"github.com/stasBigunenko/monorepa/service/health"
+	"github.com/stasBigunenko/monorepa/service/interceptor"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
+	"github.com/stasBigunenko/monorepa/service/tracing"
 )
 
-func init() {
-	// Log as JSON instead of the default ASCII formatter.
-	log.SetFormatter(&log.JSONFormatter{})
-
-	// Output to stdout instead of the default stderr
-	// Can be any io.Writer, see below for File example
-	log.SetOutput(os.Stdout)
-}
-
 func defaultEnvConfig() {
 	// serever
 	os.Setenv("SERVER_HOST", "")
@@ -29,11 +33,16 @@ func defaultEnvConfig() {
 	os.Setenv("Server_Cancel_Timeout", "5")
 
 	// JWT config
-	os.Setenv("TOKEN_EXPIRE", "10") // minutes
+	os.Setenv("TOKEN_EXPIRE", "10")            // minutes
+	os.Setenv("REFRESH_TOKEN_EXPIRE", "43200") // minutes
 
 	// certificates
 	os.Setenv("CERT_VERSION", "1")
 	os.Setenv("CERT_PATH", "./pkg/storage/certificates")
+
+	// credentials
+	os.Setenv("GRPC_USERS_ADDRESS", "127.0.0.1:50052")
+	os.Setenv("STORAGE_TYPE", "memory")
 }
 
 func main() {
@@ -49,15 +58,60 @@ func main() {
 	if *addEnv {
 		defaultEnvConfig()
 	}
+	logger := loggingservice.New(loggingservice.Config{Service: "auth", Level: os.Getenv("LOG_LEVEL"), Format: os.Getenv("LOG_FORMAT")})
+
+	// without OTEL_TRACES_EXPORTER traces are passed on but not exported
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{Service: "auth", Exporter: os.Getenv("OTEL_TRACES_EXPORTER")})
+	if err != nil {
+		log.Fatal("failed to set up tracing: ", err)
+	}
+	defer shutdownTracing(context.Background()) //nolint:errcheck
 
 	// init server and config
-	server := auth.New(ctx)
+	server := auth.New(ctx, logger)
+	reg := metrics.NewRegistry()
+	server.Instrument(metrics.NewHTTPMetrics(reg), reg)
 	if err := server.ServerAddrConfig(); err != nil {
 		log.Fatal("Can`t get config of server: ", err)
 	}
 
+	// users are created in the user service on registration
+	connUser, err := grpc.Dial(os.Getenv("GRPC_USERS_ADDRESS"), append(interceptor.ClientOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		log.Fatal("did not connect to grpc: ", err)
+	}
+	defer connUser.Close()
+
+	userService := userscontroller.New(pbusers.NewUserGRPCServiceClient(connUser), logger)
+
+	store, err := newStorage.NewAuthStore(os.Getenv("STORAGE_TYPE"), os.Getenv("STORAGE_DSN"), logger)
+	if err != nil {
+		log.Fatal("Can`t open credential storage: ", err)
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close() //nolint:errcheck
+	}
+	metrics.RegisterStore(reg, "auth", store)
+
+	// all key versions are loaded once, rotation happens through the admin endpoints
+	jwtConf, err := jwt.NewJTWConfig()
+	if err != nil {
+		log.Fatal("Can`t get jwt config: ", err)
+	}
+
+	keys, err := jwt.NewKeyManager(jwtConf)
+	if err != nil {
+		log.Fatal("Can`t load signing keys: ", err)
+	}
+
+	// auth is ready while it can sign tokens and create users on registration
+	checker := health.NewChecker(health.DefaultTimeout)
+	checker.Add("users", health.GRPC(connUser, pbusers.UserGRPCService_ServiceDesc.ServiceName))
+	checker.Add("keys", func(context.Context) error { return keys.Check() })
+	server.Health(checker)
+
 	// add all routers endpoints
-	server.GetRouters()
+	server.GetRouters(authService.New(store, userService, keys, jwtConf.RefreshTokenLife(), authService.WithMetrics(reg)), os.Getenv("ADMIN_TOKEN"))
 
 	// create shutdown
 	ctx, cancel := context.WithCancel(ctx)
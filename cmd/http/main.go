@@ -6,16 +6,25 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 
+	"github.com/stasBigunenko/monorepa/model"
 	accountscontroller "github.com/stasBigunenko/monorepa/pkg/accountGRPC/controller"
 	pbaccounts "github.com/stasBigunenko/monorepa/pkg/accountGRPC/proto"
+	auditcontroller "github.com/stasBigunenko/monorepa/pkg/auditGRPC/controller"
+	pbaudit "github.com/stasBigunenko/monorepa/pkg/auditGRPC/proto"
 	httphandler "github.com/stasBigunenko/monorepa/pkg/http/handler"
 	userscontroller "github.com/stasBigunenko/monorepa/pkg/userGRPC/controller"
 	pbusers "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
+	"github.com/stasBigunenko/monorepa/service/health"
+	httpservice "github.com/stasBigunenko/monorepa/service/http"
+	"github.com/stasBigunenko/monorepa/service/interceptor"
 	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
+	"github.com/stasBigunenko/monorepa/service/tracing"
 )
 
 type Config struct {
@@ -23,6 +32,11 @@ type Config struct {
 	JWTAddress         string
 	GRPCAccountAddress string
 	GRPCUserAddress    string
+	IdempotencyTTL     time.Duration
+	LogLevel           string
+	LogFormat          string
+	TraceExporter      string
+	AdminToken         string
 }
 
 func getCfg() Config {
@@ -46,49 +60,80 @@ func getCfg() Config {
 		grpcUserAddr = "127.0.0.1:50052"
 	}
 
+	// a malformed value keeps the default
+	idempotencyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	if err != nil || idempotencyTTL <= 0 {
+		idempotencyTTL = httpservice.DefaultIdempotencyTTL
+	}
+
 	return Config{
 		HTTPAddress:        httpAddr,
 		JWTAddress:         jwtAddr,
 		GRPCAccountAddress: grpcAccAddr,
 		GRPCUserAddress:    grpcUserAddr,
+		IdempotencyTTL:     idempotencyTTL,
+		LogLevel:           os.Getenv("LOG_LEVEL"),
+		LogFormat:          os.Getenv("LOG_FORMAT"),
+		TraceExporter:      os.Getenv("OTEL_TRACES_EXPORTER"),
+		AdminToken:         os.Getenv("ADMIN_TOKEN"),
 	}
 }
 
-func init() {
-	// Log as JSON instead of the default ASCII formatter.
-	log.SetFormatter(&log.JSONFormatter{})
-
-	// Output to stdout instead of the default stderr
-	// Can be any io.Writer, see below for File example
-	log.SetOutput(os.Stdout)
-}
-
 func main() {
 	cfg := getCfg()
+	logger := loggingservice.New(loggingservice.Config{Service: "http", Level: cfg.LogLevel, Format: cfg.LogFormat})
 
-	connAcc, err := grpc.Dial(cfg.GRPCAccountAddress, grpc.WithInsecure())
+	// without OTEL_TRACES_EXPORTER traces are passed on but not exported
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{Service: "http", Exporter: cfg.TraceExporter})
+	if err != nil {
+		log.Fatal("failed to set up tracing: ", err)
+	}
+	defer shutdownTracing(context.Background()) //nolint:errcheck
+
+	connAcc, err := grpc.Dial(cfg.GRPCAccountAddress, append(interceptor.ClientOptions(), grpc.WithInsecure())...)
 	if err != nil {
 		log.Info("did not connect to grpc: ", err)
 		return
 	}
 	defer connAcc.Close()
 
-	connUser, err := grpc.Dial(cfg.GRPCUserAddress, grpc.WithInsecure())
+	connUser, err := grpc.Dial(cfg.GRPCUserAddress, append(interceptor.ClientOptions(), grpc.WithInsecure())...)
 	if err != nil {
 		log.Info("did not connect to grpc: ", err)
 		return
 	}
 	defer connUser.Close()
 
-	loggingService := loggingservice.New()
-	userService := userscontroller.New(pbusers.NewUserGRPCServiceClient(connUser), loggingService)
-	accountService := accountscontroller.New(pbaccounts.NewAccountGRPCServiceClient(connAcc), loggingService)
-
-	h := httphandler.New(accountService, userService, loggingService, cfg.JWTAddress)
+	userService := userscontroller.New(pbusers.NewUserGRPCServiceClient(connUser), logger)
+	accountService := accountscontroller.New(pbaccounts.NewAccountGRPCServiceClient(connAcc), logger)
+	auditService := auditcontroller.New(map[string]pbaudit.AuditGRPCServiceClient{
+		model.AuditResourceAccount: pbaudit.NewAuditGRPCServiceClient(connAcc),
+		model.AuditResourceUser:    pbaudit.NewAuditGRPCServiceClient(connUser),
+	}, logger)
+
+	// the admin token of the auth service lets the gateway disable the logins of users it deletes
+	tokenService := httpservice.New(cfg.JWTAddress, httpservice.DefaultKeySetTTL, cfg.AdminToken, logger)
+
+	reg := metrics.NewRegistry()
+	h := httphandler.New(accountService, userService, auditService, tokenService, tokenService, logger, cfg.IdempotencyTTL, metrics.NewHTTPMetrics(reg))
+
+	// the gateway is ready while the gRPC services serve and tokens can be verified,
+	// /readyz reports every dependency so a degraded one is seen at once
+	checker := health.NewChecker(health.DefaultTimeout)
+	checker.Add("accounts", health.GRPC(connAcc, pbaccounts.AccountGRPCService_ServiceDesc.ServiceName))
+	checker.Add("users", health.GRPC(connUser, pbusers.UserGRPCService_ServiceDesc.ServiceName))
+	checker.Add("auth", func(context.Context) error { return tokenService.CheckKeys() })
+
+	// every route of the gateway wants a token, the metrics and probes are served next to them
+	root := http.NewServeMux()
+	root.Handle("/metrics", metrics.Handler(reg))
+	root.HandleFunc("/healthz", health.Live)
+	root.Handle("/readyz", checker)
+	root.Handle("/", h.AccessControlMiddleware(h.GetRouter()))
 
 	srv := http.Server{
 		Addr:    cfg.HTTPAddress,
-		Handler: h.AccessControlMiddleware(h.GetRouter()),
+		Handler: root,
 	}
 
 	sigC := make(chan os.Signal, 1)
@@ -1,23 +1,42 @@
 package main
 
 import (
+	"context"
+	"io"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
 
+	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 
+	"github.com/stasBigunenko/monorepa/model"
+	pbaudit "github.com/stasBigunenko/monorepa/pkg/auditGRPC/proto"
+	auditgrpcserver "github.com/stasBigunenko/monorepa/pkg/auditGRPC/server"
 	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
 	pb "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
 	usergrpcserver "github.com/stasBigunenko/monorepa/pkg/userGRPC/server"
+	"github.com/stasBigunenko/monorepa/service/audit"
+	"github.com/stasBigunenko/monorepa/service/health"
+	"github.com/stasBigunenko/monorepa/service/interceptor"
 	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
+	"github.com/stasBigunenko/monorepa/service/tracing"
 	"github.com/stasBigunenko/monorepa/service/user"
 )
 
 type Config struct {
 	userGRPCServAddress string
+	storageType         string
+	storageDSN          string
+	auditType           string
+	auditDSN            string
+	logLevel            string
+	logFormat           string
+	traceExporter       string
+	metricsAddress      string
 }
 
 func getConfig() Config {
@@ -26,41 +45,97 @@ func getConfig() Config {
 		userGrpcServAddr = "127.0.0.1:50052"
 	}
 
-	return Config{
-		userGRPCServAddress: userGrpcServAddr,
+	storageType := os.Getenv("STORAGE_TYPE")
+	if storageType == "" {
+		storageType = newStorage.MemoryStorage
 	}
-}
 
-func init() {
-	// Log as JSON instead of the default ASCII formatter.
-	log.SetFormatter(&log.JSONFormatter{})
+	// AUDIT_STORAGE_DSN keeps the audit log in postgres also when the rest is in memory
+	auditType, auditDSN := storageType, os.Getenv("STORAGE_DSN")
+	if dsn := os.Getenv("AUDIT_STORAGE_DSN"); dsn != "" {
+		auditType, auditDSN = newStorage.PostgresStorage, dsn
+	}
+
+	metricsAddr := os.Getenv("METRICS_ADDRESS")
+	if metricsAddr == "" {
+		metricsAddr = "127.0.0.1:9092"
+	}
 
-	// Output to stdout instead of the default stderr
-	// Can be any io.Writer, see below for File example
-	log.SetOutput(os.Stdout)
+	return Config{
+		userGRPCServAddress: userGrpcServAddr,
+		storageType:         storageType,
+		storageDSN:          os.Getenv("STORAGE_DSN"),
+		auditType:           auditType,
+		auditDSN:            auditDSN,
+		logLevel:            os.Getenv("LOG_LEVEL"),
+		logFormat:           os.Getenv("LOG_FORMAT"),
+		traceExporter:       os.Getenv("OTEL_TRACES_EXPORTER"),
+		metricsAddress:      metricsAddr,
+	}
 }
 
 func main() {
 	config := getConfig()
+	logger := loggingservice.New(loggingservice.Config{Service: "user", Level: config.logLevel, Format: config.logFormat})
+
+	// without OTEL_TRACES_EXPORTER traces are passed on but not exported
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{Service: "user", Exporter: config.traceExporter})
+	if err != nil {
+		log.Fatal("failed to set up tracing: ", err)
+	}
+	defer shutdownTracing(context.Background()) //nolint:errcheck
+
+	reg := metrics.NewRegistry()
 
 	lis, err := net.Listen("tcp", config.userGRPCServAddress)
 	if err != nil {
 		log.Fatal("failed to listen: ", err)
 	}
 
-	loggingService := loggingservice.New()
+	dbInt, err := newStorage.NewUserRepository(config.storageType, config.storageDSN, logger)
+	if err != nil {
+		log.Fatal("failed to open storage: ", err)
+	}
+	if closer, ok := dbInt.(io.Closer); ok {
+		defer closer.Close() //nolint:errcheck
+	}
+
+	auditLog, err := newStorage.NewAuditLog(config.auditType, config.auditDSN, logger)
+	if err != nil {
+		log.Fatal("failed to open audit log: ", err)
+	}
+	if config.auditType != newStorage.PostgresStorage {
+		logger.Warn(context.Background(), "the audit log is kept in memory and lost on restart")
+	}
+	if closer, ok := auditLog.(io.Closer); ok {
+		defer closer.Close() //nolint:errcheck
+	}
+	metrics.RegisterStore(reg, "users", dbInt)
+	metrics.RegisterStore(reg, "audit", auditLog)
+	auditRecorder := audit.NewRecorder(auditLog, model.AuditResourceUser, logger, audit.WithMetrics(reg))
+
+	usi := user.NewUsrService(dbInt, logger, user.WithAudit(auditRecorder))
 
-	db := newStorage.NewDB(loggingService)
-	dbInt := newStorage.NewStore(db)
-	usi := user.NewUsrService(dbInt, loggingService)
+	s := grpc.NewServer(interceptor.ServerOptions(logger, metrics.NewGRPCMetrics(reg))...)
+	pb.RegisterUserGRPCServiceServer(s, usergrpcserver.NewUsersGRPCServer(usi, logger))
+	pbaudit.RegisterAuditGRPCServiceServer(s, auditgrpcserver.NewAuditGRPCServer(auditRecorder, logger))
+	healthServer := health.NewGRPCServer(s, pb.UserGRPCService_ServiceDesc.ServiceName, pbaudit.AuditGRPCService_ServiceDesc.ServiceName)
 
-	s := grpc.NewServer()
-	pb.RegisterUserGRPCServiceServer(s, usergrpcserver.NewUsersGRPCServer(usi, loggingService))
+	// the metrics are scraped from their own listener, the gRPC port speaks gRPC only
+	metricsCtx, stopMetrics := context.WithCancel(context.Background())
+	defer stopMetrics()
+	go func() {
+		if err := metrics.Serve(metricsCtx, config.metricsAddress, reg); err != nil {
+			log.Error("error: metrics server failed: ", err)
+		}
+	}()
 
 	sigC := make(chan os.Signal, 1)
 	defer close(sigC)
 	go func() {
 		<-sigC
+		// callers checking health stop sending requests while the running ones finish
+		healthServer.Shutdown()
 		s.GracefulStop()
 	}()
 	signal.Notify(sigC, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,23 @@
+package customErrors
+
+type AccountError string
+
+func (e AccountError) Error() string {
+	return string(e)
+}
+
+func (e AccountError) Is(target error) bool {
+	return isKind(e, target)
+}
+
+const (
+	InsufficientFunds    AccountError = "insufficient funds"
+	SameAccount          AccountError = "transfer to the same account"
+	InvalidAmount        AccountError = "amount must be positive"
+	IdempotencyKeyReused AccountError = "idempotency key reused with different parameters"
+	AccountsNotEmpty     AccountError = "user has accounts with non-zero balance"
+	UnknownOwner         AccountError = "account owner does not exist"
+	CurrencyTaken        AccountError = "user already has an account in this currency"
+	UnknownCurrency      AccountError = "unknown currency"
+	NoExchangeRate       AccountError = "no exchange rate between the currencies"
+)
@@ -0,0 +1,59 @@
+package customErrors
+
+import "errors"
+
+// DomainError is a kind of failure every layer agrees on. The specific errors of the services
+// are one of these kinds, errors.Is(InsufficientFunds, FailedPrecondition) holds, and the kind
+// decides the gRPC code and the HTTP status.
+type DomainError string
+
+func (e DomainError) Error() string {
+	return string(e)
+}
+
+const (
+	NotFound           DomainError = "not found"
+	InvalidArgument    DomainError = "invalid argument"
+	Conflict           DomainError = "conflict"
+	FailedPrecondition DomainError = "failed precondition"
+	PermissionDenied   DomainError = "permission denied"
+	Unavailable        DomainError = "unavailable"
+)
+
+var kinds = []DomainError{NotFound, InvalidArgument, Conflict, FailedPrecondition, PermissionDenied, Unavailable}
+
+// KindOf returns the kind of the error, false for errors of no kind such as failures of storage
+func KindOf(err error) (DomainError, bool) {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind, true
+		}
+	}
+
+	return "", false
+}
+
+// kind of every specific error, errors missing here have none
+var kindOf = map[error]DomainError{
+	InsufficientFunds:    FailedPrecondition,
+	SameAccount:          InvalidArgument,
+	InvalidAmount:        InvalidArgument,
+	IdempotencyKeyReused: FailedPrecondition,
+	AccountsNotEmpty:     FailedPrecondition,
+	UnknownOwner:         FailedPrecondition,
+	CurrencyTaken:        Conflict,
+	UnknownCurrency:      InvalidArgument,
+	NoExchangeRate:       FailedPrecondition,
+
+	AlreadyExists:   Conflict,
+	VersionMismatch: Conflict,
+
+	UUIDError:           InvalidArgument,
+	JSONError:           InvalidArgument,
+	IdempotencyKeyInUse: Conflict,
+}
+
+func isKind(err error, target error) bool {
+	kind, ok := kindOf[err]
+	return ok && target == kind
+}
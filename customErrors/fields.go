@@ -0,0 +1,30 @@
+package customErrors
+
+import "strings"
+
+// FieldError tells which field of a request is invalid and why
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors are the invalid fields of a request, they are an InvalidArgument
+type FieldErrors []FieldError
+
+// Field is the error of a single invalid field
+func Field(name, message string) FieldErrors {
+	return FieldErrors{{Field: name, Message: message}}
+}
+
+func (e FieldErrors) Error() string {
+	parts := make([]string, len(e))
+	for i, field := range e {
+		parts[i] = field.Field + ": " + field.Message
+	}
+
+	return "invalid " + strings.Join(parts, ", ")
+}
+
+func (e FieldErrors) Is(target error) bool {
+	return target == InvalidArgument
+}
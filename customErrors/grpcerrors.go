@@ -6,9 +6,13 @@ func (e GRPCError) Error() string {
 	return string(e)
 }
 
+func (e GRPCError) Is(target error) bool {
+	return isKind(e, target)
+}
+
 const (
 	DeadlineExceeded GRPCError = "deadline exceeded"
-	NotFound         GRPCError = "not found"
 	AlreadyExists    GRPCError = "already exists"
 	ParseError       GRPCError = "failed to parse"
+	VersionMismatch  GRPCError = "version mismatch"
 )
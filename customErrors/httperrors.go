@@ -8,6 +8,10 @@ func (e HTTPError) Error() string {
 	return e.Message
 }
 
+func (e HTTPError) Is(target error) bool {
+	return isKind(e, target)
+}
+
 var UUIDError = HTTPError{
 	Message: "failed to parse uuid",
 }
@@ -19,3 +23,7 @@ var RWError = HTTPError{
 var JSONError = HTTPError{
 	Message: "failed to marshal / unmarshal json",
 }
+
+var IdempotencyKeyInUse = HTTPError{
+	Message: "a request with this idempotency key is still in progress",
+}
@@ -0,0 +1,188 @@
+package customErrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain marks the error details the services attach, details of other domains are ignored
+const errorDomain = "monorepa"
+
+// reasons name the specific errors, in the ErrorInfo detail of a status, so the client gets the same
+// error back, and in the problem responses of the gateway
+var reasons = map[error]string{
+	InsufficientFunds:    "INSUFFICIENT_FUNDS",
+	SameAccount:          "SAME_ACCOUNT",
+	InvalidAmount:        "INVALID_AMOUNT",
+	IdempotencyKeyReused: "IDEMPOTENCY_KEY_REUSED",
+	AccountsNotEmpty:     "ACCOUNTS_NOT_EMPTY",
+	UnknownOwner:         "UNKNOWN_OWNER",
+	CurrencyTaken:        "CURRENCY_TAKEN",
+	UnknownCurrency:      "UNKNOWN_CURRENCY",
+	NoExchangeRate:       "NO_EXCHANGE_RATE",
+	AlreadyExists:        "ALREADY_EXISTS",
+	VersionMismatch:      "VERSION_MISMATCH",
+	DeadlineExceeded:     "DEADLINE_EXCEEDED",
+
+	UUIDError:           "INVALID_UUID",
+	JSONError:           "INVALID_JSON",
+	IdempotencyKeyInUse: "IDEMPOTENCY_KEY_IN_USE",
+
+	InvalidCredentials: "INVALID_CREDENTIALS",
+	InvalidToken:       "INVALID_TOKEN",
+	TokenReused:        "REFRESH_TOKEN_REUSED",
+}
+
+var codeOf = map[DomainError]codes.Code{
+	NotFound:           codes.NotFound,
+	InvalidArgument:    codes.InvalidArgument,
+	Conflict:           codes.AlreadyExists,
+	FailedPrecondition: codes.FailedPrecondition,
+	PermissionDenied:   codes.PermissionDenied,
+	Unavailable:        codes.Unavailable,
+}
+
+// ToStatus converts an error of a service into the status a gRPC server returns. The kind decides
+// the code and the specific error goes along as the reason of an ErrorInfo detail. Errors of no kind
+// are Internal with the given message, their text stays on the server.
+func ToStatus(err error, internal string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var st *status.Status
+	kind, ok := KindOf(err)
+	switch {
+	case errors.Is(err, VersionMismatch):
+		// a stale version is worth a retry with the current one
+		st = status.New(codes.Aborted, err.Error())
+	case ok:
+		st = status.New(codeOf[kind], err.Error())
+	case errors.Is(err, DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, internal)
+	}
+
+	if reason, ok := specificReason(err); ok {
+		if withInfo, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}); err == nil {
+			st = withInfo
+		}
+	}
+
+	var fields FieldErrors
+	if errors.As(err, &fields) {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations,
+				&errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Message})
+		}
+		if withFields, err := st.WithDetails(badRequest); err == nil {
+			st = withFields
+		}
+	}
+
+	return st.Err()
+}
+
+// ReasonOf names the error for clients: the reason of the specific error, the kind otherwise
+// and "" for errors of no kind
+func ReasonOf(err error) string {
+	if reason, ok := specificReason(err); ok {
+		return reason
+	}
+	if kind, ok := KindOf(err); ok {
+		return strings.ToUpper(strings.ReplaceAll(string(kind), " ", "_"))
+	}
+
+	return ""
+}
+
+func specificReason(err error) (string, bool) {
+	for specific, reason := range reasons {
+		if errors.Is(err, specific) {
+			return reason, true
+		}
+	}
+
+	return "", false
+}
+
+// FromStatus converts the status a gRPC client got back into the error the server started from,
+// the specific one when the status names it and the kind of the code otherwise
+func FromStatus(err error, message string) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return fmt.Errorf("%s, failed to parse status or not a grpc error type: %w", message, err)
+	}
+
+	if specific := specificOf(st); specific != nil {
+		return fmt.Errorf("%s: %w", message, specific)
+	}
+	if fields := fieldsOf(st); fields != nil {
+		return fmt.Errorf("%s: %w", message, fields)
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		return fmt.Errorf("%s: %w", message, NotFound)
+	case codes.InvalidArgument, codes.OutOfRange:
+		return fmt.Errorf("%s: %s: %w", message, st.Message(), InvalidArgument)
+	case codes.AlreadyExists:
+		return fmt.Errorf("%s: %s: %w", message, st.Message(), Conflict)
+	case codes.Aborted:
+		return fmt.Errorf("%s: %w", message, VersionMismatch)
+	case codes.FailedPrecondition:
+		return fmt.Errorf("%s: %s: %w", message, st.Message(), FailedPrecondition)
+	case codes.PermissionDenied:
+		return fmt.Errorf("%s: %s: %w", message, st.Message(), PermissionDenied)
+	case codes.Unavailable:
+		return fmt.Errorf("%s: %s: %w", message, st.Message(), Unavailable)
+	case codes.DeadlineExceeded:
+		return fmt.Errorf("%s: %w", message, DeadlineExceeded)
+	}
+
+	return fmt.Errorf("%s: %s", message, err.Error())
+}
+
+func specificOf(st *status.Status) error {
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != errorDomain {
+			continue
+		}
+		for specific, reason := range reasons {
+			if reason == info.Reason {
+				return specific
+			}
+		}
+	}
+
+	return nil
+}
+
+func fieldsOf(st *status.Status) FieldErrors {
+	var fields FieldErrors
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, violation := range badRequest.FieldViolations {
+			fields = append(fields, FieldError{Field: violation.Field, Message: violation.Description})
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,78 @@
+package customErrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatus_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+		kind DomainError
+	}{
+		{name: "kind", err: NotFound, code: codes.NotFound, kind: NotFound},
+		{name: "specific", err: fmt.Errorf("transfer: %w", InsufficientFunds), code: codes.FailedPrecondition, kind: FailedPrecondition},
+		{name: "conflict", err: CurrencyTaken, code: codes.AlreadyExists, kind: Conflict},
+		{name: "stale version", err: VersionMismatch, code: codes.Aborted, kind: Conflict},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			st := ToStatus(tc.err, "internal")
+			assert.Equal(t, tc.code, status.Code(st))
+
+			got := FromStatus(st, "call")
+			assert.True(t, errors.Is(got, tc.kind))
+			if specific := errors.Unwrap(tc.err); specific != nil {
+				assert.True(t, errors.Is(got, specific), "the specific error survives the trip")
+			}
+		})
+	}
+}
+
+func TestToStatus_HidesInternal(t *testing.T) {
+	st := ToStatus(errors.New("pq: connection refused"), "internal storage problem")
+	assert.Equal(t, codes.Internal, status.Code(st))
+	assert.Equal(t, "internal storage problem", status.Convert(st).Message())
+
+	_, ok := KindOf(FromStatus(st, "call"))
+	assert.False(t, ok)
+}
+
+func TestFromStatus_Code(t *testing.T) {
+	// statuses without details, as other servers send them, still map by code
+	err := FromStatus(status.Error(codes.InvalidArgument, "bad id"), "call")
+	assert.True(t, errors.Is(err, InvalidArgument))
+	assert.Contains(t, err.Error(), "bad id")
+
+	err = FromStatus(errors.New("eof"), "call")
+	_, ok := KindOf(err)
+	assert.False(t, ok)
+}
+
+func TestStatus_FieldErrors(t *testing.T) {
+	fields := FieldErrors{{Field: "amount", Message: "must be positive"}, {Field: "to", Message: "must be a uuid"}}
+
+	st := ToStatus(fields, "internal")
+	assert.Equal(t, codes.InvalidArgument, status.Code(st))
+
+	got := FromStatus(st, "call")
+	assert.True(t, errors.Is(got, InvalidArgument))
+
+	var gotFields FieldErrors
+	assert.True(t, errors.As(got, &gotFields))
+	assert.Equal(t, fields, gotFields)
+}
+
+func TestReasonOf(t *testing.T) {
+	assert.Equal(t, "INSUFFICIENT_FUNDS", ReasonOf(fmt.Errorf("transfer: %w", InsufficientFunds)))
+	assert.Equal(t, "INVALID_ARGUMENT", ReasonOf(Field("page_size", "must be an integer")))
+	assert.Equal(t, "NOT_FOUND", ReasonOf(NotFound))
+	assert.Equal(t, "", ReasonOf(errors.New("connection refused")))
+}
@@ -1,7 +1,11 @@
 package customErrors
 
 const (
-	WrongPassword UserValidationError = "wrong password"
+	WrongPassword      UserValidationError = "wrong password"
+	WrongName          UserValidationError = "wrong name"
+	InvalidCredentials UserValidationError = "invalid name or password"
+	InvalidToken       UserValidationError = "invalid token"
+	TokenReused        UserValidationError = "refresh token reused"
 )
 
 type UserValidationError string
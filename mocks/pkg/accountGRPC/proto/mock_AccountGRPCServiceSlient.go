@@ -9,12 +9,18 @@ import (
 )
 
 type MockAccountGrpcServiceClient struct {
-	MockGetAccount      func(ctx context.Context, in *pb.AccountID, opts ...grpc.CallOption) (*pb.Account, error)
-	MockGetUserAccounts func(ctx context.Context, in *pb.UserID, opts ...grpc.CallOption) (*pb.AllAccounts, error)
-	MockGetAllUsers     func(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*pb.AllAccounts, error)
-	MockCreateAccount   func(ctx context.Context, in *pb.UserID, opts ...grpc.CallOption) (*pb.Account, error)
-	MockUpdateAccount   func(ctx context.Context, in *pb.Account, opts ...grpc.CallOption) (*pb.Account, error)
-	MockDeleteAccount   func(ctx context.Context, in *pb.AccountID, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	MockGetAccount        func(ctx context.Context, in *pb.AccountID, opts ...grpc.CallOption) (*pb.Account, error)
+	MockGetUserAccounts   func(ctx context.Context, in *pb.UserID, opts ...grpc.CallOption) (*pb.AllAccounts, error)
+	MockGetAllUsers       func(ctx context.Context, in *pb.AccountFilter, opts ...grpc.CallOption) (*pb.AllAccounts, error)
+	MockCreateAccount     func(ctx context.Context, in *pb.NewAccount, opts ...grpc.CallOption) (*pb.Account, error)
+	MockUpdateAccount     func(ctx context.Context, in *pb.Account, opts ...grpc.CallOption) (*pb.Account, error)
+	MockDeleteAccount     func(ctx context.Context, in *pb.AccountID, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	MockTransfer          func(ctx context.Context, in *pb.TransferRequest, opts ...grpc.CallOption) (*pb.TransferResult, error)
+	MockListTransactions  func(ctx context.Context, in *pb.TransactionFilter, opts ...grpc.CallOption) (*pb.Transactions, error)
+	MockDeposit           func(ctx context.Context, in *pb.BalanceChange, opts ...grpc.CallOption) (*pb.Account, error)
+	MockWithdraw          func(ctx context.Context, in *pb.BalanceChange, opts ...grpc.CallOption) (*pb.Account, error)
+	MockCloseUserAccounts func(ctx context.Context, in *pb.CloseAccounts, opts ...grpc.CallOption) (*pb.AllAccounts, error)
+	MockRestoreAccounts   func(ctx context.Context, in *pb.AllAccounts, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 func (m MockAccountGrpcServiceClient) GetAccount(ctx context.Context, in *pb.AccountID, opts ...grpc.CallOption) (*pb.Account, error) {
@@ -25,11 +31,11 @@ func (m MockAccountGrpcServiceClient) GetUserAccounts(ctx context.Context, in *p
 	return m.MockGetUserAccounts(ctx, in, opts...)
 }
 
-func (m MockAccountGrpcServiceClient) GetAllUsers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*pb.AllAccounts, error) {
+func (m MockAccountGrpcServiceClient) GetAllUsers(ctx context.Context, in *pb.AccountFilter, opts ...grpc.CallOption) (*pb.AllAccounts, error) {
 	return m.MockGetAllUsers(ctx, in, opts...)
 }
 
-func (m MockAccountGrpcServiceClient) CreateAccount(ctx context.Context, in *pb.UserID, opts ...grpc.CallOption) (*pb.Account, error) {
+func (m MockAccountGrpcServiceClient) CreateAccount(ctx context.Context, in *pb.NewAccount, opts ...grpc.CallOption) (*pb.Account, error) {
 	return m.MockCreateAccount(ctx, in, opts...)
 }
 
@@ -40,3 +46,27 @@ func (m MockAccountGrpcServiceClient) UpdateAccount(ctx context.Context, in *pb.
 func (m MockAccountGrpcServiceClient) DeleteAccount(ctx context.Context, in *pb.AccountID, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	return m.MockDeleteAccount(ctx, in, opts...)
 }
+
+func (m MockAccountGrpcServiceClient) Transfer(ctx context.Context, in *pb.TransferRequest, opts ...grpc.CallOption) (*pb.TransferResult, error) {
+	return m.MockTransfer(ctx, in, opts...)
+}
+
+func (m MockAccountGrpcServiceClient) ListTransactions(ctx context.Context, in *pb.TransactionFilter, opts ...grpc.CallOption) (*pb.Transactions, error) {
+	return m.MockListTransactions(ctx, in, opts...)
+}
+
+func (m MockAccountGrpcServiceClient) Deposit(ctx context.Context, in *pb.BalanceChange, opts ...grpc.CallOption) (*pb.Account, error) {
+	return m.MockDeposit(ctx, in, opts...)
+}
+
+func (m MockAccountGrpcServiceClient) Withdraw(ctx context.Context, in *pb.BalanceChange, opts ...grpc.CallOption) (*pb.Account, error) {
+	return m.MockWithdraw(ctx, in, opts...)
+}
+
+func (m MockAccountGrpcServiceClient) CloseUserAccounts(ctx context.Context, in *pb.CloseAccounts, opts ...grpc.CallOption) (*pb.AllAccounts, error) {
+	return m.MockCloseUserAccounts(ctx, in, opts...)
+}
+
+func (m MockAccountGrpcServiceClient) RestoreAccounts(ctx context.Context, in *pb.AllAccounts, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.MockRestoreAccounts(ctx, in, opts...)
+}
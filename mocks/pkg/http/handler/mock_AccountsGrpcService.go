@@ -8,16 +8,22 @@ import (
 )
 
 type MockAccountsGrpcServer struct {
-	MockCreateAccount   func(ctx context.Context, userID uuid.UUID) (uuid.UUID, error)
-	MockGetAccount      func(ctx context.Context, id uuid.UUID) (model.Account, error)
-	MockGetUserAccounts func(ctx context.Context, userID uuid.UUID) ([]model.Account, error)
-	MockGetAllAccounts  func(ctx context.Context) ([]model.Account, error)
-	MockUpdateAccount   func(ctx context.Context, account model.Account) error
-	MockDeleteAccount   func(ctx context.Context, id uuid.UUID) error
+	MockCreateAccount     func(ctx context.Context, userID uuid.UUID, currency string) (uuid.UUID, error)
+	MockGetAccount        func(ctx context.Context, id uuid.UUID) (model.Account, error)
+	MockGetUserAccounts   func(ctx context.Context, userID uuid.UUID) ([]model.Account, error)
+	MockGetAllAccounts    func(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error)
+	MockUpdateAccount     func(ctx context.Context, account model.Account) (model.Account, error)
+	MockDeleteAccount     func(ctx context.Context, id uuid.UUID) error
+	MockTransfer          func(ctx context.Context, transfer model.Transfer) (model.TransferResult, error)
+	MockListTransactions  func(ctx context.Context, filter model.TransactionFilter) (model.TransactionPage, error)
+	MockDeposit           func(ctx context.Context, id uuid.UUID, amount int64) (model.Account, error)
+	MockWithdraw          func(ctx context.Context, id uuid.UUID, amount int64) (model.Account, error)
+	MockCloseUserAccounts func(ctx context.Context, userID uuid.UUID, force bool) ([]model.Account, error)
+	MockRestoreAccounts   func(ctx context.Context, accounts []model.Account) error
 }
 
-func (m *MockAccountsGrpcServer) CreateAccount(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
-	return m.MockCreateAccount(ctx, userID)
+func (m *MockAccountsGrpcServer) CreateAccount(ctx context.Context, userID uuid.UUID, currency string) (uuid.UUID, error) {
+	return m.MockCreateAccount(ctx, userID, currency)
 }
 func (m *MockAccountsGrpcServer) GetAccount(ctx context.Context, id uuid.UUID) (model.Account, error) {
 	return m.MockGetAccount(ctx, id)
@@ -25,12 +31,30 @@ func (m *MockAccountsGrpcServer) GetAccount(ctx context.Context, id uuid.UUID) (
 func (m *MockAccountsGrpcServer) GetUserAccounts(ctx context.Context, userID uuid.UUID) ([]model.Account, error) {
 	return m.MockGetUserAccounts(ctx, userID)
 }
-func (m *MockAccountsGrpcServer) GetAllAccounts(ctx context.Context) ([]model.Account, error) {
-	return m.MockGetAllAccounts(ctx)
+func (m *MockAccountsGrpcServer) GetAllAccounts(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+	return m.MockGetAllAccounts(ctx, filter)
 }
-func (m *MockAccountsGrpcServer) UpdateAccount(ctx context.Context, account model.Account) error {
+func (m *MockAccountsGrpcServer) UpdateAccount(ctx context.Context, account model.Account) (model.Account, error) {
 	return m.MockUpdateAccount(ctx, account)
 }
 func (m *MockAccountsGrpcServer) DeleteAccount(ctx context.Context, id uuid.UUID) error {
 	return m.MockDeleteAccount(ctx, id)
 }
+func (m *MockAccountsGrpcServer) Transfer(ctx context.Context, transfer model.Transfer) (model.TransferResult, error) {
+	return m.MockTransfer(ctx, transfer)
+}
+func (m *MockAccountsGrpcServer) ListTransactions(ctx context.Context, filter model.TransactionFilter) (model.TransactionPage, error) {
+	return m.MockListTransactions(ctx, filter)
+}
+func (m *MockAccountsGrpcServer) Deposit(ctx context.Context, id uuid.UUID, amount int64) (model.Account, error) {
+	return m.MockDeposit(ctx, id, amount)
+}
+func (m *MockAccountsGrpcServer) Withdraw(ctx context.Context, id uuid.UUID, amount int64) (model.Account, error) {
+	return m.MockWithdraw(ctx, id, amount)
+}
+func (m *MockAccountsGrpcServer) CloseUserAccounts(ctx context.Context, userID uuid.UUID, force bool) ([]model.Account, error) {
+	return m.MockCloseUserAccounts(ctx, userID, force)
+}
+func (m *MockAccountsGrpcServer) RestoreAccounts(ctx context.Context, accounts []model.Account) error {
+	return m.MockRestoreAccounts(ctx, accounts)
+}
@@ -0,0 +1,15 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+type MockAuditGrpcServer struct {
+	MockListAudit func(ctx context.Context, filter model.AuditFilter) (model.AuditPage, error)
+}
+
+func (m *MockAuditGrpcServer) ListAudit(ctx context.Context, filter model.AuditFilter) (model.AuditPage, error) {
+	return m.MockListAudit(ctx, filter)
+}
@@ -0,0 +1,19 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type MockCredentialService struct {
+	MockDisableUser func(ctx context.Context, id uuid.UUID) error
+	MockEnableUser  func(ctx context.Context, id uuid.UUID) error
+}
+
+func (m *MockCredentialService) DisableUser(ctx context.Context, id uuid.UUID) error {
+	return m.MockDisableUser(ctx, id)
+}
+func (m *MockCredentialService) EnableUser(ctx context.Context, id uuid.UUID) error {
+	return m.MockEnableUser(ctx, id)
+}
@@ -8,11 +8,13 @@ import (
 )
 
 type MockUsersGrpcServer struct {
-	MockCreateUser  func(ctx context.Context, name string) (uuid.UUID, error)
-	MockGetUser     func(ctx context.Context, id uuid.UUID) (model.UserHTTP, error)
-	MockGetAllUsers func(ctx context.Context) ([]model.UserHTTP, error)
-	MockUpdateUser  func(ctx context.Context, user model.UserHTTP) error
-	MockDeleteUser  func(ctx context.Context, id uuid.UUID) error
+	MockCreateUser     func(ctx context.Context, name string) (uuid.UUID, error)
+	MockGetUser        func(ctx context.Context, id uuid.UUID) (model.UserHTTP, error)
+	MockGetAllUsers    func(ctx context.Context, filter model.UserFilter) (model.UserPage, error)
+	MockUpdateUser     func(ctx context.Context, user model.UserHTTP) (model.UserHTTP, error)
+	MockDeleteUser     func(ctx context.Context, id uuid.UUID) error
+	MockSoftDeleteUser func(ctx context.Context, id uuid.UUID) error
+	MockRestoreUser    func(ctx context.Context, id uuid.UUID) error
 }
 
 func (m *MockUsersGrpcServer) CreateUser(ctx context.Context, name string) (uuid.UUID, error) {
@@ -21,12 +23,18 @@ func (m *MockUsersGrpcServer) CreateUser(ctx context.Context, name string) (uuid
 func (m *MockUsersGrpcServer) GetUser(ctx context.Context, id uuid.UUID) (model.UserHTTP, error) {
 	return m.MockGetUser(ctx, id)
 }
-func (m *MockUsersGrpcServer) GetAllUsers(ctx context.Context) ([]model.UserHTTP, error) {
-	return m.MockGetAllUsers(ctx)
+func (m *MockUsersGrpcServer) GetAllUsers(ctx context.Context, filter model.UserFilter) (model.UserPage, error) {
+	return m.MockGetAllUsers(ctx, filter)
 }
-func (m *MockUsersGrpcServer) UpdateUser(ctx context.Context, user model.UserHTTP) error {
+func (m *MockUsersGrpcServer) UpdateUser(ctx context.Context, user model.UserHTTP) (model.UserHTTP, error) {
 	return m.MockUpdateUser(ctx, user)
 }
 func (m *MockUsersGrpcServer) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return m.MockDeleteUser(ctx, id)
 }
+func (m *MockUsersGrpcServer) SoftDeleteUser(ctx context.Context, id uuid.UUID) error {
+	return m.MockSoftDeleteUser(ctx, id)
+}
+func (m *MockUsersGrpcServer) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	return m.MockRestoreUser(ctx, id)
+}
@@ -1,145 +0,0 @@
-// Code generated by mockery v0.0.0-dev. DO NOT EDIT.
-
-package mockNewStore
-
-import (
-	context "context"
-
-	mock "github.com/stretchr/testify/mock"
-
-	uuid "github.com/google/uuid"
-)
-
-// NewStore is an autogenerated mock type for the NewStore type
-type NewStore struct {
-	mock.Mock
-}
-
-// Create provides a mock function with given fields: _a0, _a1
-func (_m *NewStore) Create(_a0 context.Context, _a1 interface{}) (interface{}, error) {
-	ret := _m.Called(_a0, _a1)
-
-	var r0 interface{}
-	if rf, ok := ret.Get(0).(func(context.Context, interface{}) interface{}); ok {
-		r0 = rf(_a0, _a1)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(interface{})
-		}
-	}
-
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
-		r1 = rf(_a0, _a1)
-	} else {
-		r1 = ret.Error(1)
-	}
-
-	return r0, r1
-}
-
-// Delete provides a mock function with given fields: _a0, _a1
-func (_m *NewStore) Delete(_a0 context.Context, _a1 uuid.UUID) error {
-	ret := _m.Called(_a0, _a1)
-
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
-		r0 = rf(_a0, _a1)
-	} else {
-		r0 = ret.Error(0)
-	}
-
-	return r0
-}
-
-// Get provides a mock function with given fields: _a0, _a1
-func (_m *NewStore) Get(_a0 context.Context, _a1 uuid.UUID) (interface{}, error) {
-	ret := _m.Called(_a0, _a1)
-
-	var r0 interface{}
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) interface{}); ok {
-		r0 = rf(_a0, _a1)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(interface{})
-		}
-	}
-
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
-		r1 = rf(_a0, _a1)
-	} else {
-		r1 = ret.Error(1)
-	}
-
-	return r0, r1
-}
-
-// GetAll provides a mock function with given fields: _a0
-func (_m *NewStore) GetAll(_a0 context.Context) (interface{}, error) {
-	ret := _m.Called(_a0)
-
-	var r0 interface{}
-	if rf, ok := ret.Get(0).(func(context.Context) interface{}); ok {
-		r0 = rf(_a0)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(interface{})
-		}
-	}
-
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(_a0)
-	} else {
-		r1 = ret.Error(1)
-	}
-
-	return r0, r1
-}
-
-// GetUserAccounts provides a mock function with given fields: _a0, _a1
-func (_m *NewStore) GetUserAccounts(_a0 context.Context, _a1 uuid.UUID) (interface{}, error) {
-	ret := _m.Called(_a0, _a1)
-
-	var r0 interface{}
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) interface{}); ok {
-		r0 = rf(_a0, _a1)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(interface{})
-		}
-	}
-
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
-		r1 = rf(_a0, _a1)
-	} else {
-		r1 = ret.Error(1)
-	}
-
-	return r0, r1
-}
-
-// Update provides a mock function with given fields: _a0, _a1
-func (_m *NewStore) Update(_a0 context.Context, _a1 interface{}) (interface{}, error) {
-	ret := _m.Called(_a0, _a1)
-
-	var r0 interface{}
-	if rf, ok := ret.Get(0).(func(context.Context, interface{}) interface{}); ok {
-		r0 = rf(_a0, _a1)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(interface{})
-		}
-	}
-
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
-		r1 = rf(_a0, _a1)
-	} else {
-		r1 = ret.Error(1)
-	}
-
-	return r0, r1
-}
@@ -12,8 +12,10 @@ type MockUserGrpcServiceClient struct {
 	MockCreate      func(ctx context.Context, in *pb.Name, opts ...grpc.CallOption) (*pb.User, error)
 	MockGet         func(ctx context.Context, in *pb.Id, opts ...grpc.CallOption) (*pb.User, error)
 	MockDelete      func(ctx context.Context, in *pb.Id, opts ...grpc.CallOption) (*emptypb.Empty, error)
-	MockGetAllUsers func(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*pb.AllUsers, error)
+	MockGetAllUsers func(ctx context.Context, in *pb.UserFilter, opts ...grpc.CallOption) (*pb.AllUsers, error)
 	MockUpdate      func(ctx context.Context, in *pb.User, opts ...grpc.CallOption) (*pb.User, error)
+	MockSoftDelete  func(ctx context.Context, in *pb.Id, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	MockRestore     func(ctx context.Context, in *pb.Id, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 func (m MockUserGrpcServiceClient) Create(ctx context.Context, in *pb.Name, opts ...grpc.CallOption) (*pb.User, error) {
@@ -28,10 +30,18 @@ func (m MockUserGrpcServiceClient) Delete(ctx context.Context, in *pb.Id, opts .
 	return m.MockDelete(ctx, in, opts...)
 }
 
-func (m MockUserGrpcServiceClient) GetAllUsers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*pb.AllUsers, error) {
+func (m MockUserGrpcServiceClient) GetAllUsers(ctx context.Context, in *pb.UserFilter, opts ...grpc.CallOption) (*pb.AllUsers, error) {
 	return m.MockGetAllUsers(ctx, in, opts...)
 }
 
 func (m MockUserGrpcServiceClient) Update(ctx context.Context, in *pb.User, opts ...grpc.CallOption) (*pb.User, error) {
 	return m.MockUpdate(ctx, in, opts...)
 }
+
+func (m MockUserGrpcServiceClient) SoftDelete(ctx context.Context, in *pb.Id, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.MockSoftDelete(ctx, in, opts...)
+}
+
+func (m MockUserGrpcServiceClient) Restore(ctx context.Context, in *pb.Id, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return m.MockRestore(ctx, in, opts...)
+}
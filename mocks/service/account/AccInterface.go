@@ -16,20 +16,43 @@ type AccInterface struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: _a0, _a1
-func (_m *AccInterface) Create(_a0 context.Context, _a1 uuid.UUID) (model.Account, error) {
-	ret := _m.Called(_a0, _a1)
+// CloseUserAccounts provides a mock function with given fields: _a0, _a1, _a2
+func (_m *AccInterface) CloseUserAccounts(_a0 context.Context, _a1 uuid.UUID, _a2 bool) ([]model.Account, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []model.Account
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, bool) []model.Account); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Account)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, bool) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: _a0, _a1, _a2
+func (_m *AccInterface) Create(_a0 context.Context, _a1 uuid.UUID, _a2 string) (model.Account, error) {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 model.Account
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) model.Account); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) model.Account); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		r0 = ret.Get(0).(model.Account)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -51,6 +74,27 @@ func (_m *AccInterface) Delete(_a0 context.Context, _a1 uuid.UUID) error {
 	return r0
 }
 
+// Deposit provides a mock function with given fields: _a0, _a1, _a2
+func (_m *AccInterface) Deposit(_a0 context.Context, _a1 uuid.UUID, _a2 int64) (model.Account, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 model.Account
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, int64) model.Account); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(model.Account)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: _a0, _a1
 func (_m *AccInterface) Get(_a0 context.Context, _a1 uuid.UUID) (model.Account, error) {
 	ret := _m.Called(_a0, _a1)
@@ -72,13 +116,13 @@ func (_m *AccInterface) Get(_a0 context.Context, _a1 uuid.UUID) (model.Account,
 	return r0, r1
 }
 
-// GetAll provides a mock function with given fields: _a0
-func (_m *AccInterface) GetAll(_a0 context.Context) ([]model.Account, error) {
-	ret := _m.Called(_a0)
+// GetUser provides a mock function with given fields: _a0, _a1
+func (_m *AccInterface) GetUser(_a0 context.Context, _a1 uuid.UUID) ([]model.Account, error) {
+	ret := _m.Called(_a0, _a1)
 
 	var r0 []model.Account
-	if rf, ok := ret.Get(0).(func(context.Context) []model.Account); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []model.Account); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]model.Account)
@@ -86,8 +130,8 @@ func (_m *AccInterface) GetAll(_a0 context.Context) ([]model.Account, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -95,21 +139,75 @@ func (_m *AccInterface) GetAll(_a0 context.Context) ([]model.Account, error) {
 	return r0, r1
 }
 
-// GetUser provides a mock function with given fields: _a0, _a1
-func (_m *AccInterface) GetUser(_a0 context.Context, _a1 uuid.UUID) ([]model.Account, error) {
+// List provides a mock function with given fields: _a0, _a1
+func (_m *AccInterface) List(_a0 context.Context, _a1 model.AccountFilter) (model.AccountPage, error) {
 	ret := _m.Called(_a0, _a1)
 
-	var r0 []model.Account
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []model.Account); ok {
+	var r0 model.AccountPage
+	if rf, ok := ret.Get(0).(func(context.Context, model.AccountFilter) model.AccountPage); ok {
 		r0 = rf(_a0, _a1)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]model.Account)
-		}
+		r0 = ret.Get(0).(model.AccountPage)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, model.AccountFilter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListTransactions provides a mock function with given fields: _a0, _a1
+func (_m *AccInterface) ListTransactions(_a0 context.Context, _a1 model.TransactionFilter) (model.TransactionPage, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 model.TransactionPage
+	if rf, ok := ret.Get(0).(func(context.Context, model.TransactionFilter) model.TransactionPage); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(model.TransactionPage)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.TransactionFilter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreAccounts provides a mock function with given fields: _a0, _a1
+func (_m *AccInterface) RestoreAccounts(_a0 context.Context, _a1 []model.Account) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []model.Account) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Transfer provides a mock function with given fields: _a0, _a1
+func (_m *AccInterface) Transfer(_a0 context.Context, _a1 model.Transfer) (model.TransferResult, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 model.TransferResult
+	if rf, ok := ret.Get(0).(func(context.Context, model.Transfer) model.TransferResult); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(model.TransferResult)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.Transfer) error); ok {
 		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
@@ -138,3 +236,24 @@ func (_m *AccInterface) Update(_a0 context.Context, _a1 model.Account) (model.Ac
 
 	return r0, r1
 }
+
+// Withdraw provides a mock function with given fields: _a0, _a1, _a2
+func (_m *AccInterface) Withdraw(_a0 context.Context, _a1 uuid.UUID, _a2 int64) (model.Account, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 model.Account
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, int64) model.Account); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(model.Account)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
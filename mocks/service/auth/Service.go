@@ -3,8 +3,12 @@
 package mocks
 
 import (
+	context "context"
+
 	model "github.com/stasBigunenko/monorepa/model"
 	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
 )
 
 // Service is an autogenerated mock type for the Service type
@@ -12,6 +16,69 @@ type Service struct {
 	mock.Mock
 }
 
+// ChangePassword provides a mock function with given fields: _a0, _a1
+func (_m *Service) ChangePassword(_a0 context.Context, _a1 model.PasswordChange) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.PasswordChange) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DisableUser provides a mock function with given fields: _a0, _a1
+func (_m *Service) DisableUser(_a0 context.Context, _a1 uuid.UUID) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnableUser provides a mock function with given fields: _a0, _a1
+func (_m *Service) EnableUser(_a0 context.Context, _a1 uuid.UUID) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GenerateKey provides a mock function with given fields:
+func (_m *Service) GenerateKey() (string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCert provides a mock function with given fields: _a0
 func (_m *Service) GetCert(_a0 string) ([]byte, error) {
 	ret := _m.Called(_a0)
@@ -35,15 +102,36 @@ func (_m *Service) GetCert(_a0 string) ([]byte, error) {
 	return r0, r1
 }
 
+// GetJWKS provides a mock function with given fields:
+func (_m *Service) GetJWKS() (model.JWKS, error) {
+	ret := _m.Called()
+
+	var r0 model.JWKS
+	if rf, ok := ret.Get(0).(func() model.JWKS); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(model.JWKS)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Login provides a mock function with given fields: _a0
-func (_m *Service) Login(_a0 model.User) (string, error) {
+func (_m *Service) Login(_a0 model.User) (model.TokenPair, error) {
 	ret := _m.Called(_a0)
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(model.User) string); ok {
+	var r0 model.TokenPair
+	if rf, ok := ret.Get(0).(func(model.User) model.TokenPair); ok {
 		r0 = rf(_a0)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(model.TokenPair)
 	}
 
 	var r1 error
@@ -55,3 +143,131 @@ func (_m *Service) Login(_a0 model.User) (string, error) {
 
 	return r0, r1
 }
+
+// Logout provides a mock function with given fields: _a0, _a1
+func (_m *Service) Logout(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PromoteKey provides a mock function with given fields: _a0
+func (_m *Service) PromoteKey(_a0 string) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Refresh provides a mock function with given fields: _a0, _a1
+func (_m *Service) Refresh(_a0 context.Context, _a1 string) (model.TokenPair, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 model.TokenPair
+	if rf, ok := ret.Get(0).(func(context.Context, string) model.TokenPair); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(model.TokenPair)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Register provides a mock function with given fields: _a0, _a1
+func (_m *Service) Register(_a0 context.Context, _a1 model.User) (uuid.UUID, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 uuid.UUID
+	if rf, ok := ret.Get(0).(func(context.Context, model.User) uuid.UUID); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(uuid.UUID)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.User) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokedTokens provides a mock function with given fields: _a0
+func (_m *Service) RevokedTokens(_a0 context.Context) ([]model.RevokedToken, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []model.RevokedToken
+	if rf, ok := ret.Get(0).(func(context.Context) []model.RevokedToken); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.RevokedToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RotateKey provides a mock function with given fields:
+func (_m *Service) RotateKey() (string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRole provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Service) SetRole(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -72,22 +72,20 @@ func (_m *User) Get(_a0 context.Context, _a1 uuid.UUID) (model.UserHTTP, error)
 	return r0, r1
 }
 
-// GetAll provides a mock function with given fields: _a0
-func (_m *User) GetAll(_a0 context.Context) ([]model.UserHTTP, error) {
-	ret := _m.Called(_a0)
+// List provides a mock function with given fields: _a0, _a1
+func (_m *User) List(_a0 context.Context, _a1 model.UserFilter) (model.UserPage, error) {
+	ret := _m.Called(_a0, _a1)
 
-	var r0 []model.UserHTTP
-	if rf, ok := ret.Get(0).(func(context.Context) []model.UserHTTP); ok {
-		r0 = rf(_a0)
+	var r0 model.UserPage
+	if rf, ok := ret.Get(0).(func(context.Context, model.UserFilter) model.UserPage); ok {
+		r0 = rf(_a0, _a1)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]model.UserHTTP)
-		}
+		r0 = ret.Get(0).(model.UserPage)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, model.UserFilter) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -95,6 +93,34 @@ func (_m *User) GetAll(_a0 context.Context) ([]model.UserHTTP, error) {
 	return r0, r1
 }
 
+// Restore provides a mock function with given fields: _a0, _a1
+func (_m *User) Restore(_a0 context.Context, _a1 uuid.UUID) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SoftDelete provides a mock function with given fields: _a0, _a1
+func (_m *User) SoftDelete(_a0 context.Context, _a1 uuid.UUID) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Update provides a mock function with given fields: _a0, _a1
 func (_m *User) Update(_a0 context.Context, _a1 model.UserHTTP) (model.UserHTTP, error) {
 	ret := _m.Called(_a0, _a1)
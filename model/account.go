@@ -2,8 +2,22 @@ package model
 
 import "github.com/google/uuid"
 
+// DefaultCurrency is given to accounts opened without one
+const DefaultCurrency = "USD"
+
+// Account balance may go below zero down to -OverdraftLimit, both are in minor units of the
+// ISO 4217 Currency, e.g. cents. Version grows with every change, updates carrying a version apply
+// only to that version.
 type Account struct {
-	ID      uuid.UUID `json:"id,omitempty"`
-	UserID  uuid.UUID `json:"user_id,omitempty"`
-	Balance int       `json:"balance"`
+	ID             uuid.UUID `json:"id,omitempty"`
+	UserID         uuid.UUID `json:"user_id,omitempty"`
+	Currency       string    `json:"currency,omitempty"`
+	Balance        int64     `json:"balance"`
+	OverdraftLimit int64     `json:"overdraft_limit"`
+	Version        int64     `json:"version"`
+}
+
+// BalanceChange is a deposit or withdrawal amount, always positive
+type BalanceChange struct {
+	Amount int64 `json:"amount"`
 }
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// resources the audit trail is kept for, each service records its own
+const (
+	AuditResourceAccount = "account"
+	AuditResourceUser    = "user"
+)
+
+// operations recorded in the audit trail
+const (
+	AuditCreate     = "create"
+	AuditUpdate     = "update"
+	AuditDelete     = "delete"
+	AuditSoftDelete = "soft_delete"
+	AuditRestore    = "restore"
+	AuditTransfer   = "transfer"
+	AuditDeposit    = "deposit"
+	AuditWithdraw   = "withdraw"
+)
+
+// AuditEntry records who changed a resource and how. Before and After are the resource
+// as json around the change, creates have no Before and deletes no After.
+type AuditEntry struct {
+	ID         uuid.UUID       `json:"id"`
+	Time       time.Time       `json:"time"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Operation  string          `json:"operation"`
+	Resource   string          `json:"resource"`
+	ResourceID uuid.UUID       `json:"resource_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditFilter selects audit entries recorded in [From, To), zero fields are not applied
+type AuditFilter struct {
+	Resource   string
+	ResourceID uuid.UUID
+	Actor      string
+	Operation  string
+	From       time.Time
+	To         time.Time
+	PageSize   int
+	PageToken  string
+}
+
+type AuditPage struct {
+	Entries       []AuditEntry `json:"entries"`
+	NextPageToken string       `json:"next_page_token,omitempty"`
+}
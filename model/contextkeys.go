@@ -5,4 +5,13 @@ type ContextKey string
 const (
 	NameKey             ContextKey = "name"
 	ContextKeyRequestID ContextKey = "requestID"
+	IdentityKey         ContextKey = "identity"
+)
+
+// gRPC metadata keys the request id and the caller identity are passed in
+const (
+	MetadataRequestID = "requestid"
+	MetadataUserID    = "userid"
+	MetadataRoles     = "roles"
+	MetadataName      = "callername"
 )
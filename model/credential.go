@@ -0,0 +1,19 @@
+package model
+
+import "github.com/google/uuid"
+
+// Credential links a login name to the user it was registered for
+type Credential struct {
+	Name         string
+	UserID       uuid.UUID
+	PasswordHash string
+	Role         string
+	// Disabled credentials of deleted users can not log in or refresh tokens
+	Disabled bool
+}
+
+type PasswordChange struct {
+	Name        string `json:"name"`
+	Password    string `json:"password"`
+	NewPassword string `json:"new_password"`
+}
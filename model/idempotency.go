@@ -0,0 +1,10 @@
+package model
+
+import "net/http"
+
+// StoredResponse is the first response to a request with an idempotency key, retries get it again
+type StoredResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
@@ -0,0 +1,30 @@
+package model
+
+import "github.com/google/uuid"
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Identity is the caller the gateway authenticated from the access token
+type Identity struct {
+	Name   string
+	UserID uuid.UUID
+	Roles  []string
+}
+
+func (i Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RoleChange is the body of the admin request granting a role to a login name
+type RoleChange struct {
+	Role string `json:"role"`
+}
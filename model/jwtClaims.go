@@ -3,7 +3,8 @@ package model
 import "github.com/golang-jwt/jwt"
 
 type JWTUserClaims struct {
-	Name       string `json:"name"`
-	KeyVersion string `json:"keyVersion"`
+	Name       string   `json:"name"`
+	KeyVersion string   `json:"keyVersion"`
+	Roles      []string `json:"roles,omitempty"`
 	jwt.StandardClaims
 }
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EntryType string
+
+const (
+	EntryDeposit     EntryType = "deposit"
+	EntryWithdrawal  EntryType = "withdrawal"
+	EntryTransferIn  EntryType = "transfer_in"
+	EntryTransferOut EntryType = "transfer_out"
+	EntryAdjustment  EntryType = "adjustment"
+	// a force closed account leaves its balance to the closing entry, an undone close reopens it
+	EntryClosing   EntryType = "closing"
+	EntryReopening EntryType = "reopening"
+)
+
+// LedgerEntry is one balance change, Balance is the account balance right after it
+type LedgerEntry struct {
+	ID        uuid.UUID `json:"id"`
+	AccountID uuid.UUID `json:"account_id"`
+	Type      EntryType `json:"type"`
+	Amount    int64     `json:"amount"`
+	Balance   int64     `json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TransactionFilter selects ledger entries of an account created in [From, To),
+// zero From or To leaves that side of the range open
+type TransactionFilter struct {
+	AccountID uuid.UUID
+	From      time.Time
+	To        time.Time
+	PageSize  int
+	PageToken string
+}
+
+type TransactionPage struct {
+	Transactions  []LedgerEntry `json:"transactions"`
+	NextPageToken string        `json:"next_page_token,omitempty"`
+}
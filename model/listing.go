@@ -0,0 +1,51 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Sort orders of user and account lists, prefixed with "-" they sort descending.
+// Items with equal keys are ordered by id, so pages never overlap.
+const (
+	SortByID      = "id"
+	SortByName    = "name"
+	SortByBalance = "balance"
+)
+
+// ParseSort splits a sort order into the field and the direction
+func ParseSort(sort string) (field string, desc bool) {
+	if strings.HasPrefix(sort, "-") {
+		return sort[1:], true
+	}
+
+	return sort, false
+}
+
+type UserFilter struct {
+	NamePrefix string
+	Sort       string
+	PageSize   int
+	PageToken  string
+}
+
+type UserPage struct {
+	Users         []UserHTTP `json:"users"`
+	NextPageToken string     `json:"next_page_token,omitempty"`
+}
+
+// AccountFilter selects accounts, nil bounds and zero UserID are not applied
+type AccountFilter struct {
+	UserID     uuid.UUID
+	MinBalance *int64
+	MaxBalance *int64
+	Sort       string
+	PageSize   int
+	PageToken  string
+}
+
+type AccountPage struct {
+	Accounts      []Account `json:"accounts"`
+	NextPageToken string    `json:"next_page_token,omitempty"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is stored by the hash of the opaque token handed out, every refresh
+// replaces the token with a new one of the same family
+type RefreshToken struct {
+	Hash            string
+	Family          uuid.UUID
+	Name            string
+	ExpiresAt       time.Time
+	AccessJTI       string
+	AccessExpiresAt time.Time
+}
+
+// RevokedToken is an access token id that must be rejected until the token expires
+type RevokedToken struct {
+	JTI       string    `json:"jti"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RevokedTokens struct {
+	Revoked []RevokedToken `json:"revoked"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import "github.com/google/uuid"
+
+// Transfer moves Amount minor units of the From currency, To is credited with Credit minor units
+// of its own currency at Rate. Credit and Rate are filled in by the account service.
+type Transfer struct {
+	From           uuid.UUID `json:"from"`
+	To             uuid.UUID `json:"to"`
+	Amount         int64     `json:"amount"`
+	IdempotencyKey string    `json:"idempotency_key,omitempty"`
+	Credit         int64     `json:"-"`
+	Rate           string    `json:"-"`
+}
+
+// SameRequest tells whether a retry asks for the same transfer, the rate may have changed since
+func (t Transfer) SameRequest(other Transfer) bool {
+	return t.From == other.From && t.To == other.To && t.Amount == other.Amount && t.IdempotencyKey == other.IdempotencyKey
+}
+
+// TransferResult is Replayed when a retry got the result of the transfer first made with its idempotency key
+type TransferResult struct {
+	From     Account `json:"from"`
+	To       Account `json:"to"`
+	Credit   int64   `json:"credit"`
+	Rate     string  `json:"rate"`
+	Replayed bool    `json:"-"`
+}
@@ -2,7 +2,9 @@ package model
 
 import "github.com/google/uuid"
 
+// UserHTTP Version grows with every update, updates carrying a version apply only to that version
 type UserHTTP struct {
-	ID   uuid.UUID `json:"id,omitempty"`
-	Name string    `json:"name,omitempty"`
+	ID      uuid.UUID `json:"id,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Version int64     `json:"version,omitempty"`
 }
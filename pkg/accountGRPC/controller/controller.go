@@ -3,69 +3,39 @@ package accountgrpccontroller
 import (
 	"context"
 	"fmt"
-	log "github.com/sirupsen/logrus"
-	"google.golang.org/grpc/metadata"
 
 	"github.com/google/uuid"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	customerrors "github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/model"
 	pb "github.com/stasBigunenko/monorepa/pkg/accountGRPC/proto"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 )
 
-type LoggingService interface {
-	WriteLog(ctx context.Context, message string)
-}
-
 type AccountGRPCСontroller struct {
-	client         pb.AccountGRPCServiceClient
-	loggingService LoggingService
+	client pb.AccountGRPCServiceClient
+	logger loggingservice.Logger
 }
 
-func New(cli pb.AccountGRPCServiceClient, loggingService LoggingService) *AccountGRPCСontroller {
+func New(cli pb.AccountGRPCServiceClient, logger loggingservice.Logger) *AccountGRPCСontroller {
 	return &AccountGRPCСontroller{
-		client:         cli,
-		loggingService: loggingService,
-	}
-}
-
-func (s AccountGRPCСontroller) formatError(err error, message string) error {
-	st, ok := status.FromError(err)
-	if !ok {
-		return fmt.Errorf("%s, failed to parse status or not a grpc error type: %w", message, err)
-	}
-
-	switch st.Code() {
-	case codes.NotFound:
-		return fmt.Errorf("%s: %w", message, customerrors.NotFound)
-	case codes.AlreadyExists:
-		return fmt.Errorf("%s: %w", message, customerrors.AlreadyExists)
-	case codes.DeadlineExceeded:
-		return fmt.Errorf("%s: %w", message, customerrors.DeadlineExceeded)
+		client: cli,
+		logger: logger.With("component", "grpc client"),
 	}
-
-	return fmt.Errorf("%s: %s", message, err.Error())
 }
 
-func (s AccountGRPCСontroller) CreateAccount(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command CreateAccount received...")
+func (s AccountGRPCСontroller) CreateAccount(ctx context.Context, userID uuid.UUID, currency string) (uuid.UUID, error) {
+	s.logger.Debug(ctx, "command received", "method", "CreateAccount")
 
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
-	}
-
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
-
-	resp, err := s.client.CreateAccount(c, &pb.UserID{
-		UserID: userID.String(),
+	resp, err := s.client.CreateAccount(ctx, &pb.NewAccount{
+		UserID:   userID.String(),
+		Currency: currency,
 	})
 
 	if err != nil {
-		return uuid.Nil, s.formatError(err, "failed to create account")
+		return uuid.Nil, customerrors.FromStatus(err, "failed to create account")
 	}
 
 	id, err := uuid.Parse(resp.Id)
@@ -77,21 +47,14 @@ func (s AccountGRPCСontroller) CreateAccount(ctx context.Context, userID uuid.U
 }
 
 func (s AccountGRPCСontroller) GetAccount(ctx context.Context, id uuid.UUID) (model.Account, error) {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command GetAccount received...")
-
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
-	}
-
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
+	s.logger.Debug(ctx, "command received", "method", "GetAccount")
 
-	resp, err := s.client.GetAccount(c, &pb.AccountID{
+	resp, err := s.client.GetAccount(ctx, &pb.AccountID{
 		Id: id.String(),
 	})
 
 	if err != nil {
-		return model.Account{}, s.formatError(err, "failed to get account")
+		return model.Account{}, customerrors.FromStatus(err, "failed to get account")
 	}
 
 	accountID, err := uuid.Parse(resp.Id)
@@ -105,28 +68,24 @@ func (s AccountGRPCСontroller) GetAccount(ctx context.Context, id uuid.UUID) (m
 	}
 
 	return model.Account{
-		ID:      accountID,
-		UserID:  userID,
-		Balance: int(resp.Balance),
+		ID:             accountID,
+		UserID:         userID,
+		Balance:        resp.Balance,
+		OverdraftLimit: resp.OverdraftLimit,
+		Version:        resp.Version,
+		Currency:       resp.Currency,
 	}, nil
 }
 
 func (s AccountGRPCСontroller) GetUserAccounts(ctx context.Context, userID uuid.UUID) ([]model.Account, error) {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command GetUserAccounts received...")
+	s.logger.Debug(ctx, "command received", "method", "GetUserAccounts")
 
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
-	}
-
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
-
-	resp, err := s.client.GetUserAccounts(c, &pb.UserID{
+	resp, err := s.client.GetUserAccounts(ctx, &pb.UserID{
 		UserID: userID.String(),
 	})
 
 	if err != nil {
-		return nil, s.formatError(err, "failed to get all user accounts")
+		return nil, customerrors.FromStatus(err, "failed to get all user accounts")
 	}
 
 	accounts := []model.Account{}
@@ -142,92 +101,281 @@ func (s AccountGRPCСontroller) GetUserAccounts(ctx context.Context, userID uuid
 		}
 
 		accounts = append(accounts, model.Account{
-			ID:      accountID,
-			UserID:  userID,
-			Balance: int(account.Balance),
+			ID:             accountID,
+			UserID:         userID,
+			Balance:        account.Balance,
+			OverdraftLimit: account.OverdraftLimit,
+			Version:        account.Version,
+			Currency:       account.Currency,
 		})
 	}
 
 	return accounts, nil
 }
 
-func (s AccountGRPCСontroller) GetAllAccounts(ctx context.Context) ([]model.Account, error) {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command GetAllAccounts received...")
+func (s AccountGRPCСontroller) GetAllAccounts(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+	s.logger.Debug(ctx, "command received", "method", "GetAllAccounts")
 
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
+	in := &pb.AccountFilter{
+		Sort:      filter.Sort,
+		PageSize:  int32(filter.PageSize),
+		PageToken: filter.PageToken,
+	}
+	if filter.UserID != uuid.Nil {
+		in.UserID = filter.UserID.String()
+	}
+	if filter.MinBalance != nil {
+		in.MinBalance = wrapperspb.Int64(*filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		in.MaxBalance = wrapperspb.Int64(*filter.MaxBalance)
 	}
 
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
-
-	resp, err := s.client.GetAllUsers(c, &emptypb.Empty{})
+	resp, err := s.client.GetAllUsers(ctx, in)
 	if err != nil {
-		return nil, s.formatError(err, "failed to get all accounts")
+		return model.AccountPage{}, customerrors.FromStatus(err, "failed to get all accounts")
 	}
 
 	accounts := []model.Account{}
 	for _, account := range resp.Accounts {
 		accountID, err := uuid.Parse(account.Id)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse account ID: %s, %w", err.Error(), customerrors.ParseError)
+			return model.AccountPage{}, fmt.Errorf("failed to parse account ID: %s, %w", err.Error(), customerrors.ParseError)
 		}
 
 		userID, err := uuid.Parse(account.UserID)
+		if err != nil {
+			return model.AccountPage{}, fmt.Errorf("failed to parse account ID: %s, %w", err.Error(), customerrors.ParseError)
+		}
+
+		accounts = append(accounts, model.Account{
+			ID:             accountID,
+			UserID:         userID,
+			Balance:        account.Balance,
+			OverdraftLimit: account.OverdraftLimit,
+			Version:        account.Version,
+			Currency:       account.Currency,
+		})
+	}
+
+	return model.AccountPage{Accounts: accounts, NextPageToken: resp.NextPageToken}, nil
+}
+
+func (s AccountGRPCСontroller) UpdateAccount(ctx context.Context, account model.Account) (model.Account, error) {
+	s.logger.Debug(ctx, "command received", "method", "UpdateAccount")
+
+	resp, err := s.client.UpdateAccount(ctx, &pb.Account{
+		Id:             account.ID.String(),
+		UserID:         account.UserID.String(),
+		Balance:        account.Balance,
+		OverdraftLimit: account.OverdraftLimit,
+		Version:        account.Version,
+		Currency:       account.Currency,
+	})
+
+	if err != nil {
+		return model.Account{}, customerrors.FromStatus(err, "failed to update account")
+	}
+
+	return parseAccount(resp)
+}
+
+func (s AccountGRPCСontroller) DeleteAccount(ctx context.Context, id uuid.UUID) error {
+	s.logger.Debug(ctx, "command received", "method", "DeleteAccount")
+
+	_, err := s.client.DeleteAccount(ctx, &pb.AccountID{
+		Id: id.String(),
+	})
+
+	if err != nil {
+		return customerrors.FromStatus(err, "failed to delete account")
+	}
+
+	return nil
+}
+
+// CloseUserAccounts removes all accounts of a user, without force it fails
+// with AccountsNotEmpty while any of them has a non-zero balance
+func (s AccountGRPCСontroller) CloseUserAccounts(ctx context.Context, userID uuid.UUID, force bool) ([]model.Account, error) {
+	s.logger.Debug(ctx, "command received", "method", "CloseUserAccounts")
+
+	resp, err := s.client.CloseUserAccounts(ctx, &pb.CloseAccounts{
+		UserID: userID.String(),
+		Force:  force,
+	})
+	if err != nil {
+		return nil, customerrors.FromStatus(err, "failed to close accounts")
+	}
+
+	accounts := []model.Account{}
+	for _, account := range resp.Accounts {
+		accountID, err := uuid.Parse(account.Id)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse account ID: %s, %w", err.Error(), customerrors.ParseError)
 		}
 
 		accounts = append(accounts, model.Account{
-			ID:      accountID,
-			UserID:  userID,
-			Balance: int(account.Balance),
+			ID:             accountID,
+			UserID:         userID,
+			Balance:        account.Balance,
+			OverdraftLimit: account.OverdraftLimit,
+			Version:        account.Version,
+			Currency:       account.Currency,
 		})
 	}
 
 	return accounts, nil
 }
 
-func (s AccountGRPCСontroller) UpdateAccount(ctx context.Context, account model.Account) error {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command UpdateAccount received...")
+func (s AccountGRPCСontroller) RestoreAccounts(ctx context.Context, accounts []model.Account) error {
+	s.logger.Debug(ctx, "command received", "method", "RestoreAccounts")
+
+	in := &pb.AllAccounts{}
+	for _, account := range accounts {
+		in.Accounts = append(in.Accounts, &pb.Account{
+			Id:             account.ID.String(),
+			UserID:         account.UserID.String(),
+			Balance:        account.Balance,
+			OverdraftLimit: account.OverdraftLimit,
+			Version:        account.Version,
+			Currency:       account.Currency,
+		})
+	}
 
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
+	if _, err := s.client.RestoreAccounts(ctx, in); err != nil {
+		return customerrors.FromStatus(err, "failed to restore accounts")
 	}
 
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
+	return nil
+}
+
+func (s AccountGRPCСontroller) Transfer(ctx context.Context, transfer model.Transfer) (model.TransferResult, error) {
+	s.logger.Debug(ctx, "command received", "method", "Transfer")
 
-	_, err := s.client.UpdateAccount(c, &pb.Account{
-		Id:      account.ID.String(),
-		UserID:  account.UserID.String(),
-		Balance: int32(account.Balance),
+	resp, err := s.client.Transfer(ctx, &pb.TransferRequest{
+		FromID:         transfer.From.String(),
+		ToID:           transfer.To.String(),
+		Amount:         transfer.Amount,
+		IdempotencyKey: transfer.IdempotencyKey,
 	})
 
 	if err != nil {
-		return s.formatError(err, "failed to update account")
+		return model.TransferResult{}, customerrors.FromStatus(err, "failed to transfer")
 	}
 
-	return nil
+	from, err := parseAccount(resp.From)
+	if err != nil {
+		return model.TransferResult{}, err
+	}
+
+	to, err := parseAccount(resp.To)
+	if err != nil {
+		return model.TransferResult{}, err
+	}
+
+	return model.TransferResult{
+		From:   from,
+		To:     to,
+		Credit: resp.Credit,
+		Rate:   resp.Rate,
+	}, nil
 }
 
-func (s AccountGRPCСontroller) DeleteAccount(ctx context.Context, id uuid.UUID) error {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command DeleteAccount received...")
+func (s AccountGRPCСontroller) ListTransactions(ctx context.Context, filter model.TransactionFilter) (model.TransactionPage, error) {
+	s.logger.Debug(ctx, "command received", "method", "ListTransactions")
+
+	in := &pb.TransactionFilter{
+		AccountID: filter.AccountID.String(),
+		PageSize:  int32(filter.PageSize),
+		PageToken: filter.PageToken,
+	}
+	if !filter.From.IsZero() {
+		in.From = timestamppb.New(filter.From)
+	}
+	if !filter.To.IsZero() {
+		in.To = timestamppb.New(filter.To)
+	}
 
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
+	resp, err := s.client.ListTransactions(ctx, in)
+	if err != nil {
+		return model.TransactionPage{}, customerrors.FromStatus(err, "failed to list transactions")
 	}
 
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
+	page := model.TransactionPage{
+		Transactions:  []model.LedgerEntry{},
+		NextPageToken: resp.NextPageToken,
+	}
+	for _, tr := range resp.Transactions {
+		id, err := uuid.Parse(tr.Id)
+		if err != nil {
+			return model.TransactionPage{}, fmt.Errorf("failed to parse transaction ID: %s, %w", err.Error(), customerrors.ParseError)
+		}
 
-	_, err := s.client.DeleteAccount(c, &pb.AccountID{
-		Id: id.String(),
+		accountID, err := uuid.Parse(tr.AccountID)
+		if err != nil {
+			return model.TransactionPage{}, fmt.Errorf("failed to parse account ID: %s, %w", err.Error(), customerrors.ParseError)
+		}
+
+		page.Transactions = append(page.Transactions, model.LedgerEntry{
+			ID:        id,
+			AccountID: accountID,
+			Type:      model.EntryType(tr.Type),
+			Amount:    tr.Amount,
+			Balance:   tr.Balance,
+			CreatedAt: tr.CreatedAt.AsTime(),
+		})
+	}
+
+	return page, nil
+}
+
+func (s AccountGRPCСontroller) Deposit(ctx context.Context, id uuid.UUID, amount int64) (model.Account, error) {
+	s.logger.Debug(ctx, "command received", "method", "Deposit")
+
+	resp, err := s.client.Deposit(ctx, &pb.BalanceChange{
+		Id:     id.String(),
+		Amount: amount,
 	})
 
 	if err != nil {
-		return s.formatError(err, "failed to delete account")
+		return model.Account{}, customerrors.FromStatus(err, "failed to deposit")
 	}
 
-	return nil
+	return parseAccount(resp)
+}
+
+func (s AccountGRPCСontroller) Withdraw(ctx context.Context, id uuid.UUID, amount int64) (model.Account, error) {
+	s.logger.Debug(ctx, "command received", "method", "Withdraw")
+
+	resp, err := s.client.Withdraw(ctx, &pb.BalanceChange{
+		Id:     id.String(),
+		Amount: amount,
+	})
+
+	if err != nil {
+		return model.Account{}, customerrors.FromStatus(err, "failed to withdraw")
+	}
+
+	return parseAccount(resp)
+}
+
+func parseAccount(account *pb.Account) (model.Account, error) {
+	accountID, err := uuid.Parse(account.GetId())
+	if err != nil {
+		return model.Account{}, fmt.Errorf("failed to parse account ID: %s, %w", err.Error(), customerrors.ParseError)
+	}
+
+	userID, err := uuid.Parse(account.GetUserID())
+	if err != nil {
+		return model.Account{}, fmt.Errorf("failed to parse account ID: %s, %w", err.Error(), customerrors.ParseError)
+	}
+
+	return model.Account{
+		ID:             accountID,
+		UserID:         userID,
+		Balance:        account.GetBalance(),
+		OverdraftLimit: account.GetOverdraftLimit(),
+		Version:        account.GetVersion(),
+		Currency:       account.GetCurrency(),
+	}, nil
 }
@@ -5,24 +5,25 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	customerrors "github.com/stasBigunenko/monorepa/customErrors"
 	mocks "github.com/stasBigunenko/monorepa/mocks/pkg/accountGRPC/proto"
 	"github.com/stasBigunenko/monorepa/model"
 	pb "github.com/stasBigunenko/monorepa/pkg/accountGRPC/proto"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-type MockLoggingService struct {
-}
-
-func (MockLoggingService) WriteLog(ctx context.Context, message string) {}
-
 func TestAccountGRPCСontroller_CreateAccount(t *testing.T) {
 	type fields struct {
-		client         pb.AccountGRPCServiceClient
-		loggingService LoggingService
+		client pb.AccountGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	type args struct {
 		userID uuid.UUID
@@ -38,7 +39,7 @@ func TestAccountGRPCСontroller_CreateAccount(t *testing.T) {
 			name: "CreateAccount OK",
 			fields: fields{
 				client: mocks.MockAccountGrpcServiceClient{
-					MockCreateAccount: func(ctx context.Context, in *pb.UserID, opts ...grpc.CallOption) (*pb.Account, error) {
+					MockCreateAccount: func(ctx context.Context, in *pb.NewAccount, opts ...grpc.CallOption) (*pb.Account, error) {
 						it := pb.Account{
 							Id:      "00000000-0000-0000-0000-000000000000",
 							UserID:  "00000000-0000-0000-0000-000000000000",
@@ -59,7 +60,7 @@ func TestAccountGRPCСontroller_CreateAccount(t *testing.T) {
 			name: "CreateAccount !OK",
 			fields: fields{
 				client: mocks.MockAccountGrpcServiceClient{
-					MockCreateAccount: func(ctx context.Context, in *pb.UserID, opts ...grpc.CallOption) (*pb.Account, error) {
+					MockCreateAccount: func(ctx context.Context, in *pb.NewAccount, opts ...grpc.CallOption) (*pb.Account, error) {
 						return nil, errors.New("err")
 					},
 				},
@@ -76,10 +77,10 @@ func TestAccountGRPCСontroller_CreateAccount(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := AccountGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
-			got, err := s.CreateAccount(context.Background(), tt.args.userID)
+			got, err := s.CreateAccount(context.Background(), tt.args.userID, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("AccountGRPCСontroller.CreateAccount() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -93,8 +94,8 @@ func TestAccountGRPCСontroller_CreateAccount(t *testing.T) {
 
 func TestAccountGRPCСontroller_GetAccount(t *testing.T) {
 	type fields struct {
-		client         pb.AccountGRPCServiceClient
-		loggingService LoggingService
+		client pb.AccountGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	type args struct {
 		id uuid.UUID
@@ -151,8 +152,8 @@ func TestAccountGRPCСontroller_GetAccount(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := AccountGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
 			got, err := s.GetAccount(context.Background(), tt.args.id)
 			if (err != nil) != tt.wantErr {
@@ -168,8 +169,8 @@ func TestAccountGRPCСontroller_GetAccount(t *testing.T) {
 
 func TestAccountGRPCСontroller_GetUserAccounts(t *testing.T) {
 	type fields struct {
-		client         pb.AccountGRPCServiceClient
-		loggingService LoggingService
+		client pb.AccountGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	type args struct {
 		id uuid.UUID
@@ -229,8 +230,8 @@ func TestAccountGRPCСontroller_GetUserAccounts(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := AccountGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
 			got, err := s.GetUserAccounts(context.Background(), tt.args.id)
 			if (err != nil) != tt.wantErr {
@@ -246,21 +247,25 @@ func TestAccountGRPCСontroller_GetUserAccounts(t *testing.T) {
 
 func TestAccountGRPCСontroller_GetAllAccounts(t *testing.T) {
 	type fields struct {
-		client         pb.AccountGRPCServiceClient
-		loggingService LoggingService
+		client pb.AccountGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	tests := []struct {
 		name    string
 		fields  fields
-		want    []model.Account
+		want    model.AccountPage
 		wantErr bool
 	}{
 		{
 			name: "GetAllAccounts OK",
 			fields: fields{
 				client: mocks.MockAccountGrpcServiceClient{
-					MockGetAllUsers: func(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*pb.AllAccounts, error) {
+					MockGetAllUsers: func(ctx context.Context, in *pb.AccountFilter, opts ...grpc.CallOption) (*pb.AllAccounts, error) {
+						if in.UserID != "00000000-0000-0000-0000-000000000001" || in.MinBalance.GetValue() != 10 || in.MaxBalance != nil {
+							return nil, errors.New("filter not passed")
+						}
 						it := pb.AllAccounts{
+							NextPageToken: "next",
 							Accounts: []*pb.Account{
 								{
 									Id:      "00000000-0000-0000-0000-000000000000",
@@ -273,11 +278,14 @@ func TestAccountGRPCСontroller_GetAllAccounts(t *testing.T) {
 					},
 				},
 			},
-			want: []model.Account{
-				{
-					ID:      uuid.MustParse("00000000-0000-0000-0000-000000000000"),
-					UserID:  uuid.MustParse("00000000-0000-0000-0000-000000000000"),
-					Balance: 0},
+			want: model.AccountPage{
+				Accounts: []model.Account{
+					{
+						ID:      uuid.MustParse("00000000-0000-0000-0000-000000000000"),
+						UserID:  uuid.MustParse("00000000-0000-0000-0000-000000000000"),
+						Balance: 0},
+				},
+				NextPageToken: "next",
 			},
 			wantErr: false,
 		},
@@ -285,22 +293,26 @@ func TestAccountGRPCСontroller_GetAllAccounts(t *testing.T) {
 			name: "GetAllAccounts !OK",
 			fields: fields{
 				client: mocks.MockAccountGrpcServiceClient{
-					MockGetAllUsers: func(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*pb.AllAccounts, error) {
+					MockGetAllUsers: func(ctx context.Context, in *pb.AccountFilter, opts ...grpc.CallOption) (*pb.AllAccounts, error) {
 						return &pb.AllAccounts{}, errors.New("err")
 					},
 				},
 			},
-			want:    nil,
+			want:    model.AccountPage{},
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := AccountGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
-			got, err := s.GetAllAccounts(context.Background())
+			min := int64(10)
+			got, err := s.GetAllAccounts(context.Background(), model.AccountFilter{
+				UserID:     uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+				MinBalance: &min,
+			})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("AccountGRPCСontroller.GetAllAccounts() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -314,8 +326,8 @@ func TestAccountGRPCСontroller_GetAllAccounts(t *testing.T) {
 
 func TestAccountGRPCСontroller_UpdateAccount(t *testing.T) {
 	type fields struct {
-		client         pb.AccountGRPCServiceClient
-		loggingService LoggingService
+		client pb.AccountGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	type args struct {
 		Account model.Account
@@ -367,10 +379,10 @@ func TestAccountGRPCСontroller_UpdateAccount(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := AccountGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
-			if err := s.UpdateAccount(context.Background(), tt.args.Account); (err != nil) != tt.wantErr {
+			if _, err := s.UpdateAccount(context.Background(), tt.args.Account); (err != nil) != tt.wantErr {
 				t.Errorf("AccountGRPCСontroller.UpdateAccount() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -379,8 +391,8 @@ func TestAccountGRPCСontroller_UpdateAccount(t *testing.T) {
 
 func TestAccountGRPCСontroller_DeleteAccount(t *testing.T) {
 	type fields struct {
-		client         pb.AccountGRPCServiceClient
-		loggingService LoggingService
+		client pb.AccountGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	type args struct {
 		id uuid.UUID
@@ -424,8 +436,8 @@ func TestAccountGRPCСontroller_DeleteAccount(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := AccountGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
 			if err := s.DeleteAccount(context.Background(), tt.args.id); (err != nil) != tt.wantErr {
 				t.Errorf("AccountGRPCСontroller.DeleteAccount() error = %v, wantErr %v", err, tt.wantErr)
@@ -433,3 +445,228 @@ func TestAccountGRPCСontroller_DeleteAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestAccountGRPCСontroller_Transfer(t *testing.T) {
+	type fields struct {
+		client pb.AccountGRPCServiceClient
+		logger loggingservice.Logger
+	}
+	type args struct {
+		transfer model.Transfer
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    model.TransferResult
+		wantErr error
+	}{
+		{
+			name: "Transfer OK",
+			fields: fields{
+				client: mocks.MockAccountGrpcServiceClient{
+					MockTransfer: func(ctx context.Context, in *pb.TransferRequest, opts ...grpc.CallOption) (*pb.TransferResult, error) {
+						return &pb.TransferResult{
+							From: &pb.Account{Id: in.FromID, UserID: in.FromID, Balance: 0},
+							To:   &pb.Account{Id: in.ToID, UserID: in.ToID, Balance: in.Amount},
+						}, nil
+					},
+				},
+			},
+			args: args{
+				transfer: model.Transfer{
+					From:   uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+					To:     uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+					Amount: 10,
+				},
+			},
+			want: model.TransferResult{
+				From: model.Account{
+					ID:     uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+					UserID: uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+				},
+				To: model.Account{
+					ID:      uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+					UserID:  uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+					Balance: 10,
+				},
+			},
+		},
+		{
+			name: "Transfer insufficient funds",
+			fields: fields{
+				client: mocks.MockAccountGrpcServiceClient{
+					MockTransfer: func(ctx context.Context, in *pb.TransferRequest, opts ...grpc.CallOption) (*pb.TransferResult, error) {
+						return nil, status.Error(codes.FailedPrecondition, "insufficient funds")
+					},
+				},
+			},
+			args: args{
+				transfer: model.Transfer{},
+			},
+			want:    model.TransferResult{},
+			wantErr: customerrors.FailedPrecondition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := AccountGRPCСontroller{
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
+			}
+			got, err := s.Transfer(context.Background(), tt.args.transfer)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("AccountGRPCСontroller.Transfer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AccountGRPCСontroller.Transfer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccountGRPCСontroller_ListTransactions(t *testing.T) {
+	created := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	type fields struct {
+		client pb.AccountGRPCServiceClient
+		logger loggingservice.Logger
+	}
+	type args struct {
+		filter model.TransactionFilter
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    model.TransactionPage
+		wantErr bool
+	}{
+		{
+			name: "ListTransactions OK",
+			fields: fields{
+				client: mocks.MockAccountGrpcServiceClient{
+					MockListTransactions: func(ctx context.Context, in *pb.TransactionFilter, opts ...grpc.CallOption) (*pb.Transactions, error) {
+						if in.From != nil || !in.To.AsTime().Equal(created) {
+							return nil, status.Error(codes.InvalidArgument, "unexpected range")
+						}
+						return &pb.Transactions{
+							Transactions: []*pb.Transaction{{
+								Id:        "00000000-0000-0000-0000-000000000001",
+								AccountID: in.AccountID,
+								Type:      "deposit",
+								Amount:    10,
+								Balance:   10,
+								CreatedAt: timestamppb.New(created),
+							}},
+							NextPageToken: "1",
+						}, nil
+					},
+				},
+			},
+			args: args{
+				filter: model.TransactionFilter{
+					AccountID: uuid.MustParse("00000000-0000-0000-0000-000000000000"),
+					To:        created,
+				},
+			},
+			want: model.TransactionPage{
+				Transactions: []model.LedgerEntry{{
+					ID:        uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+					AccountID: uuid.MustParse("00000000-0000-0000-0000-000000000000"),
+					Type:      model.EntryDeposit,
+					Amount:    10,
+					Balance:   10,
+					CreatedAt: created,
+				}},
+				NextPageToken: "1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ListTransactions !OK",
+			fields: fields{
+				client: mocks.MockAccountGrpcServiceClient{
+					MockListTransactions: func(ctx context.Context, in *pb.TransactionFilter, opts ...grpc.CallOption) (*pb.Transactions, error) {
+						return nil, errors.New("err")
+					},
+				},
+			},
+			args: args{
+				filter: model.TransactionFilter{},
+			},
+			want:    model.TransactionPage{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := AccountGRPCСontroller{
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
+			}
+			got, err := s.ListTransactions(context.Background(), tt.args.filter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AccountGRPCСontroller.ListTransactions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AccountGRPCСontroller.ListTransactions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccountGRPCСontroller_Withdraw(t *testing.T) {
+	id := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	s := AccountGRPCСontroller{
+		client: mocks.MockAccountGrpcServiceClient{
+			MockWithdraw: func(ctx context.Context, in *pb.BalanceChange, opts ...grpc.CallOption) (*pb.Account, error) {
+				if in.Amount > 10 {
+					return nil, status.Error(codes.FailedPrecondition, "insufficient funds")
+				}
+				return &pb.Account{Id: in.Id, UserID: in.Id, Balance: 10 - in.Amount}, nil
+			},
+		},
+		logger: loggingservice.LoggingService{},
+	}
+
+	got, err := s.Withdraw(context.Background(), id, 4)
+	if err != nil {
+		t.Fatalf("AccountGRPCСontroller.Withdraw() error = %v", err)
+	}
+	if want := (model.Account{ID: id, UserID: id, Balance: 6}); !reflect.DeepEqual(got, want) {
+		t.Errorf("AccountGRPCСontroller.Withdraw() = %v, want %v", got, want)
+	}
+
+	if _, err = s.Withdraw(context.Background(), id, 11); !errors.Is(err, customerrors.FailedPrecondition) {
+		t.Errorf("AccountGRPCСontroller.Withdraw() error = %v, want %v", err, customerrors.FailedPrecondition)
+	}
+}
+
+func TestAccountGRPCСontroller_CloseUserAccounts(t *testing.T) {
+	userID := uuid.New()
+	accountID := uuid.New()
+	var force bool
+	s := New(mocks.MockAccountGrpcServiceClient{
+		MockCloseUserAccounts: func(ctx context.Context, in *pb.CloseAccounts, opts ...grpc.CallOption) (*pb.AllAccounts, error) {
+			force = in.Force
+			if !in.Force {
+				return nil, customerrors.ToStatus(customerrors.AccountsNotEmpty, "")
+			}
+			return &pb.AllAccounts{Accounts: []*pb.Account{{Id: accountID.String(), UserID: in.UserID, Balance: 7}}}, nil
+		},
+	}, loggingservice.LoggingService{})
+
+	_, err := s.CloseUserAccounts(context.Background(), userID, false)
+	if !errors.Is(err, customerrors.AccountsNotEmpty) {
+		t.Errorf("CloseUserAccounts() error = %v, want %v", err, customerrors.AccountsNotEmpty)
+	}
+
+	got, err := s.CloseUserAccounts(context.Background(), userID, true)
+	want := []model.Account{{ID: accountID, UserID: userID, Balance: 7}}
+	if err != nil || !force || !reflect.DeepEqual(got, want) {
+		t.Errorf("CloseUserAccounts() = %v, %v, want %v", got, err, want)
+	}
+}
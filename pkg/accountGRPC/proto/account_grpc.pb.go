@@ -21,10 +21,16 @@ const _ = grpc.SupportPackageIsVersion7
 type AccountGRPCServiceClient interface {
 	GetAccount(ctx context.Context, in *AccountID, opts ...grpc.CallOption) (*Account, error)
 	GetUserAccounts(ctx context.Context, in *UserID, opts ...grpc.CallOption) (*AllAccounts, error)
-	GetAllUsers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*AllAccounts, error)
-	CreateAccount(ctx context.Context, in *UserID, opts ...grpc.CallOption) (*Account, error)
+	GetAllUsers(ctx context.Context, in *AccountFilter, opts ...grpc.CallOption) (*AllAccounts, error)
+	CreateAccount(ctx context.Context, in *NewAccount, opts ...grpc.CallOption) (*Account, error)
 	UpdateAccount(ctx context.Context, in *Account, opts ...grpc.CallOption) (*Account, error)
 	DeleteAccount(ctx context.Context, in *AccountID, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	CloseUserAccounts(ctx context.Context, in *CloseAccounts, opts ...grpc.CallOption) (*AllAccounts, error)
+	RestoreAccounts(ctx context.Context, in *AllAccounts, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResult, error)
+	ListTransactions(ctx context.Context, in *TransactionFilter, opts ...grpc.CallOption) (*Transactions, error)
+	Deposit(ctx context.Context, in *BalanceChange, opts ...grpc.CallOption) (*Account, error)
+	Withdraw(ctx context.Context, in *BalanceChange, opts ...grpc.CallOption) (*Account, error)
 }
 
 type accountGRPCServiceClient struct {
@@ -53,7 +59,7 @@ func (c *accountGRPCServiceClient) GetUserAccounts(ctx context.Context, in *User
 	return out, nil
 }
 
-func (c *accountGRPCServiceClient) GetAllUsers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*AllAccounts, error) {
+func (c *accountGRPCServiceClient) GetAllUsers(ctx context.Context, in *AccountFilter, opts ...grpc.CallOption) (*AllAccounts, error) {
 	out := new(AllAccounts)
 	err := c.cc.Invoke(ctx, "/accountGRPC.AccountGRPCService/GetAllUsers", in, out, opts...)
 	if err != nil {
@@ -62,7 +68,7 @@ func (c *accountGRPCServiceClient) GetAllUsers(ctx context.Context, in *emptypb.
 	return out, nil
 }
 
-func (c *accountGRPCServiceClient) CreateAccount(ctx context.Context, in *UserID, opts ...grpc.CallOption) (*Account, error) {
+func (c *accountGRPCServiceClient) CreateAccount(ctx context.Context, in *NewAccount, opts ...grpc.CallOption) (*Account, error) {
 	out := new(Account)
 	err := c.cc.Invoke(ctx, "/accountGRPC.AccountGRPCService/CreateAccount", in, out, opts...)
 	if err != nil {
@@ -89,16 +95,76 @@ func (c *accountGRPCServiceClient) DeleteAccount(ctx context.Context, in *Accoun
 	return out, nil
 }
 
+func (c *accountGRPCServiceClient) CloseUserAccounts(ctx context.Context, in *CloseAccounts, opts ...grpc.CallOption) (*AllAccounts, error) {
+	out := new(AllAccounts)
+	err := c.cc.Invoke(ctx, "/accountGRPC.AccountGRPCService/CloseUserAccounts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountGRPCServiceClient) RestoreAccounts(ctx context.Context, in *AllAccounts, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/accountGRPC.AccountGRPCService/RestoreAccounts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountGRPCServiceClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResult, error) {
+	out := new(TransferResult)
+	err := c.cc.Invoke(ctx, "/accountGRPC.AccountGRPCService/Transfer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountGRPCServiceClient) ListTransactions(ctx context.Context, in *TransactionFilter, opts ...grpc.CallOption) (*Transactions, error) {
+	out := new(Transactions)
+	err := c.cc.Invoke(ctx, "/accountGRPC.AccountGRPCService/ListTransactions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountGRPCServiceClient) Deposit(ctx context.Context, in *BalanceChange, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/accountGRPC.AccountGRPCService/Deposit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountGRPCServiceClient) Withdraw(ctx context.Context, in *BalanceChange, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/accountGRPC.AccountGRPCService/Withdraw", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AccountGRPCServiceServer is the server API for AccountGRPCService service.
 // All implementations must embed UnimplementedAccountGRPCServiceServer
 // for forward compatibility
 type AccountGRPCServiceServer interface {
 	GetAccount(context.Context, *AccountID) (*Account, error)
 	GetUserAccounts(context.Context, *UserID) (*AllAccounts, error)
-	GetAllUsers(context.Context, *emptypb.Empty) (*AllAccounts, error)
-	CreateAccount(context.Context, *UserID) (*Account, error)
+	GetAllUsers(context.Context, *AccountFilter) (*AllAccounts, error)
+	CreateAccount(context.Context, *NewAccount) (*Account, error)
 	UpdateAccount(context.Context, *Account) (*Account, error)
 	DeleteAccount(context.Context, *AccountID) (*emptypb.Empty, error)
+	CloseUserAccounts(context.Context, *CloseAccounts) (*AllAccounts, error)
+	RestoreAccounts(context.Context, *AllAccounts) (*emptypb.Empty, error)
+	Transfer(context.Context, *TransferRequest) (*TransferResult, error)
+	ListTransactions(context.Context, *TransactionFilter) (*Transactions, error)
+	Deposit(context.Context, *BalanceChange) (*Account, error)
+	Withdraw(context.Context, *BalanceChange) (*Account, error)
 	mustEmbedUnimplementedAccountGRPCServiceServer()
 }
 
@@ -112,10 +178,10 @@ func (UnimplementedAccountGRPCServiceServer) GetAccount(context.Context, *Accoun
 func (UnimplementedAccountGRPCServiceServer) GetUserAccounts(context.Context, *UserID) (*AllAccounts, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUserAccounts not implemented")
 }
-func (UnimplementedAccountGRPCServiceServer) GetAllUsers(context.Context, *emptypb.Empty) (*AllAccounts, error) {
+func (UnimplementedAccountGRPCServiceServer) GetAllUsers(context.Context, *AccountFilter) (*AllAccounts, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetAllUsers not implemented")
 }
-func (UnimplementedAccountGRPCServiceServer) CreateAccount(context.Context, *UserID) (*Account, error) {
+func (UnimplementedAccountGRPCServiceServer) CreateAccount(context.Context, *NewAccount) (*Account, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateAccount not implemented")
 }
 func (UnimplementedAccountGRPCServiceServer) UpdateAccount(context.Context, *Account) (*Account, error) {
@@ -124,6 +190,24 @@ func (UnimplementedAccountGRPCServiceServer) UpdateAccount(context.Context, *Acc
 func (UnimplementedAccountGRPCServiceServer) DeleteAccount(context.Context, *AccountID) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteAccount not implemented")
 }
+func (UnimplementedAccountGRPCServiceServer) CloseUserAccounts(context.Context, *CloseAccounts) (*AllAccounts, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseUserAccounts not implemented")
+}
+func (UnimplementedAccountGRPCServiceServer) RestoreAccounts(context.Context, *AllAccounts) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreAccounts not implemented")
+}
+func (UnimplementedAccountGRPCServiceServer) Transfer(context.Context, *TransferRequest) (*TransferResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedAccountGRPCServiceServer) ListTransactions(context.Context, *TransactionFilter) (*Transactions, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTransactions not implemented")
+}
+func (UnimplementedAccountGRPCServiceServer) Deposit(context.Context, *BalanceChange) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deposit not implemented")
+}
+func (UnimplementedAccountGRPCServiceServer) Withdraw(context.Context, *BalanceChange) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Withdraw not implemented")
+}
 func (UnimplementedAccountGRPCServiceServer) mustEmbedUnimplementedAccountGRPCServiceServer() {}
 
 // UnsafeAccountGRPCServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -174,7 +258,7 @@ func _AccountGRPCService_GetUserAccounts_Handler(srv interface{}, ctx context.Co
 }
 
 func _AccountGRPCService_GetAllUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(emptypb.Empty)
+	in := new(AccountFilter)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -186,13 +270,13 @@ func _AccountGRPCService_GetAllUsers_Handler(srv interface{}, ctx context.Contex
 		FullMethod: "/accountGRPC.AccountGRPCService/GetAllUsers",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AccountGRPCServiceServer).GetAllUsers(ctx, req.(*emptypb.Empty))
+		return srv.(AccountGRPCServiceServer).GetAllUsers(ctx, req.(*AccountFilter))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
 func _AccountGRPCService_CreateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UserID)
+	in := new(NewAccount)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -204,7 +288,7 @@ func _AccountGRPCService_CreateAccount_Handler(srv interface{}, ctx context.Cont
 		FullMethod: "/accountGRPC.AccountGRPCService/CreateAccount",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AccountGRPCServiceServer).CreateAccount(ctx, req.(*UserID))
+		return srv.(AccountGRPCServiceServer).CreateAccount(ctx, req.(*NewAccount))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -245,6 +329,114 @@ func _AccountGRPCService_DeleteAccount_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AccountGRPCService_CloseUserAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseAccounts)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountGRPCServiceServer).CloseUserAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/accountGRPC.AccountGRPCService/CloseUserAccounts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountGRPCServiceServer).CloseUserAccounts(ctx, req.(*CloseAccounts))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountGRPCService_RestoreAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllAccounts)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountGRPCServiceServer).RestoreAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/accountGRPC.AccountGRPCService/RestoreAccounts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountGRPCServiceServer).RestoreAccounts(ctx, req.(*AllAccounts))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountGRPCService_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountGRPCServiceServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/accountGRPC.AccountGRPCService/Transfer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountGRPCServiceServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountGRPCService_ListTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionFilter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountGRPCServiceServer).ListTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/accountGRPC.AccountGRPCService/ListTransactions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountGRPCServiceServer).ListTransactions(ctx, req.(*TransactionFilter))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountGRPCService_Deposit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BalanceChange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountGRPCServiceServer).Deposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/accountGRPC.AccountGRPCService/Deposit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountGRPCServiceServer).Deposit(ctx, req.(*BalanceChange))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountGRPCService_Withdraw_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BalanceChange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountGRPCServiceServer).Withdraw(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/accountGRPC.AccountGRPCService/Withdraw",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountGRPCServiceServer).Withdraw(ctx, req.(*BalanceChange))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AccountGRPCService_ServiceDesc is the grpc.ServiceDesc for AccountGRPCService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -276,6 +468,30 @@ var AccountGRPCService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteAccount",
 			Handler:    _AccountGRPCService_DeleteAccount_Handler,
 		},
+		{
+			MethodName: "CloseUserAccounts",
+			Handler:    _AccountGRPCService_CloseUserAccounts_Handler,
+		},
+		{
+			MethodName: "RestoreAccounts",
+			Handler:    _AccountGRPCService_RestoreAccounts_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _AccountGRPCService_Transfer_Handler,
+		},
+		{
+			MethodName: "ListTransactions",
+			Handler:    _AccountGRPCService_ListTransactions_Handler,
+		},
+		{
+			MethodName: "Deposit",
+			Handler:    _AccountGRPCService_Deposit_Handler,
+		},
+		{
+			MethodName: "Withdraw",
+			Handler:    _AccountGRPCService_Withdraw_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "account.proto",
@@ -2,102 +2,88 @@ package accountgrpcserver
 
 import (
 	"context"
-	"errors"
-	log "github.com/sirupsen/logrus"
 	"github.com/stasBigunenko/monorepa/customErrors"
-	"google.golang.org/grpc/metadata"
 
 	"github.com/google/uuid"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/stasBigunenko/monorepa/model"
 	pb "github.com/stasBigunenko/monorepa/pkg/accountGRPC/proto"
 	"github.com/stasBigunenko/monorepa/service/account"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 )
 
-type LoggingService interface {
-	WriteLog(ctx context.Context, message string)
-}
-
 type AccountServerGRPC struct {
 	pb.UnimplementedAccountGRPCServiceServer
 
-	service        account.AccInterface
-	loggingService LoggingService
+	service account.AccInterface
+	logger  loggingservice.Logger
 }
 
-func NewAccountGRPCServer(s account.AccInterface, loggingService LoggingService) AccountServerGRPC {
+func NewAccountGRPCServer(s account.AccInterface, logger loggingservice.Logger) AccountServerGRPC {
 	return AccountServerGRPC{
-		service:        s,
-		loggingService: loggingService,
+		service: s,
+		logger:  logger.With("component", "grpc server"),
 	}
 }
 
 func (s AccountServerGRPC) GetAccount(c context.Context, in *pb.AccountID) (*pb.Account, error) {
-
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
-	}
-
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
-	}
-
-	s.loggingService.WriteLog(c, "GRPC Server: Command GetAccount received...")
+	s.logger.Debug(c, "command received", "method", "GetAccount")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
 
 	id, err := uuid.Parse(in.Id)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "failed to parse uuid in grpc server")
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
 	res, err := s.service.Get(c, id)
 	if err != nil {
-		return &pb.Account{}, status.Error(codes.DataLoss, "internal problems")
+		return nil, customErrors.ToStatus(err, "internal storage problem")
+	}
+
+	if err = checkOwner(caller, res.UserID); err != nil {
+		return nil, err
 	}
 
 	return &pb.Account{
-		Id:      res.ID.String(),
-		UserID:  res.UserID.String(),
-		Balance: int32(res.Balance),
+		Id:             res.ID.String(),
+		UserID:         res.UserID.String(),
+		Balance:        res.Balance,
+		OverdraftLimit: res.OverdraftLimit,
+		Version:        res.Version,
+		Currency:       res.Currency,
 	}, nil
 }
 
 func (s AccountServerGRPC) GetUserAccounts(c context.Context, in *pb.UserID) (*pb.AllAccounts, error) {
-
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
-	}
-
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
-	}
-
-	s.loggingService.WriteLog(c, "GRPC Server: Command GetUserAccounts received...")
+	s.logger.Debug(c, "command received", "method", "GetUserAccounts")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
 
 	userID, err := uuid.Parse(in.UserID)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "failed to parse uuid in grpc server")
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
+	}
+
+	if err = checkOwner(caller, userID); err != nil {
+		return nil, err
 	}
 
 	users, err := s.service.GetUser(c, userID)
 	if err != nil {
-		if errors.Is(err, customErrors.NotFound) {
-			return nil, status.Error(codes.NotFound, "not found")
-		}
-		return nil, status.Error(codes.Internal, "internal storage problem")
+		return nil, customErrors.ToStatus(err, "internal storage problem")
 	}
 
 	all := []*pb.Account{}
 
 	for _, val := range users {
 		all = append(all, &pb.Account{
-			Id:      val.ID.String(),
-			UserID:  val.UserID.String(),
-			Balance: int32(val.Balance),
+			Id:             val.ID.String(),
+			UserID:         val.UserID.String(),
+			Balance:        val.Balance,
+			OverdraftLimit: val.OverdraftLimit,
+			Version:        val.Version,
+			Currency:       val.Currency,
 		})
 	}
 	return &pb.AllAccounts{
@@ -105,134 +91,376 @@ func (s AccountServerGRPC) GetUserAccounts(c context.Context, in *pb.UserID) (*p
 	}, nil
 }
 
-func (s AccountServerGRPC) GetAllUsers(c context.Context, in *emptypb.Empty) (*pb.AllAccounts, error) {
+func (s AccountServerGRPC) GetAllUsers(c context.Context, in *pb.AccountFilter) (*pb.AllAccounts, error) {
+	s.logger.Debug(c, "command received", "method", "GetAllUsers")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
 
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
+	if err := checkAdmin(caller); err != nil {
+		return nil, err
 	}
 
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
+	filter := model.AccountFilter{
+		Sort:      in.Sort,
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	if in.UserID != "" {
+		userID, err := uuid.Parse(in.UserID)
+		if err != nil {
+			return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
+		}
+		filter.UserID = userID
+	}
+	if in.MinBalance != nil {
+		min := in.MinBalance.Value
+		filter.MinBalance = &min
+	}
+	if in.MaxBalance != nil {
+		max := in.MaxBalance.Value
+		filter.MaxBalance = &max
 	}
 
-	s.loggingService.WriteLog(c, "GRPC Server: Command GetAllUsers received...")
-
-	users, err := s.service.GetAll(c)
+	page, err := s.service.List(c, filter)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to get the list of users")
+		return nil, customErrors.ToStatus(err, "failed to get the list of accounts")
 	}
 
 	all := []*pb.Account{}
 
-	for _, val := range users {
+	for _, val := range page.Accounts {
 		all = append(all, &pb.Account{
-			Id:      val.ID.String(),
-			UserID:  val.UserID.String(),
-			Balance: int32(val.Balance),
+			Id:             val.ID.String(),
+			UserID:         val.UserID.String(),
+			Balance:        val.Balance,
+			OverdraftLimit: val.OverdraftLimit,
+			Version:        val.Version,
+			Currency:       val.Currency,
 		})
 	}
 	return &pb.AllAccounts{
-		Accounts: all,
+		Accounts:      all,
+		NextPageToken: page.NextPageToken,
 	}, nil
 }
-func (s AccountServerGRPC) CreateAccount(c context.Context, in *pb.UserID) (*pb.Account, error) {
+func (s AccountServerGRPC) CreateAccount(c context.Context, in *pb.NewAccount) (*pb.Account, error) {
+	s.logger.Debug(c, "command received", "method", "CreateAccount")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
 
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
+	userID, err := uuid.Parse(in.UserID)
+	if err != nil {
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
+	if err = checkOwner(caller, userID); err != nil {
+		return nil, err
 	}
 
-	s.loggingService.WriteLog(c, "GRPC Server: Command CreateAccount received...")
+	res, err := s.service.Create(c, userID, in.Currency)
+	if err != nil {
+		return nil, customErrors.ToStatus(err, "failed to create account")
+	}
+
+	return &pb.Account{
+		Id:             res.ID.String(),
+		UserID:         res.UserID.String(),
+		Balance:        res.Balance,
+		OverdraftLimit: res.OverdraftLimit,
+		Version:        res.Version,
+		Currency:       res.Currency,
+	}, nil
+}
+func (s AccountServerGRPC) UpdateAccount(c context.Context, in *pb.Account) (*pb.Account, error) {
+	s.logger.Debug(c, "command received", "method", "UpdateAccount")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
+
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
+	}
 
 	userID, err := uuid.Parse(in.UserID)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "failed to parse uuid in grpc server")
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
-	res, err := s.service.Create(c, userID)
+	version, err := s.checkAccountUpdate(c, caller, id, in.OverdraftLimit, in.Version)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create user")
+		return nil, err
+	}
+	// the account can not be handed over to another user either, the nil owner keeps the stored one
+	if userID != uuid.Nil {
+		if err = checkOwner(caller, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// the balance is not taken from the update, it changes through deposits, withdrawals and transfers only
+	m := model.Account{
+		ID:             id,
+		UserID:         userID,
+		OverdraftLimit: in.OverdraftLimit,
+		Version:        version,
+		Currency:       in.Currency,
+	}
+
+	res, err := s.service.Update(c, m)
+	if err != nil {
+		return nil, customErrors.ToStatus(err, "internal storage problem")
 	}
 
 	return &pb.Account{
-		Id:      res.ID.String(),
-		UserID:  res.UserID.String(),
-		Balance: int32(res.Balance),
+		Id:             res.ID.String(),
+		UserID:         res.UserID.String(),
+		Balance:        res.Balance,
+		OverdraftLimit: res.OverdraftLimit,
+		Version:        res.Version,
+		Currency:       res.Currency,
 	}, nil
 }
-func (s AccountServerGRPC) UpdateAccount(c context.Context, in *pb.Account) (*pb.Account, error) {
+func (s AccountServerGRPC) DeleteAccount(c context.Context, in *pb.AccountID) (*emptypb.Empty, error) {
+	s.logger.Debug(c, "command received", "method", "DeleteAccount")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
 
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
+	if err = s.checkAccountOwner(c, caller, id); err != nil {
+		return nil, err
 	}
 
-	s.loggingService.WriteLog(c, "GRPC Server: Command UpdateAccount received...")
-
-	id, err := uuid.Parse(in.Id)
+	err = s.service.Delete(c, id)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to parse uuid")
+		return nil, customErrors.ToStatus(err, "internal storage problem")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s AccountServerGRPC) CloseUserAccounts(c context.Context, in *pb.CloseAccounts) (*pb.AllAccounts, error) {
+	s.logger.Debug(c, "command received", "method", "CloseUserAccounts")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
+
+	if err := checkAdmin(caller); err != nil {
+		return nil, err
 	}
 
 	userID, err := uuid.Parse(in.UserID)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to parse uuid")
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
-	m := model.Account{
-		ID:      id,
-		UserID:  userID,
-		Balance: int(in.Balance),
+	closed, err := s.service.CloseUserAccounts(c, userID, in.Force)
+	if err != nil {
+		return nil, customErrors.ToStatus(err, "internal storage problem")
 	}
 
-	res, err := s.service.Update(c, m)
-	if err != nil {
-		if errors.Is(err, customErrors.NotFound) {
-			return nil, status.Error(codes.NotFound, "not found")
+	all := []*pb.Account{}
+
+	for _, val := range closed {
+		all = append(all, &pb.Account{
+			Id:             val.ID.String(),
+			UserID:         val.UserID.String(),
+			Balance:        val.Balance,
+			OverdraftLimit: val.OverdraftLimit,
+			Version:        val.Version,
+			Currency:       val.Currency,
+		})
+	}
+	return &pb.AllAccounts{
+		Accounts: all,
+	}, nil
+}
+
+func (s AccountServerGRPC) RestoreAccounts(c context.Context, in *pb.AllAccounts) (*emptypb.Empty, error) {
+	s.logger.Debug(c, "command received", "method", "RestoreAccounts")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
+
+	if err := checkAdmin(caller); err != nil {
+		return nil, err
+	}
+
+	accounts := []model.Account{}
+	for _, val := range in.Accounts {
+		id, err := uuid.Parse(val.Id)
+		if err != nil {
+			return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 		}
-		return nil, status.Error(codes.Internal, "internal storage problem")
+
+		userID, err := uuid.Parse(val.UserID)
+		if err != nil {
+			return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
+		}
+
+		accounts = append(accounts, model.Account{
+			ID:             id,
+			UserID:         userID,
+			Balance:        val.Balance,
+			OverdraftLimit: val.OverdraftLimit,
+			Version:        val.Version,
+			Currency:       val.Currency,
+		})
 	}
 
-	return &pb.Account{
-		Id:      res.ID.String(),
-		UserID:  res.UserID.String(),
-		Balance: int32(res.Balance),
+	if err := s.service.RestoreAccounts(c, accounts); err != nil {
+		return nil, customErrors.ToStatus(err, "internal storage problem")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s AccountServerGRPC) Transfer(c context.Context, in *pb.TransferRequest) (*pb.TransferResult, error) {
+	s.logger.Debug(c, "command received", "method", "Transfer")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
+
+	from, err := uuid.Parse(in.FromID)
+	if err != nil {
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
+	}
+
+	to, err := uuid.Parse(in.ToID)
+	if err != nil {
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
+	}
+
+	// money may be sent to anyone, but only taken from an own account
+	if err = s.checkAccountOwner(c, caller, from); err != nil {
+		return nil, err
+	}
+
+	res, err := s.service.Transfer(c, model.Transfer{
+		From:           from,
+		To:             to,
+		Amount:         in.Amount,
+		IdempotencyKey: in.IdempotencyKey,
+	})
+	if err != nil {
+		return nil, customErrors.ToStatus(err, "internal storage problem")
+	}
+
+	return &pb.TransferResult{
+		From: &pb.Account{
+			Id:             res.From.ID.String(),
+			UserID:         res.From.UserID.String(),
+			Balance:        res.From.Balance,
+			OverdraftLimit: res.From.OverdraftLimit,
+			Version:        res.From.Version,
+			Currency:       res.From.Currency,
+		},
+		To: &pb.Account{
+			Id:             res.To.ID.String(),
+			UserID:         res.To.UserID.String(),
+			Balance:        res.To.Balance,
+			OverdraftLimit: res.To.OverdraftLimit,
+			Version:        res.To.Version,
+			Currency:       res.To.Currency,
+		},
+		Credit: res.Credit,
+		Rate:   res.Rate,
 	}, nil
 }
-func (s AccountServerGRPC) DeleteAccount(c context.Context, in *pb.AccountID) (*emptypb.Empty, error) {
 
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
+func (s AccountServerGRPC) ListTransactions(c context.Context, in *pb.TransactionFilter) (*pb.Transactions, error) {
+	s.logger.Debug(c, "command received", "method", "ListTransactions")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
+
+	id, err := uuid.Parse(in.AccountID)
+	if err != nil {
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
+	if err = s.checkAccountOwner(c, caller, id); err != nil {
+		return nil, err
 	}
 
-	s.loggingService.WriteLog(c, "GRPC Server: Command DeleteAccount received...")
+	filter := model.TransactionFilter{
+		AccountID: id,
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	if in.From != nil {
+		filter.From = in.From.AsTime()
+	}
+	if in.To != nil {
+		filter.To = in.To.AsTime()
+	}
+
+	page, err := s.service.ListTransactions(c, filter)
+	if err != nil {
+		return nil, customErrors.ToStatus(err, "internal storage problem")
+	}
+
+	all := []*pb.Transaction{}
+
+	for _, val := range page.Transactions {
+		all = append(all, &pb.Transaction{
+			Id:        val.ID.String(),
+			AccountID: val.AccountID.String(),
+			Type:      string(val.Type),
+			Amount:    val.Amount,
+			Balance:   val.Balance,
+			CreatedAt: timestamppb.New(val.CreatedAt),
+		})
+	}
+	return &pb.Transactions{
+		Transactions:  all,
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
+func (s AccountServerGRPC) Deposit(c context.Context, in *pb.BalanceChange) (*pb.Account, error) {
+	s.logger.Debug(c, "command received", "method", "Deposit")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
 
 	id, err := uuid.Parse(in.Id)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to parse uuid")
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
-	err = s.service.Delete(c, id)
+	if err = s.checkAccountOwner(c, caller, id); err != nil {
+		return nil, err
+	}
+
+	res, err := s.service.Deposit(c, id, in.Amount)
 	if err != nil {
-		if errors.Is(err, customErrors.NotFound) {
-			return nil, status.Error(codes.NotFound, "not found")
-		}
-		return nil, status.Error(codes.Internal, "internal storage problem")
+		return nil, customErrors.ToStatus(err, "internal storage problem")
 	}
 
-	return &emptypb.Empty{}, nil
+	return &pb.Account{
+		Id:             res.ID.String(),
+		UserID:         res.UserID.String(),
+		Balance:        res.Balance,
+		OverdraftLimit: res.OverdraftLimit,
+		Version:        res.Version,
+		Currency:       res.Currency,
+	}, nil
+}
+
+func (s AccountServerGRPC) Withdraw(c context.Context, in *pb.BalanceChange) (*pb.Account, error) {
+	s.logger.Debug(c, "command received", "method", "Withdraw")
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
+
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
+	}
+
+	if err = s.checkAccountOwner(c, caller, id); err != nil {
+		return nil, err
+	}
+
+	res, err := s.service.Withdraw(c, id, in.Amount)
+	if err != nil {
+		return nil, customErrors.ToStatus(err, "internal storage problem")
+	}
+
+	return &pb.Account{
+		Id:             res.ID.String(),
+		UserID:         res.UserID.String(),
+		Balance:        res.Balance,
+		OverdraftLimit: res.OverdraftLimit,
+		Version:        res.Version,
+		Currency:       res.Currency,
+	}, nil
 }
@@ -4,56 +4,83 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
+	"github.com/stasBigunenko/monorepa/customErrors"
 	mockAccInt "github.com/stasBigunenko/monorepa/mocks/service/account"
 	"github.com/stasBigunenko/monorepa/model"
 	pb "github.com/stasBigunenko/monorepa/pkg/accountGRPC/proto"
 	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 )
 
+// adminContext carries an admin caller, as the interceptor reads it from the gateway metadata
+func adminContext() context.Context {
+	return context.WithValue(context.Background(), model.IdentityKey, model.Identity{UserID: uuid.New(), Roles: []string{model.RoleAdmin}})
+}
+
 func Test_Create(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(mockAccInt.AccInterface)
 	uuidS := "00000000-0000-0000-0000-000000000000"
 	id, _ := uuid.Parse(uuidS)
-	m := model.Account{ID: id, UserID: id, Balance: 0}
-	ui.On("Create", mock.Anything, id).Return(m, nil)
+	m := model.Account{ID: id, UserID: id, Balance: 0, Currency: "EUR"}
+	ui.On("Create", mock.Anything, id, "EUR").Return(m, nil)
 
 	ui2 := new(mockAccInt.AccInterface)
-	ui.On("Create", mock.Anything, id).Return(nil, errors.New("err"))
+	ui2.On("Create", mock.Anything, id, "").Return(model.Account{}, customErrors.UnknownOwner)
+
+	ui3 := new(mockAccInt.AccInterface)
+	ui3.On("Create", mock.Anything, id, "EUR").Return(model.Account{}, customErrors.CurrencyTaken)
 
 	tests := []struct {
 		name    string
 		stor    *mockAccInt.AccInterface
-		param   *pb.UserID
+		param   *pb.NewAccount
 		want    *pb.Account
 		wantErr codes.Code
 	}{
 		{
 			name:  "Everything good",
-			param: &pb.UserID{UserID: uuidS},
+			param: &pb.NewAccount{UserID: uuidS, Currency: "EUR"},
 			stor:  ui,
-			want:  &pb.Account{Id: uuidS, UserID: uuidS, Balance: 0},
+			want:  &pb.Account{Id: uuidS, UserID: uuidS, Balance: 0, Currency: "EUR"},
 		},
 		{
 			name:    "Wrong uuid",
-			param:   &pb.UserID{UserID: "000000-0000-0000-0000-000"},
+			param:   &pb.NewAccount{UserID: "000000-0000-0000-0000-000"},
 			stor:    ui2,
 			want:    nil,
 			wantErr: codes.InvalidArgument,
 		},
+		{
+			name:    "Unknown owner",
+			param:   &pb.NewAccount{UserID: uuidS},
+			stor:    ui2,
+			want:    nil,
+			wantErr: codes.FailedPrecondition,
+		},
+		{
+			name:    "Second account in a currency",
+			param:   &pb.NewAccount{UserID: uuidS, Currency: "EUR"},
+			stor:    ui3,
+			want:    nil,
+			wantErr: codes.AlreadyExists,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			u := NewAccountGRPCServer(tc.stor, loggingService)
-			got, err := u.CreateAccount(context.Background(), tc.param)
+			got, err := u.CreateAccount(adminContext(), tc.param)
 			if (err != nil) && status.Code(err) != tc.wantErr {
 				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
 				return
@@ -64,17 +91,20 @@ func Test_Create(t *testing.T) {
 }
 
 func Test_Get(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(mockAccInt.AccInterface)
 	uuidS := "00000000-0000-0000-0000-000000000000"
 	id, _ := uuid.Parse(uuidS)
 	m := model.Account{ID: id, UserID: id, Balance: 0}
-	ui.On("Get", context.Background(), id).Return(m, nil)
+	ui.On("Get", mock.Anything, id).Return(m, nil)
 
 	ui2 := new(mockAccInt.AccInterface)
 	m1 := model.UserHTTP{}
 	ui.On("Get", mock.Anything, mock.Anything).Return(m1, errors.New("ss"))
 
+	ui3 := new(mockAccInt.AccInterface)
+	ui3.On("Get", mock.Anything, id).Return(model.Account{}, customErrors.NotFound)
+
 	tests := []struct {
 		name    string
 		stor    *mockAccInt.AccInterface
@@ -94,11 +124,17 @@ func Test_Get(t *testing.T) {
 			param: &pb.AccountID{Id: "00000000-0000-00-000000000000"},
 			want:  &pb.Account{},
 		},
+		{
+			name:    "Not found",
+			stor:    ui3,
+			param:   &pb.AccountID{Id: "00000000-0000-0000-0000-000000000000"},
+			wantErr: codes.NotFound,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			u := NewAccountGRPCServer(tc.stor, loggingService)
-			got, err := u.GetAccount(context.Background(), tc.param)
+			got, err := u.GetAccount(adminContext(), tc.param)
 			if err != nil && status.Code(err) != tc.wantErr {
 				assert.Error(t, err)
 				return
@@ -109,11 +145,11 @@ func Test_Get(t *testing.T) {
 }
 
 func TestAccount_Delete(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(mockAccInt.AccInterface)
 	uuidS := "00000000-0000-0000-0000-000000000000"
 	id, _ := uuid.Parse(uuidS)
-	ui.On("Delete", context.Background(), id).Return(nil)
+	ui.On("Delete", mock.Anything, id).Return(nil)
 
 	tests := []struct {
 		name  string
@@ -137,7 +173,7 @@ func TestAccount_Delete(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			u := NewAccountGRPCServer(tc.stor, loggingService)
-			got, err := u.DeleteAccount(context.Background(), tc.param)
+			got, err := u.DeleteAccount(adminContext(), tc.param)
 			if err != nil {
 				assert.Error(t, err)
 				return
@@ -148,7 +184,7 @@ func TestAccount_Delete(t *testing.T) {
 }
 
 func TestAccount_GetAllUser(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(mockAccInt.AccInterface)
 	uuidS := "00000000-0000-0000-0000-000000000000"
 	id, _ := uuid.Parse(uuidS)
@@ -163,12 +199,19 @@ func TestAccount_GetAllUser(t *testing.T) {
 	u2 := &pb.Account{Id: uuidS, UserID: uuidS, Balance: 13}
 	all := []*pb.Account{u1, u2}
 	aa := pb.AllAccounts{
-		Accounts: all,
+		Accounts:      all,
+		NextPageToken: "next",
 	}
-	ui.On("GetAll", context.Background()).Return(m, nil)
+	min := int64(10)
+	filter := model.AccountFilter{UserID: id, MinBalance: &min, Sort: "-balance", PageSize: 2}
+	in := &pb.AccountFilter{UserID: uuidS, MinBalance: wrapperspb.Int64(10), Sort: "-balance", PageSize: 2}
+	ui.On("List", mock.Anything, filter).Return(model.AccountPage{Accounts: m, NextPageToken: "next"}, nil)
 
 	ui2 := new(mockAccInt.AccInterface)
-	ui2.On("GetAll", context.Background()).Return(nil, errors.New("err"))
+	ui2.On("List", mock.Anything, filter).Return(model.AccountPage{}, errors.New("err"))
+
+	ui3 := new(mockAccInt.AccInterface)
+	ui3.On("List", mock.Anything, filter).Return(model.AccountPage{}, customErrors.InvalidArgument)
 
 	tests := []struct {
 		name    string
@@ -186,13 +229,18 @@ func TestAccount_GetAllUser(t *testing.T) {
 			stor:    ui2,
 			wantErr: codes.Internal,
 		},
+		{
+			name:    "Bad page token",
+			stor:    ui3,
+			wantErr: codes.InvalidArgument,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			u := NewAccountGRPCServer(tc.stor, loggingService)
-			got, err := u.GetAllUsers(context.Background(), &emptypb.Empty{})
-			if err != nil && status.Code(err) != tc.wantErr {
-				assert.Error(t, err)
+			got, err := u.GetAllUsers(adminContext(), in)
+			if tc.wantErr != codes.OK {
+				assert.Equal(t, tc.wantErr, status.Code(err))
 				return
 			}
 			assert.Equal(t, tc.want, got)
@@ -201,14 +249,18 @@ func TestAccount_GetAllUser(t *testing.T) {
 }
 
 func TestAccount_Update(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(mockAccInt.AccInterface)
 	idd := "00000000-0000-0000-0000-000000000000"
 	id, _ := uuid.Parse(idd)
 	m := model.Account{ID: id, UserID: id, Balance: 100}
-	ui.On("Update", context.Background(), m).Return(m, nil)
+	// the balance of the update is dropped, the stored one comes back
+	ui.On("Update", mock.Anything, model.Account{ID: id, UserID: id}).Return(m, nil)
 	ui2 := new(mockAccInt.AccInterface)
-	ui2.On("Update", context.Background(), m).Return(model.Account{}, errors.New("err"))
+	ui2.On("Update", mock.Anything, model.Account{ID: id, UserID: id}).Return(model.Account{}, errors.New("err"))
+	stale := model.Account{ID: id, UserID: id, Version: 2}
+	ui3 := new(mockAccInt.AccInterface)
+	ui3.On("Update", mock.Anything, stale).Return(model.Account{}, customErrors.VersionMismatch)
 
 	tests := []struct {
 		name    string
@@ -229,11 +281,17 @@ func TestAccount_Update(t *testing.T) {
 			param:   &pb.Account{Id: idd, UserID: idd, Balance: 100},
 			wantErr: codes.Internal,
 		},
+		{
+			name:    "Stale version",
+			stor:    ui3,
+			param:   &pb.Account{Id: idd, UserID: idd, Balance: 100, Version: 2},
+			wantErr: codes.Aborted,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			u := NewAccountGRPCServer(tc.stor, loggingService)
-			got, err := u.UpdateAccount(context.Background(), tc.param)
+			got, err := u.UpdateAccount(adminContext(), tc.param)
 			if (err != nil) && status.Code(err) != tc.wantErr {
 				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
 				return
@@ -243,8 +301,28 @@ func TestAccount_Update(t *testing.T) {
 	}
 }
 
+func TestAccount_UpdateByOwner(t *testing.T) {
+	owner := uuid.New()
+	id := uuid.New()
+	stored := model.Account{ID: id, UserID: owner, Balance: 100, OverdraftLimit: 50, Version: 3}
+	ctx := context.WithValue(context.Background(), model.IdentityKey, model.Identity{UserID: owner, Roles: []string{model.RoleUser}})
+
+	ui := new(mockAccInt.AccInterface)
+	ui.On("Get", mock.Anything, id).Return(stored, nil)
+	// the update of an owner applies to the version its limit was checked on
+	ui.On("Update", mock.Anything, model.Account{ID: id, OverdraftLimit: 50, Version: 3}).Return(stored, nil)
+	u := NewAccountGRPCServer(ui, loggingservice.LoggingService{})
+
+	_, err := u.UpdateAccount(ctx, &pb.Account{Id: id.String(), UserID: uuid.Nil.String(), OverdraftLimit: 50})
+	require.NoError(t, err)
+
+	_, err = u.UpdateAccount(ctx, &pb.Account{Id: id.String(), UserID: uuid.Nil.String(), OverdraftLimit: 1000})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	ui.AssertNumberOfCalls(t, "Update", 1)
+}
+
 func TestAccount_GetUserAccount(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(mockAccInt.AccInterface)
 	uuidS := "00000000-0000-0000-0000-000000000000"
 	id, _ := uuid.Parse(uuidS)
@@ -261,10 +339,10 @@ func TestAccount_GetUserAccount(t *testing.T) {
 	aa := pb.AllAccounts{
 		Accounts: all,
 	}
-	ui.On("GetUser", context.Background(), id).Return(m, nil)
+	ui.On("GetUser", mock.Anything, id).Return(m, nil)
 
 	ui2 := new(mockAccInt.AccInterface)
-	ui2.On("GetUser", context.Background(), id).Return(nil, errors.New("err"))
+	ui2.On("GetUser", mock.Anything, id).Return(nil, errors.New("err"))
 
 	tests := []struct {
 		name    string
@@ -290,7 +368,7 @@ func TestAccount_GetUserAccount(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			u := NewAccountGRPCServer(tc.stor, loggingService)
-			got, err := u.GetUserAccounts(context.Background(), tc.param)
+			got, err := u.GetUserAccounts(adminContext(), tc.param)
 			if err != nil && status.Code(err) != tc.wantErr {
 				assert.Error(t, err)
 				return
@@ -299,3 +377,235 @@ func TestAccount_GetUserAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestAccount_Transfer(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	fromS := "00000000-0000-0000-0000-000000000001"
+	toS := "00000000-0000-0000-0000-000000000002"
+	from, _ := uuid.Parse(fromS)
+	to, _ := uuid.Parse(toS)
+	tr := model.Transfer{From: from, To: to, Amount: 10, IdempotencyKey: "key"}
+	res := model.TransferResult{
+		From:   model.Account{ID: from, UserID: from, Balance: 0, Currency: "USD"},
+		To:     model.Account{ID: to, UserID: to, Balance: 1495, Currency: "JPY"},
+		Credit: 1495,
+		Rate:   "149.5",
+	}
+
+	ui := new(mockAccInt.AccInterface)
+	ui.On("Transfer", mock.Anything, tr).Return(res, nil)
+	ui2 := new(mockAccInt.AccInterface)
+	ui2.On("Transfer", mock.Anything, tr).Return(model.TransferResult{}, customErrors.InsufficientFunds)
+	ui3 := new(mockAccInt.AccInterface)
+	ui3.On("Transfer", mock.Anything, tr).Return(model.TransferResult{}, customErrors.NoExchangeRate)
+
+	tests := []struct {
+		name    string
+		stor    *mockAccInt.AccInterface
+		param   *pb.TransferRequest
+		want    *pb.TransferResult
+		wantErr codes.Code
+	}{
+		{
+			name:  "Everything ok",
+			stor:  ui,
+			param: &pb.TransferRequest{FromID: fromS, ToID: toS, Amount: 10, IdempotencyKey: "key"},
+			want: &pb.TransferResult{
+				From:   &pb.Account{Id: fromS, UserID: fromS, Balance: 0, Currency: "USD"},
+				To:     &pb.Account{Id: toS, UserID: toS, Balance: 1495, Currency: "JPY"},
+				Credit: 1495,
+				Rate:   "149.5",
+			},
+		},
+		{
+			name:    "Insufficient funds",
+			stor:    ui2,
+			param:   &pb.TransferRequest{FromID: fromS, ToID: toS, Amount: 10, IdempotencyKey: "key"},
+			wantErr: codes.FailedPrecondition,
+		},
+		{
+			name:    "No exchange rate",
+			stor:    ui3,
+			param:   &pb.TransferRequest{FromID: fromS, ToID: toS, Amount: 10, IdempotencyKey: "key"},
+			wantErr: codes.FailedPrecondition,
+		},
+		{
+			name:    "Wrong uuid",
+			stor:    ui,
+			param:   &pb.TransferRequest{FromID: "000000-0000", ToID: toS, Amount: 10},
+			wantErr: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u := NewAccountGRPCServer(tc.stor, loggingService)
+			got, err := u.Transfer(adminContext(), tc.param)
+			if status.Code(err) != tc.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestAccount_ListTransactions(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	uuidS := "00000000-0000-0000-0000-000000000000"
+	id, _ := uuid.Parse(uuidS)
+	created := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	filter := model.TransactionFilter{AccountID: id, From: created, PageSize: 1}
+	page := model.TransactionPage{
+		Transactions:  []model.LedgerEntry{{ID: id, AccountID: id, Type: model.EntryTransferIn, Amount: 10, Balance: 10, CreatedAt: created}},
+		NextPageToken: "1",
+	}
+
+	ui := new(mockAccInt.AccInterface)
+	ui.On("ListTransactions", mock.Anything, filter).Return(page, nil)
+	ui2 := new(mockAccInt.AccInterface)
+	ui2.On("ListTransactions", mock.Anything, filter).Return(model.TransactionPage{}, customErrors.NotFound)
+
+	tests := []struct {
+		name    string
+		stor    *mockAccInt.AccInterface
+		param   *pb.TransactionFilter
+		want    *pb.Transactions
+		wantErr codes.Code
+	}{
+		{
+			name:  "Everything ok",
+			stor:  ui,
+			param: &pb.TransactionFilter{AccountID: uuidS, From: timestamppb.New(created), PageSize: 1},
+			want: &pb.Transactions{
+				Transactions:  []*pb.Transaction{{Id: uuidS, AccountID: uuidS, Type: "transfer_in", Amount: 10, Balance: 10, CreatedAt: timestamppb.New(created)}},
+				NextPageToken: "1",
+			},
+		},
+		{
+			name:    "Not found",
+			stor:    ui2,
+			param:   &pb.TransactionFilter{AccountID: uuidS, From: timestamppb.New(created), PageSize: 1},
+			wantErr: codes.NotFound,
+		},
+		{
+			name:    "Wrong uuid",
+			stor:    ui,
+			param:   &pb.TransactionFilter{AccountID: "0000-0000"},
+			wantErr: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u := NewAccountGRPCServer(tc.stor, loggingService)
+			got, err := u.ListTransactions(adminContext(), tc.param)
+			if status.Code(err) != tc.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestAccount_DepositWithdraw(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	idS := "00000000-0000-0000-0000-000000000001"
+	id, _ := uuid.Parse(idS)
+
+	ui := new(mockAccInt.AccInterface)
+	ui.On("Deposit", mock.Anything, id, int64(10)).Return(model.Account{ID: id, UserID: id, Balance: 10, OverdraftLimit: 5}, nil)
+	ui.On("Withdraw", mock.Anything, id, int64(20)).Return(model.Account{}, customErrors.InsufficientFunds)
+	ui.On("Withdraw", mock.Anything, id, int64(0)).Return(model.Account{}, customErrors.InvalidAmount)
+
+	u := NewAccountGRPCServer(ui, loggingService)
+
+	got, err := u.Deposit(adminContext(), &pb.BalanceChange{Id: idS, Amount: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.Account{Id: idS, UserID: idS, Balance: 10, OverdraftLimit: 5}, got)
+
+	_, err = u.Withdraw(adminContext(), &pb.BalanceChange{Id: idS, Amount: 20})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = u.Withdraw(adminContext(), &pb.BalanceChange{Id: idS, Amount: 0})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = u.Deposit(adminContext(), &pb.BalanceChange{Id: "000000-0000", Amount: 10})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.ErrorIs(t, customErrors.FromStatus(err, "deposit"), customErrors.UUIDError, "the reason reaches the gateway")
+}
+
+func TestAccount_Ownership(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	owner := uuid.New()
+	other := uuid.New()
+	id := uuid.New()
+	acc := model.Account{ID: id, UserID: owner, Balance: 10}
+
+	ui := new(mockAccInt.AccInterface)
+	ui.On("Get", mock.Anything, id).Return(acc, nil)
+	ui.On("Deposit", mock.Anything, id, int64(5)).Return(acc, nil)
+	ui.On("List", mock.Anything, model.AccountFilter{}).Return(model.AccountPage{Accounts: []model.Account{acc}}, nil)
+	u := NewAccountGRPCServer(ui, loggingService)
+
+	callerContext := func(userID uuid.UUID, role string) context.Context {
+		return context.WithValue(context.Background(), model.IdentityKey, model.Identity{UserID: userID, Roles: []string{role}})
+	}
+
+	_, err := u.GetAccount(callerContext(owner, model.RoleUser), &pb.AccountID{Id: id.String()})
+	assert.NoError(t, err)
+
+	_, err = u.GetAccount(callerContext(other, model.RoleUser), &pb.AccountID{Id: id.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = u.GetAccount(context.Background(), &pb.AccountID{Id: id.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "a caller without identity owns nothing")
+
+	_, err = u.Deposit(callerContext(other, model.RoleUser), &pb.BalanceChange{Id: id.String(), Amount: 5})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = u.Deposit(callerContext(owner, model.RoleUser), &pb.BalanceChange{Id: id.String(), Amount: 5})
+	assert.NoError(t, err)
+
+	_, err = u.Transfer(callerContext(other, model.RoleUser), &pb.TransferRequest{FromID: id.String(), ToID: uuid.New().String(), Amount: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = u.UpdateAccount(callerContext(owner, model.RoleUser), &pb.Account{Id: id.String(), UserID: other.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "an account can not be handed over")
+
+	_, err = u.CreateAccount(callerContext(owner, model.RoleUser), &pb.NewAccount{UserID: other.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = u.GetAllUsers(callerContext(owner, model.RoleUser), &pb.AccountFilter{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = u.GetAllUsers(callerContext(other, model.RoleAdmin), &pb.AccountFilter{})
+	assert.NoError(t, err)
+}
+
+func TestAccount_CloseUserAccounts(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	userID := uuid.New()
+	acc := model.Account{ID: uuid.New(), UserID: userID, Balance: 10}
+
+	ui := new(mockAccInt.AccInterface)
+	ui.On("CloseUserAccounts", mock.Anything, userID, false).Return(nil, customErrors.AccountsNotEmpty)
+	ui.On("CloseUserAccounts", mock.Anything, userID, true).Return([]model.Account{acc}, nil)
+	ui.On("RestoreAccounts", mock.Anything, []model.Account{acc}).Return(nil)
+	u := NewAccountGRPCServer(ui, loggingService)
+
+	owner := context.WithValue(context.Background(), model.IdentityKey, model.Identity{UserID: userID, Roles: []string{model.RoleUser}})
+	_, err := u.CloseUserAccounts(owner, &pb.CloseAccounts{UserID: userID.String(), Force: true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "only admins delete users")
+
+	_, err = u.CloseUserAccounts(adminContext(), &pb.CloseAccounts{UserID: userID.String()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	got, err := u.CloseUserAccounts(adminContext(), &pb.CloseAccounts{UserID: userID.String(), Force: true})
+	assert.NoError(t, err)
+	want := &pb.AllAccounts{Accounts: []*pb.Account{{Id: acc.ID.String(), UserID: userID.String(), Balance: 10}}}
+	assert.Equal(t, want, got)
+
+	_, err = u.RestoreAccounts(adminContext(), got)
+	assert.NoError(t, err)
+	ui.AssertExpectations(t)
+}
@@ -0,0 +1,72 @@
+package accountgrpcserver
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+func checkAdmin(caller model.Identity) error {
+	if caller.HasRole(model.RoleAdmin) {
+		return nil
+	}
+
+	return status.Error(codes.PermissionDenied, "admin role required")
+}
+
+// checkOwner lets through admins and the user owning the resource
+func checkOwner(caller model.Identity, owner uuid.UUID) error {
+	if caller.HasRole(model.RoleAdmin) {
+		return nil
+	}
+
+	if caller.UserID == uuid.Nil || caller.UserID != owner {
+		return status.Error(codes.PermissionDenied, "resource belongs to another user")
+	}
+
+	return nil
+}
+
+// checkAccountOwner loads the account to compare its owner, admins skip the lookup
+func (s AccountServerGRPC) checkAccountOwner(c context.Context, caller model.Identity, id uuid.UUID) error {
+	if caller.HasRole(model.RoleAdmin) {
+		return nil
+	}
+
+	acc, err := s.service.Get(c, id)
+	if err != nil {
+		return customErrors.ToStatus(err, "internal storage problem")
+	}
+
+	return checkOwner(caller, acc.UserID)
+}
+
+// checkAccountUpdate lets owners update their account but leaves the overdraft limit to admins. The update
+// of an owner applies to the version checked here, so the limit can not change in between.
+func (s AccountServerGRPC) checkAccountUpdate(c context.Context, caller model.Identity, id uuid.UUID, overdraftLimit, version int64) (int64, error) {
+	if caller.HasRole(model.RoleAdmin) {
+		return version, nil
+	}
+
+	acc, err := s.service.Get(c, id)
+	if err != nil {
+		return 0, customErrors.ToStatus(err, "internal storage problem")
+	}
+	if err = checkOwner(caller, acc.UserID); err != nil {
+		return 0, err
+	}
+	if overdraftLimit != acc.OverdraftLimit {
+		return 0, status.Error(codes.PermissionDenied, "admin role required to change the overdraft limit")
+	}
+
+	if version != 0 {
+		return version, nil
+	}
+
+	return acc.Version, nil
+}
@@ -0,0 +1,88 @@
+package auditgrpccontroller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	customerrors "github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+	pb "github.com/stasBigunenko/monorepa/pkg/auditGRPC/proto"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+// AuditGRPCController asks the service keeping a resource for its audit trail
+type AuditGRPCController struct {
+	clients map[string]pb.AuditGRPCServiceClient
+	logger  loggingservice.Logger
+}
+
+// New takes the audit client of every service by the resource it keeps
+func New(clients map[string]pb.AuditGRPCServiceClient, logger loggingservice.Logger) *AuditGRPCController {
+	return &AuditGRPCController{
+		clients: clients,
+		logger:  logger.With("component", "grpc client"),
+	}
+}
+
+func (s AuditGRPCController) ListAudit(ctx context.Context, filter model.AuditFilter) (model.AuditPage, error) {
+	s.logger.Debug(ctx, "command received", "method", "ListAudit")
+
+	client, ok := s.clients[filter.Resource]
+	if !ok {
+		return model.AuditPage{}, fmt.Errorf("no audit trail of resource %q: %w", filter.Resource, customerrors.InvalidArgument)
+	}
+
+	in := &pb.AuditFilter{
+		Actor:     filter.Actor,
+		Operation: filter.Operation,
+		PageSize:  int32(filter.PageSize),
+		PageToken: filter.PageToken,
+	}
+	if filter.ResourceID != uuid.Nil {
+		in.ResourceID = filter.ResourceID.String()
+	}
+	if !filter.From.IsZero() {
+		in.From = timestamppb.New(filter.From)
+	}
+	if !filter.To.IsZero() {
+		in.To = timestamppb.New(filter.To)
+	}
+
+	resp, err := client.ListAudit(ctx, in)
+	if err != nil {
+		return model.AuditPage{}, customerrors.FromStatus(err, "failed to list audit trail")
+	}
+
+	page := model.AuditPage{
+		Entries:       []model.AuditEntry{},
+		NextPageToken: resp.NextPageToken,
+	}
+	for _, entry := range resp.Entries {
+		id, err := uuid.Parse(entry.Id)
+		if err != nil {
+			return model.AuditPage{}, fmt.Errorf("failed to parse audit entry ID: %s, %w", err.Error(), customerrors.ParseError)
+		}
+
+		resourceID, err := uuid.Parse(entry.ResourceID)
+		if err != nil {
+			return model.AuditPage{}, fmt.Errorf("failed to parse resource ID: %s, %w", err.Error(), customerrors.ParseError)
+		}
+
+		page.Entries = append(page.Entries, model.AuditEntry{
+			ID:         id,
+			Time:       entry.Time.AsTime(),
+			Actor:      entry.Actor,
+			RequestID:  entry.RequestID,
+			Operation:  entry.Operation,
+			Resource:   entry.Resource,
+			ResourceID: resourceID,
+			Before:     entry.Before,
+			After:      entry.After,
+		})
+	}
+
+	return page, nil
+}
@@ -0,0 +1,101 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AuditGRPCServiceClient is the client API for AuditGRPCService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuditGRPCServiceClient interface {
+	ListAudit(ctx context.Context, in *AuditFilter, opts ...grpc.CallOption) (*AuditPage, error)
+}
+
+type auditGRPCServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuditGRPCServiceClient(cc grpc.ClientConnInterface) AuditGRPCServiceClient {
+	return &auditGRPCServiceClient{cc}
+}
+
+func (c *auditGRPCServiceClient) ListAudit(ctx context.Context, in *AuditFilter, opts ...grpc.CallOption) (*AuditPage, error) {
+	out := new(AuditPage)
+	err := c.cc.Invoke(ctx, "/auditGRPC.AuditGRPCService/ListAudit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuditGRPCServiceServer is the server API for AuditGRPCService service.
+// All implementations must embed UnimplementedAuditGRPCServiceServer
+// for forward compatibility
+type AuditGRPCServiceServer interface {
+	ListAudit(context.Context, *AuditFilter) (*AuditPage, error)
+	mustEmbedUnimplementedAuditGRPCServiceServer()
+}
+
+// UnimplementedAuditGRPCServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAuditGRPCServiceServer struct {
+}
+
+func (UnimplementedAuditGRPCServiceServer) ListAudit(context.Context, *AuditFilter) (*AuditPage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAudit not implemented")
+}
+func (UnimplementedAuditGRPCServiceServer) mustEmbedUnimplementedAuditGRPCServiceServer() {}
+
+// UnsafeAuditGRPCServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuditGRPCServiceServer will
+// result in compilation errors.
+type UnsafeAuditGRPCServiceServer interface {
+	mustEmbedUnimplementedAuditGRPCServiceServer()
+}
+
+func RegisterAuditGRPCServiceServer(s grpc.ServiceRegistrar, srv AuditGRPCServiceServer) {
+	s.RegisterService(&AuditGRPCService_ServiceDesc, srv)
+}
+
+func _AuditGRPCService_ListAudit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditFilter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuditGRPCServiceServer).ListAudit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/auditGRPC.AuditGRPCService/ListAudit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuditGRPCServiceServer).ListAudit(ctx, req.(*AuditFilter))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AuditGRPCService_ServiceDesc is the grpc.ServiceDesc for AuditGRPCService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuditGRPCService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "auditGRPC.AuditGRPCService",
+	HandlerType: (*AuditGRPCServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListAudit",
+			Handler:    _AuditGRPCService_ListAudit_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "audit.proto",
+}
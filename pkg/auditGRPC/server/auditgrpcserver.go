@@ -0,0 +1,89 @@
+package auditgrpcserver
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+	pb "github.com/stasBigunenko/monorepa/pkg/auditGRPC/proto"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+// AuditTrail lists the changes a service recorded, implemented by audit.Recorder
+type AuditTrail interface {
+	List(context.Context, model.AuditFilter) (model.AuditPage, error)
+}
+
+type AuditServerGRPC struct {
+	pb.UnimplementedAuditGRPCServiceServer
+
+	audit  AuditTrail
+	logger loggingservice.Logger
+}
+
+func NewAuditGRPCServer(a AuditTrail, logger loggingservice.Logger) AuditServerGRPC {
+	return AuditServerGRPC{
+		audit:  a,
+		logger: logger.With("component", "grpc server"),
+	}
+}
+
+// ListAudit is for admins only
+func (s AuditServerGRPC) ListAudit(c context.Context, in *pb.AuditFilter) (*pb.AuditPage, error) {
+	s.logger.Debug(c, "command received", "method", "ListAudit")
+
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
+	if !caller.HasRole(model.RoleAdmin) {
+		return nil, status.Error(codes.PermissionDenied, "admin role required")
+	}
+
+	filter := model.AuditFilter{
+		Actor:     in.Actor,
+		Operation: in.Operation,
+		PageSize:  int(in.PageSize),
+		PageToken: in.PageToken,
+	}
+	if in.ResourceID != "" {
+		id, err := uuid.Parse(in.ResourceID)
+		if err != nil {
+			return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
+		}
+		filter.ResourceID = id
+	}
+	if in.From != nil {
+		filter.From = in.From.AsTime()
+	}
+	if in.To != nil {
+		filter.To = in.To.AsTime()
+	}
+
+	page, err := s.audit.List(c, filter)
+	if err != nil {
+		return nil, customErrors.ToStatus(err, "internal storage problem")
+	}
+
+	all := []*pb.AuditEntry{}
+	for _, val := range page.Entries {
+		all = append(all, &pb.AuditEntry{
+			Id:         val.ID.String(),
+			Time:       timestamppb.New(val.Time),
+			Actor:      val.Actor,
+			RequestID:  val.RequestID,
+			Operation:  val.Operation,
+			Resource:   val.Resource,
+			ResourceID: val.ResourceID.String(),
+			Before:     val.Before,
+			After:      val.After,
+		})
+	}
+
+	return &pb.AuditPage{
+		Entries:       all,
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
@@ -1,6 +1,13 @@
 package middleware
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/model"
+)
 
 func JSONRespHeaders(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -23,3 +30,11 @@ func AccessControlMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequestID gives every request an id, error responses carry it
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), model.ContextKeyRequestID, uuid.New().String())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	er "github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+	"github.com/stasBigunenko/monorepa/service/auth/jwt"
+	"github.com/stasBigunenko/monorepa/service/problem"
+)
+
+// adminOnly lets through requests carrying the admin token, admin routes are disabled without one configured
+func (h *HandlerItemsServ) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Admin-Token")
+		if h.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+			problem.Error(w, r, http.StatusForbidden, fmt.Errorf("admin token required: %w", er.PermissionDenied))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type keyResp struct {
+	Kid string `json:"kid"`
+}
+
+func (h *HandlerItemsServ) GenerateKey(w http.ResponseWriter, r *http.Request) {
+	kid, err := h.services.GenerateKey()
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeKey(w, r, kid)
+}
+
+func (h *HandlerItemsServ) RotateKey(w http.ResponseWriter, r *http.Request) {
+	kid, err := h.services.RotateKey()
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeKey(w, r, kid)
+}
+
+func (h *HandlerItemsServ) PromoteKey(w http.ResponseWriter, r *http.Request) {
+	kid := mux.Vars(r)["kid"]
+
+	if err := h.services.PromoteKey(kid); err != nil {
+		if errors.Is(err, jwt.ErrUnknownKey) {
+			problem.Error(w, r, http.StatusNotFound, err)
+			return
+		}
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HandlerItemsServ) SetRole(w http.ResponseWriter, r *http.Request) {
+	var change model.RoleChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, decodeError(err))
+		return
+	}
+
+	err := h.services.SetRole(r.Context(), mux.Vars(r)["name"], change.Role)
+	switch {
+	case errors.Is(err, er.InvalidArgument):
+		problem.Error(w, r, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, er.NotFound):
+		problem.Error(w, r, http.StatusNotFound, err)
+		return
+	case err != nil:
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HandlerItemsServ) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, h.services.DisableUser)
+}
+
+func (h *HandlerItemsServ) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, h.services.EnableUser)
+}
+
+func (h *HandlerItemsServ) setDisabled(w http.ResponseWriter, r *http.Request, set func(context.Context, uuid.UUID) error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, fmt.Errorf("%s: %w", err, er.UUIDError))
+		return
+	}
+
+	if err = set(r.Context(), id); err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeKey(w http.ResponseWriter, r *http.Request, kid string) {
+	respByte, err := json.Marshal(keyResp{Kid: kid})
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(respByte) //nolint:errcheck
+}
@@ -4,69 +4,223 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 
 	er "github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/model"
 	"github.com/stasBigunenko/monorepa/service/auth"
+	"github.com/stasBigunenko/monorepa/service/problem"
 
 	"github.com/gorilla/mux"
 )
 
 type HandlerItemsServ struct {
-	router   *mux.Router
-	services auth.Service
-	ctx      context.Context
+	router     *mux.Router
+	services   auth.Service
+	ctx        context.Context
+	adminToken string
 }
 
-func New(ctx context.Context, router *mux.Router) *HandlerItemsServ {
+func New(ctx context.Context, router *mux.Router, services auth.Service, adminToken string) *HandlerItemsServ {
 	return &HandlerItemsServ{
-		router:   router,
-		services: auth.New(),
-		ctx:      ctx,
+		router:     router,
+		services:   services,
+		ctx:        ctx,
+		adminToken: adminToken,
 	}
 }
 
 func (h *HandlerItemsServ) HandlerItems() {
 	h.router.HandleFunc("/login", h.GetJWTToken).Methods("POST", "OPTIONS")
+	h.router.HandleFunc("/register", h.Register).Methods("POST", "OPTIONS")
+	h.router.HandleFunc("/password", h.ChangePassword).Methods("PUT", "OPTIONS")
+	h.router.HandleFunc("/refresh", h.Refresh).Methods("POST", "OPTIONS")
+	h.router.HandleFunc("/logout", h.Logout).Methods("POST", "OPTIONS")
+	h.router.HandleFunc("/revoked", h.RevokedTokens).Methods("GET")
 	h.router.HandleFunc("/get-cert/{version}", h.GetCertKey).Methods("GET")
+	h.router.HandleFunc("/.well-known/jwks.json", h.GetJWKS).Methods("GET")
+
+	admin := h.router.PathPrefix("/admin").Subrouter()
+	admin.Use(h.adminOnly)
+	admin.HandleFunc("/keys", h.GenerateKey).Methods("POST")
+	admin.HandleFunc("/keys/rotate", h.RotateKey).Methods("POST")
+	admin.HandleFunc("/keys/{kid}/promote", h.PromoteKey).Methods("POST")
+	admin.HandleFunc("/users/{name}/role", h.SetRole).Methods("PUT")
+	// the gateway disables the logins of users it deletes and enables them again when the delete is undone
+	admin.HandleFunc("/users/{id}/disable", h.DisableUser).Methods("POST")
+	admin.HandleFunc("/users/{id}/enable", h.EnableUser).Methods("POST")
 }
 
 func (h *HandlerItemsServ) GetJWTToken(w http.ResponseWriter, r *http.Request) {
 	var user model.User
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, decodeError(err))
 		return
 	}
 
-	token, err := h.services.Login(user)
+	tokens, err := h.services.Login(user)
 	if err != nil {
-		if errors.Is(err, er.WrongPassword) {
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
+		credentialsError(w, r, err)
+		return
+	}
 
-		w.WriteHeader(http.StatusInternalServerError)
+	respByte, err := json.Marshal(tokens)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	w.Header().Set("token", token)
+	// the header is kept for clients reading the access token from it
+	w.Header().Set("token", tokens.AccessToken)
 	w.Header().Set("Access-Control-Expose-Headers", "token")
 	w.WriteHeader(http.StatusCreated)
+	w.Write(respByte) //nolint:errcheck
+}
+
+func (h *HandlerItemsServ) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req model.RefreshRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, decodeError(err))
+		return
+	}
+	if req.RefreshToken == "" {
+		problem.Error(w, r, http.StatusBadRequest, er.Field("refresh_token", "is required"))
+		return
+	}
+
+	tokens, err := h.services.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		credentialsError(w, r, err)
+		return
+	}
+
+	respByte, err := json.Marshal(tokens)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Write(respByte) //nolint:errcheck
+}
+
+func (h *HandlerItemsServ) Logout(w http.ResponseWriter, r *http.Request) {
+	var req model.RefreshRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, decodeError(err))
+		return
+	}
+	if req.RefreshToken == "" {
+		problem.Error(w, r, http.StatusBadRequest, er.Field("refresh_token", "is required"))
+		return
+	}
+
+	if err = h.services.Logout(r.Context(), req.RefreshToken); err != nil {
+		credentialsError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HandlerItemsServ) RevokedTokens(w http.ResponseWriter, r *http.Request) {
+	revoked, err := h.services.RevokedTokens(r.Context())
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	respByte, err := json.Marshal(model.RevokedTokens{Revoked: revoked})
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Write(respByte) //nolint:errcheck
+}
+
+func (h *HandlerItemsServ) Register(w http.ResponseWriter, r *http.Request) {
+	var user model.User
+	err := json.NewDecoder(r.Body).Decode(&user)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, decodeError(err))
+		return
+	}
+
+	id, err := h.services.Register(r.Context(), user)
+	if err != nil {
+		credentialsError(w, r, err)
+		return
+	}
+
+	respByte, err := json.Marshal(model.UserHTTP{ID: id, Name: user.Name})
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(respByte) //nolint:errcheck
+}
+
+func (h *HandlerItemsServ) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var change model.PasswordChange
+	err := json.NewDecoder(r.Body).Decode(&change)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, decodeError(err))
+		return
+	}
+
+	if err = h.services.ChangePassword(r.Context(), change); err != nil {
+		credentialsError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// credentialsError answers login, register and password change errors, the missing name or
+// password is a field error of the request
+func credentialsError(w http.ResponseWriter, r *http.Request, err error) {
+	status := credentialsErrorCode(err)
+	switch {
+	case errors.Is(err, er.WrongName):
+		err = er.Field("name", err.Error())
+	case errors.Is(err, er.WrongPassword):
+		err = er.Field("password", err.Error())
+	}
+
+	problem.Error(w, r, status, err)
+}
+
+// credentialsErrorCode maps login, register and password change errors to a status code
+func credentialsErrorCode(err error) int {
+	switch {
+	case errors.Is(err, er.WrongPassword) || errors.Is(err, er.WrongName):
+		return http.StatusBadRequest
+	case errors.Is(err, er.InvalidCredentials) || errors.Is(err, er.InvalidToken) || errors.Is(err, er.TokenReused):
+		return http.StatusUnauthorized
+	case errors.Is(err, er.AlreadyExists):
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
 }
 
 func (h *HandlerItemsServ) GetCertKey(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	versionCert := params["version"]
 	if versionCert == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, er.Field("version", "is required"))
 		return
 	}
 
 	byteCertPbKey, err := h.services.GetCert(versionCert)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -82,7 +236,7 @@ func (h *HandlerItemsServ) GetCertKey(w http.ResponseWriter, r *http.Request) {
 
 	respByte, err := json.Marshal(resp)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -91,3 +245,30 @@ func (h *HandlerItemsServ) GetCertKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (h *HandlerItemsServ) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	jwks, err := h.services.GetJWKS()
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	respByte, err := json.Marshal(jwks)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(respByte) //nolint:errcheck
+}
+
+// decodeError names the field of a request body holding a value of the wrong type
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return er.Field(typeErr.Field, "must be of type "+typeErr.Type.String())
+	}
+
+	return fmt.Errorf("%s: %w", err, er.JSONError)
+}
@@ -10,7 +10,10 @@ import (
 
 	authMock "github.com/stasBigunenko/monorepa/mocks/service/auth"
 	"github.com/stasBigunenko/monorepa/model"
+	"github.com/stasBigunenko/monorepa/service/auth/jwt"
+	"github.com/stasBigunenko/monorepa/service/problem"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	er "github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stretchr/testify/assert"
@@ -44,7 +47,7 @@ func TestUserTokenGen(t *testing.T) {
 			serviceFuncResp: func(mc *authMock.Service, items []byte) {
 				mc.On("Login",
 					mock.Anything,
-				).Return("qwerty.qwerty.qwerty", nil)
+				).Return(model.TokenPair{AccessToken: "qwerty.qwerty.qwerty", RefreshToken: "refresh"}, nil)
 			},
 			request: request{
 				endpoint: "/login",
@@ -73,7 +76,7 @@ func TestUserTokenGen(t *testing.T) {
 			serviceFuncResp: func(mc *authMock.Service, items []byte) {
 				mc.On("Login",
 					mock.Anything,
-				).Return("", er.WrongPassword)
+				).Return(model.TokenPair{}, er.WrongPassword)
 			},
 			request: request{
 				endpoint: "/login",
@@ -98,7 +101,7 @@ func TestUserTokenGen(t *testing.T) {
 			serviceFuncResp: func(mc *authMock.Service, items []byte) {
 				mc.On("Login",
 					mock.Anything,
-				).Return("", er.WrongPassword)
+				).Return(model.TokenPair{}, er.WrongPassword)
 			},
 			request: request{
 				endpoint: "/login",
@@ -121,6 +124,31 @@ func TestUserTokenGen(t *testing.T) {
 				headerToken: false,
 			},
 		},
+		{
+			name:    "Wrong password",
+			service: &authMock.Service{},
+			serviceFuncResp: func(mc *authMock.Service, items []byte) {
+				mc.On("Login",
+					mock.Anything,
+				).Return(model.TokenPair{}, er.InvalidCredentials)
+			},
+			request: request{
+				endpoint: "/login",
+				method:   "POST",
+				body: func() []byte {
+					user := model.User{
+						Name:     "Bob",
+						Password: "54321",
+					}
+					res, _ := json.Marshal(user)
+					return res
+				},
+			},
+			want: wantResp{
+				code:        401,
+				headerToken: false,
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -257,7 +285,348 @@ func TestGetCertificateKey(t *testing.T) {
 
 			assert.NotEmpty(t, respData.PbKey, tc.name)
 		} else {
-			assert.Empty(t, res.Body, tc.name)
+			// server errors are a problem keeping the error to the server
+			assert.Equal(t, problem.ContentType, res.Header.Get("Content-Type"), tc.name)
+			var p problem.Problem
+			assert.Nil(t, json.NewDecoder(res.Body).Decode(&p), tc.name)
+			assert.Equal(t, tc.want.code, p.Status, tc.name)
+			assert.NotContains(t, p.Detail, er.WrongPassword.Error(), tc.name)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	id := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	testCases := []struct {
+		name            string
+		serviceFuncResp func(*authMock.Service)
+		endpoint        string
+		method          string
+		body            string
+		code            int
+	}{
+		{
+			name: "Normal register",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("Register", mock.Anything, model.User{Name: "Bob", Password: "12345"}).Return(id, nil)
+			},
+			endpoint: "/register",
+			method:   "POST",
+			body:     `{"name":"Bob","password":"12345"}`,
+			code:     201,
+		},
+		{
+			name: "Name taken",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("Register", mock.Anything, mock.Anything).Return(uuid.Nil, er.AlreadyExists)
+			},
+			endpoint: "/register",
+			method:   "POST",
+			body:     `{"name":"Bob","password":"12345"}`,
+			code:     409,
+		},
+		{
+			name:            "Broken body",
+			serviceFuncResp: func(mc *authMock.Service) {},
+			endpoint:        "/register",
+			method:          "POST",
+			body:            `{"name":`,
+			code:            400,
+		},
+		{
+			name: "Password changed",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("ChangePassword", mock.Anything, model.PasswordChange{Name: "Bob", Password: "12345", NewPassword: "54321"}).Return(nil)
+			},
+			endpoint: "/password",
+			method:   "PUT",
+			body:     `{"name":"Bob","password":"12345","new_password":"54321"}`,
+			code:     204,
+		},
+		{
+			name: "Password change with wrong password",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("ChangePassword", mock.Anything, mock.Anything).Return(er.InvalidCredentials)
+			},
+			endpoint: "/password",
+			method:   "PUT",
+			body:     `{"name":"Bob","password":"1","new_password":"54321"}`,
+			code:     401,
+		},
+	}
+
+	for _, tc := range testCases {
+		service := &authMock.Service{}
+		tc.serviceFuncResp(service)
+
+		handler := HandlerItemsServ{
+			router:   mux.NewRouter(),
+			ctx:      context.Background(),
+			services: service,
+		}
+		handler.HandlerItems()
+
+		req := httptest.NewRequest(tc.method, tc.endpoint, bytes.NewBufferString(tc.body))
+		rec := httptest.NewRecorder()
+		handler.router.ServeHTTP(rec, req)
+
+		assert.Equal(t, tc.code, rec.Code, tc.name)
+		if tc.code == 201 {
+			var user model.UserHTTP
+			assert.Nil(t, json.NewDecoder(rec.Body).Decode(&user), tc.name)
+			assert.Equal(t, id, user.ID, tc.name)
+		}
+	}
+}
+
+func TestRefreshLogout(t *testing.T) {
+	testCases := []struct {
+		name            string
+		serviceFuncResp func(*authMock.Service)
+		endpoint        string
+		method          string
+		body            string
+		code            int
+	}{
+		{
+			name: "Refresh",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("Refresh", mock.Anything, "abc").Return(model.TokenPair{AccessToken: "a", RefreshToken: "b"}, nil)
+			},
+			endpoint: "/refresh",
+			method:   "POST",
+			body:     `{"refresh_token":"abc"}`,
+			code:     200,
+		},
+		{
+			name: "Refresh with reused token",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("Refresh", mock.Anything, "abc").Return(model.TokenPair{}, er.TokenReused)
+			},
+			endpoint: "/refresh",
+			method:   "POST",
+			body:     `{"refresh_token":"abc"}`,
+			code:     401,
+		},
+		{
+			name:            "Refresh without token",
+			serviceFuncResp: func(mc *authMock.Service) {},
+			endpoint:        "/refresh",
+			method:          "POST",
+			body:            `{}`,
+			code:            400,
+		},
+		{
+			name: "Logout",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("Logout", mock.Anything, "abc").Return(nil)
+			},
+			endpoint: "/logout",
+			method:   "POST",
+			body:     `{"refresh_token":"abc"}`,
+			code:     204,
+		},
+		{
+			name: "Logout with unknown token",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("Logout", mock.Anything, "abc").Return(er.InvalidToken)
+			},
+			endpoint: "/logout",
+			method:   "POST",
+			body:     `{"refresh_token":"abc"}`,
+			code:     401,
+		},
+		{
+			name: "Revoked tokens",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("RevokedTokens", mock.Anything).Return([]model.RevokedToken{{JTI: "1"}}, nil)
+			},
+			endpoint: "/revoked",
+			method:   "GET",
+			code:     200,
+		},
+	}
+
+	for _, tc := range testCases {
+		service := &authMock.Service{}
+		tc.serviceFuncResp(service)
+
+		handler := HandlerItemsServ{
+			router:   mux.NewRouter(),
+			ctx:      context.Background(),
+			services: service,
+		}
+		handler.HandlerItems()
+
+		req := httptest.NewRequest(tc.method, tc.endpoint, bytes.NewBufferString(tc.body))
+		rec := httptest.NewRecorder()
+		handler.router.ServeHTTP(rec, req)
+
+		assert.Equal(t, tc.code, rec.Code, tc.name)
+	}
+}
+
+func TestGetJWKS(t *testing.T) {
+	service := &authMock.Service{}
+	service.On("GetJWKS").Return(model.JWKS{Keys: []model.JWK{{Kty: "RSA", Kid: "1", N: "AQAB", E: "AQAB"}}}, nil)
+
+	handler := HandlerItemsServ{
+		router:   mux.NewRouter(),
+		ctx:      context.Background(),
+		services: service,
+	}
+	handler.HandlerItems()
+
+	rec := httptest.NewRecorder()
+	handler.router.ServeHTTP(rec, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+
+	assert.Equal(t, 200, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Cache-Control"))
+
+	var jwks model.JWKS
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&jwks))
+	assert.Equal(t, "1", jwks.Keys[0].Kid)
+}
+
+func TestAdminKeys(t *testing.T) {
+	testCases := []struct {
+		name            string
+		serviceFuncResp func(*authMock.Service)
+		method          string
+		endpoint        string
+		body            string
+		token           string
+		code            int
+	}{
+		{
+			name: "Rotate",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("RotateKey").Return("4", nil)
+			},
+			endpoint: "/admin/keys/rotate",
+			token:    "secret",
+			code:     201,
+		},
+		{
+			name:            "Rotate without admin token",
+			serviceFuncResp: func(mc *authMock.Service) {},
+			endpoint:        "/admin/keys/rotate",
+			token:           "guess",
+			code:            403,
+		},
+		{
+			name: "Generate",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("GenerateKey").Return("4", nil)
+			},
+			endpoint: "/admin/keys",
+			token:    "secret",
+			code:     201,
+		},
+		{
+			name: "Promote",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("PromoteKey", "4").Return(nil)
+			},
+			endpoint: "/admin/keys/4/promote",
+			token:    "secret",
+			code:     204,
+		},
+		{
+			name: "Promote unknown key",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("PromoteKey", "42").Return(jwt.ErrUnknownKey)
+			},
+			endpoint: "/admin/keys/42/promote",
+			token:    "secret",
+			code:     404,
+		},
+		{
+			name: "Grant admin role",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("SetRole", mock.Anything, "bob", "admin").Return(nil)
+			},
+			method:   "PUT",
+			endpoint: "/admin/users/bob/role",
+			body:     `{"role":"admin"}`,
+			token:    "secret",
+			code:     204,
+		},
+		{
+			name: "Grant unknown role",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("SetRole", mock.Anything, "bob", "root").Return(er.InvalidArgument)
+			},
+			method:   "PUT",
+			endpoint: "/admin/users/bob/role",
+			body:     `{"role":"root"}`,
+			token:    "secret",
+			code:     400,
+		},
+		{
+			name: "Grant role to unknown name",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("SetRole", mock.Anything, "alice", "admin").Return(er.NotFound)
+			},
+			method:   "PUT",
+			endpoint: "/admin/users/alice/role",
+			body:     `{"role":"admin"}`,
+			token:    "secret",
+			code:     404,
+		},
+		{
+			name: "Disable user",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("DisableUser", mock.Anything, uuid.MustParse("32b56c48-1b96-11ec-adc6-23ffd7a72bbb")).Return(nil)
+			},
+			endpoint: "/admin/users/32b56c48-1b96-11ec-adc6-23ffd7a72bbb/disable",
+			token:    "secret",
+			code:     204,
+		},
+		{
+			name:            "Disable user without admin token",
+			serviceFuncResp: func(mc *authMock.Service) {},
+			endpoint:        "/admin/users/32b56c48-1b96-11ec-adc6-23ffd7a72bbb/disable",
+			token:           "guess",
+			code:            403,
+		},
+		{
+			name:            "Disable user with a malformed id",
+			serviceFuncResp: func(mc *authMock.Service) {},
+			endpoint:        "/admin/users/bob/disable",
+			token:           "secret",
+			code:            400,
+		},
+		{
+			name: "Enable user",
+			serviceFuncResp: func(mc *authMock.Service) {
+				mc.On("EnableUser", mock.Anything, uuid.MustParse("32b56c48-1b96-11ec-adc6-23ffd7a72bbb")).Return(nil)
+			},
+			endpoint: "/admin/users/32b56c48-1b96-11ec-adc6-23ffd7a72bbb/enable",
+			token:    "secret",
+			code:     204,
+		},
+	}
+
+	for _, tc := range testCases {
+		service := &authMock.Service{}
+		tc.serviceFuncResp(service)
+
+		handler := New(context.Background(), mux.NewRouter(), service, "secret")
+		handler.HandlerItems()
+
+		method := tc.method
+		if method == "" {
+			method = "POST"
 		}
+
+		req := httptest.NewRequest(method, tc.endpoint, bytes.NewBufferString(tc.body))
+		req.Header.Set("X-Admin-Token", tc.token)
+		rec := httptest.NewRecorder()
+		handler.router.ServeHTTP(rec, req)
+
+		assert.Equal(t, tc.code, rec.Code, tc.name)
+		service.AssertExpectations(t)
 	}
 }
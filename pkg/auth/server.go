@@ -8,10 +8,16 @@ import (
 	"strconv"
 	"time"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 
 	"github.com/stasBigunenko/monorepa/pkg/auth/middleware"
 	"github.com/stasBigunenko/monorepa/pkg/auth/routes"
+	"github.com/stasBigunenko/monorepa/service/auth"
+	"github.com/stasBigunenko/monorepa/service/health"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
+	"github.com/stasBigunenko/monorepa/service/problem"
 
 	"github.com/gorilla/mux"
 	er "github.com/stasBigunenko/monorepa/customErrors"
@@ -24,12 +30,19 @@ type Server struct {
 		Port string
 	}
 	router *mux.Router
+	logger loggingservice.Logger
 }
 
-func New(ctx context.Context) *Server {
+func New(ctx context.Context, logger loggingservice.Logger) *Server {
+	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(problem.NotFound)
+	router.MethodNotAllowedHandler = http.HandlerFunc(problem.MethodNotAllowed)
+	router.Use(otelmux.Middleware("auth"))
+
 	return &Server{
-		router: mux.NewRouter(),
+		router: router,
 		ctx:    ctx,
+		logger: logger.With("component", "http"),
 	}
 }
 
@@ -49,11 +62,23 @@ func (s *Server) ServerAddrConfig() error {
  * also contain connection to graphql server
  */
 
-func (s *Server) GetRouters() {
-	itemsHandler := routes.New(s.ctx, s.router)
+func (s *Server) GetRouters(services auth.Service, adminToken string) {
+	itemsHandler := routes.New(s.ctx, s.router, services, adminToken)
 	itemsHandler.HandlerItems()
 }
 
+// Instrument counts the requests of every route and serves the metrics on /metrics
+func (s *Server) Instrument(m *metrics.HTTPMetrics, g prometheus.Gatherer) {
+	s.router.Use(m.Middleware)
+	s.router.Handle("/metrics", metrics.Handler(g)).Methods("GET")
+}
+
+// Health serves the liveness probe on /healthz and the readiness of the dependencies on /readyz
+func (s *Server) Health(checker *health.Checker) {
+	s.router.HandleFunc("/healthz", health.Live).Methods("GET")
+	s.router.Handle("/readyz", checker).Methods("GET")
+}
+
 func (s *Server) getHTTPAddress() string {
 	return fmt.Sprintf("%s:%s", s.config.Host, s.config.Port)
 }
@@ -63,7 +88,7 @@ func (s *Server) Start(ctx context.Context) error {
 
 	server := &http.Server{
 		Addr:         s.getHTTPAddress(),
-		Handler:      middleware.JSONRespHeaders(middleware.AccessControlMiddleware(s.router)),
+		Handler:      middleware.JSONRespHeaders(middleware.AccessControlMiddleware(middleware.RequestID(s.router))),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 5 * time.Second,
 	}
@@ -75,11 +100,12 @@ func (s *Server) Start(ctx context.Context) error {
 	// run server
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal(err)
+			s.logger.Error(ctx, "server failed", "error", err)
+			os.Exit(1)
 		}
 	}()
 
-	log.Info("Server is running on: " + s.getHTTPAddress())
+	s.logger.Info(ctx, "server is running", "address", s.getHTTPAddress())
 
 	<-ctx.Done() // wait end of work
 
@@ -87,7 +113,7 @@ func (s *Server) Start(ctx context.Context) error {
 	 * start graceful shutdown
 	 */
 
-	log.Warn("Server stopped")
+	s.logger.Warn(ctx, "server stopped")
 
 	ctxShutDown, cancel := context.WithTimeout(context.Background(), time.Duration(cancelTimeout)*time.Second)
 	defer cancel()
@@ -96,6 +122,6 @@ func (s *Server) Start(ctx context.Context) error {
 		return err
 	}
 
-	log.Warn("Server exited properly")
+	s.logger.Warn(ctx, "server exited properly")
 	return nil
 }
@@ -1,11 +1,14 @@
 package httphandler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -19,100 +22,134 @@ import (
 // ******** //
 
 func (h HTTPHandler) AddAccount(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command AddAccount received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "AddAccount")
 
 	userID, err := ioutil.ReadAll(req.Body)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
 	var account model.Account
 	if err = json.Unmarshal(userID, &account); err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		h.reportError(w, req, decodeError(err))
 		return
 	}
 
-	accountID, err := h.AccountsService.CreateAccount(req.Context(), account.UserID)
+	accountID, err := h.AccountsService.CreateAccount(req.Context(), account.UserID, account.Currency)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
-	w.Header().Set("Location", fmt.Sprintf("/accounts/%d", accountID))
+	w.Header().Set("Location", fmt.Sprintf("/accounts/%s", accountID))
 	w.WriteHeader(http.StatusCreated)
 }
 
 func (h HTTPHandler) GetAccount(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command GetAccount received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "GetAccount")
 
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
 		return
 	}
 
 	account, err := h.AccountsService.GetAccount(req.Context(), id)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
 	a, err := json.Marshal(account)
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.JSONError))
 		return
 	}
 
+	setETag(w, account.Version)
 	w.Write(a) //nolint:errcheck
 }
 
+// accountUpdate is the body of PUT /accounts/{id}, the accountUpdater policy refuses the balance.
+// An absent owner or overdraft limit is kept.
+type accountUpdate struct {
+	UserID         uuid.UUID `json:"user_id"`
+	Balance        *int64    `json:"balance"`
+	OverdraftLimit *int64    `json:"overdraft_limit"`
+	Version        int64     `json:"version"`
+}
+
 func (h HTTPHandler) UpdateAccount(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command UpdateAccount received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "UpdateAccount")
 
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
 		return
 	}
 
 	p, err := ioutil.ReadAll(req.Body)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
+		return
+	}
+
+	var update accountUpdate
+	if err = json.Unmarshal(p, &update); err != nil {
+		h.reportError(w, req, decodeError(err))
 		return
 	}
 
-	account := model.Account{}
-	if err = json.Unmarshal(p, &account); err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+	account := model.Account{ID: id, UserID: update.UserID}
+	if account.Version, err = expectedVersion(req, update.Version); err != nil {
+		h.reportError(w, req, err)
 		return
 	}
 
-	account.ID = id
+	if update.OverdraftLimit != nil {
+		if *update.OverdraftLimit < 0 {
+			h.reportError(w, req, customErrors.Field("overdraft_limit", "must not be negative"))
+			return
+		}
+		account.OverdraftLimit = *update.OverdraftLimit
+	} else {
+		// an update without a limit keeps the current one
+		current, err := h.AccountsService.GetAccount(req.Context(), id)
+		if err != nil {
+			h.reportError(w, req, err)
+			return
+		}
+		account.OverdraftLimit = current.OverdraftLimit
+		if account.Version == 0 {
+			account.Version = current.Version
+		}
+	}
 
-	err = h.AccountsService.UpdateAccount(req.Context(), account)
+	updated, err := h.AccountsService.UpdateAccount(req.Context(), account)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
+	setETag(w, updated.Version)
 	w.WriteHeader(http.StatusOK)
 }
 
 func (h HTTPHandler) DeleteAccount(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command DeleteAccount received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "DeleteAccount")
 
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
 		return
 	}
 
 	if err := h.AccountsService.DeleteAccount(req.Context(), id); err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
@@ -120,41 +157,152 @@ func (h HTTPHandler) DeleteAccount(w http.ResponseWriter, req *http.Request) {
 }
 
 func (h HTTPHandler) ListAccounts(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command ListAccount received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "ListAccount")
+
+	filter, err := accountFilter(req.URL.Query())
+	if err != nil {
+		h.reportError(w, req, err)
+		return
+	}
 
-	forUser := false
+	// older clients pass the owner in the body instead of the user_id parameter
 	p, err := ioutil.ReadAll(req.Body)
 	if err != nil {
-		if err != io.EOF {
-			forUser = true
-		} else {
-			h.reportError(w, err)
-			return
-		}
+		h.reportError(w, req, err)
+		return
 	}
 
-	var accounts []model.Account
-	if forUser {
+	if len(p) > 0 && filter.UserID == uuid.Nil {
 		account := model.Account{}
 		if err = json.Unmarshal(p, &account); err != nil {
-			h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+			h.reportError(w, req, decodeError(err))
 			return
 		}
-		accounts, err = h.AccountsService.GetUserAccounts(req.Context(), account.UserID)
-	} else {
-		accounts, err = h.AccountsService.GetAllAccounts(req.Context())
+		filter.UserID = account.UserID
+	}
+
+	page, err := h.AccountsService.GetAllAccounts(req.Context(), filter)
+	if err != nil {
+		h.reportError(w, req, err)
+		return
+	}
+
+	res, err := json.Marshal(page.Accounts)
+	if err != nil {
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		return
+	}
+
+	setNextPage(w, req, page.NextPageToken)
+	w.Write(res) //nolint:errcheck
+}
+
+func (h HTTPHandler) ListTransactions(w http.ResponseWriter, req *http.Request) {
+	h.Logger.Debug(req.Context(), "command received", "method", "ListTransactions")
+
+	vars := mux.Vars(req)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
+		return
+	}
+
+	filter, err := transactionFilter(req.URL.Query())
+	if err != nil {
+		h.reportError(w, req, err)
+		return
+	}
+	filter.AccountID = id
+
+	page, err := h.AccountsService.ListTransactions(req.Context(), filter)
+	if err != nil {
+		h.reportError(w, req, err)
+		return
 	}
 
+	res, err := json.Marshal(page)
+	if err != nil {
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		return
+	}
+
+	w.Write(res) //nolint:errcheck
+}
+
+func (h HTTPHandler) Deposit(w http.ResponseWriter, req *http.Request) {
+	h.Logger.Debug(req.Context(), "command received", "method", "Deposit")
+
+	h.changeBalance(w, req, h.AccountsService.Deposit)
+}
+
+func (h HTTPHandler) Withdraw(w http.ResponseWriter, req *http.Request) {
+	h.Logger.Debug(req.Context(), "command received", "method", "Withdraw")
+
+	h.changeBalance(w, req, h.AccountsService.Withdraw)
+}
+
+// changeBalance reads the amount from the body and responds with the updated account
+func (h HTTPHandler) changeBalance(w http.ResponseWriter, req *http.Request, apply func(context.Context, uuid.UUID, int64) (model.Account, error)) {
+	vars := mux.Vars(req)
+	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
 		return
 	}
 
-	res, err := json.Marshal(accounts)
+	p, err := ioutil.ReadAll(req.Body)
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		h.reportError(w, req, err)
+		return
+	}
+
+	var change model.BalanceChange
+	if err = json.Unmarshal(p, &change); err != nil {
+		h.reportError(w, req, decodeError(err))
+		return
+	}
+
+	account, err := apply(req.Context(), id, change.Amount)
+	if err != nil {
+		h.reportError(w, req, err)
+		return
+	}
+
+	res, err := json.Marshal(account)
+	if err != nil {
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.JSONError))
 		return
 	}
 
 	w.Write(res) //nolint:errcheck
 }
+
+// transactionFilter reads from, to (RFC 3339), page_size and page_token query parameters
+func transactionFilter(query url.Values) (model.TransactionFilter, error) {
+	var (
+		filter model.TransactionFilter
+		err    error
+	)
+
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return model.TransactionFilter{}, customErrors.Field("from", "must be an RFC 3339 time")
+		}
+	}
+
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return model.TransactionFilter{}, customErrors.Field("to", "must be an RFC 3339 time")
+		}
+	}
+
+	if size := query.Get("page_size"); size != "" {
+		if filter.PageSize, err = strconv.Atoi(size); err != nil {
+			return model.TransactionFilter{}, customErrors.Field("page_size", "must be an integer")
+		}
+	}
+
+	filter.PageToken = query.Get("page_token")
+
+	return filter, nil
+}
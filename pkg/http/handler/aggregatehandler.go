@@ -13,24 +13,24 @@ import (
 )
 
 func (h HTTPHandler) GetAggregate(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command GetAggregate received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "GetAggregate")
 
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
 		return
 	}
 
 	accounts, err := h.AccountsService.GetUserAccounts(req.Context(), id)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
 	user, err := h.UsersService.GetUser(req.Context(), id)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
@@ -41,7 +41,7 @@ func (h HTTPHandler) GetAggregate(w http.ResponseWriter, req *http.Request) {
 
 	res, err := json.Marshal(aggregated)
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.JSONError))
 		return
 	}
 
@@ -0,0 +1,76 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// ListAudit returns the audit trail of one resource, ?resource=account or ?resource=user
+func (h HTTPHandler) ListAudit(w http.ResponseWriter, req *http.Request) {
+	h.Logger.Debug(req.Context(), "command received", "method", "ListAudit")
+
+	filter, err := auditFilter(req.URL.Query())
+	if err != nil {
+		h.reportError(w, req, err)
+		return
+	}
+
+	page, err := h.AuditService.ListAudit(req.Context(), filter)
+	if err != nil {
+		h.reportError(w, req, err)
+		return
+	}
+
+	res, err := json.Marshal(page)
+	if err != nil {
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		return
+	}
+
+	w.Write(res) //nolint:errcheck
+}
+
+func auditFilter(query url.Values) (model.AuditFilter, error) {
+	filter := model.AuditFilter{
+		Resource:  query.Get("resource"),
+		Actor:     query.Get("actor"),
+		Operation: query.Get("operation"),
+		PageToken: query.Get("page_token"),
+	}
+
+	var err error
+	if id := query.Get("resource_id"); id != "" {
+		if filter.ResourceID, err = uuid.Parse(id); err != nil {
+			return model.AuditFilter{}, customErrors.Field("resource_id", "must be a uuid")
+		}
+	}
+
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return model.AuditFilter{}, customErrors.Field("from", "must be an RFC 3339 time")
+		}
+	}
+
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return model.AuditFilter{}, customErrors.Field("to", "must be an RFC 3339 time")
+		}
+	}
+
+	if size := query.Get("page_size"); size != "" {
+		if filter.PageSize, err = strconv.Atoi(size); err != nil {
+			return model.AuditFilter{}, customErrors.Field("page_size", "must be an integer")
+		}
+	}
+
+	return filter, nil
+}
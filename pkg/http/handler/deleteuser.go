@@ -0,0 +1,97 @@
+package httphandler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// policies of DELETE /users/{id}, selected by the policy query parameter
+const (
+	// deleteRestrict refuses while the user has money or debt on an account, empty accounts are closed
+	deleteRestrict = "restrict"
+	// deleteCascade closes every account of the user whatever the balance
+	deleteCascade = "cascade"
+	// deleteSoft hides the user and keeps the accounts
+	deleteSoft = "soft"
+)
+
+// compensationTimeout bounds the undo steps, they run even when the request is gone
+const compensationTimeout = 10 * time.Second
+
+// deleteUser removes the user in the user service and its accounts in the account service.
+// The logins of the user are disabled and its tokens revoked first, so nobody gets tokens for
+// a subject that is gone. The user is soft deleted next so it can be brought back if closing
+// the accounts fails, and closed accounts are restored if the user can not be removed afterwards.
+func (h HTTPHandler) deleteUser(ctx context.Context, id uuid.UUID, policy string) error {
+	switch policy {
+	case deleteSoft, deleteRestrict, deleteCascade:
+	default:
+		return fmt.Errorf("unknown delete policy %q: %w", policy, customErrors.InvalidArgument)
+	}
+
+	if err := h.CredentialService.DisableUser(ctx, id); err != nil {
+		return err
+	}
+
+	// revoked tokens stay revoked, the user logs in again
+	enable := func(c context.Context) error {
+		return h.CredentialService.EnableUser(c, id)
+	}
+
+	if err := h.UsersService.SoftDeleteUser(ctx, id); err != nil {
+		return h.compensate(ctx, err, enable)
+	}
+
+	if policy == deleteSoft {
+		return nil
+	}
+
+	closed, err := h.AccountsService.CloseUserAccounts(ctx, id, policy == deleteCascade)
+	if err != nil {
+		return h.compensate(ctx, err, func(c context.Context) error {
+			if err := h.UsersService.RestoreUser(c, id); err != nil {
+				return err
+			}
+			return enable(c)
+		})
+	}
+
+	if err = h.UsersService.DeleteUser(ctx, id); err != nil {
+		return h.compensate(ctx, err, func(c context.Context) error {
+			if err := h.AccountsService.RestoreAccounts(c, closed); err != nil {
+				return err
+			}
+			if err := h.UsersService.RestoreUser(c, id); err != nil {
+				return err
+			}
+			return enable(c)
+		})
+	}
+
+	return nil
+}
+
+// compensate undoes the finished steps of a failed deleteUser and returns the failure,
+// an undo that fails too leaves the services out of step, which is logged for an operator
+func (h HTTPHandler) compensate(ctx context.Context, failure error, undo func(context.Context) error) error {
+	c := context.WithValue(context.Background(), model.ContextKeyRequestID, ctx.Value(model.ContextKeyRequestID))
+	// the undo outlives the request but stays in its trace
+	c = trace.ContextWithSpanContext(c, trace.SpanContextFromContext(ctx))
+	c = context.WithValue(c, model.IdentityKey, ctx.Value(model.IdentityKey))
+	c, cancel := context.WithTimeout(c, compensationTimeout)
+	defer cancel()
+
+	if err := undo(c); err != nil {
+		h.Logger.Error(c, "failed to undo the delete of a user", "failure", failure, "error", err)
+		return fmt.Errorf("%w, undo failed: %s", failure, err)
+	}
+
+	return failure
+}
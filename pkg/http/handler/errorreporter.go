@@ -3,38 +3,59 @@ package httphandler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 
-	log "github.com/sirupsen/logrus"
-
 	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/service/problem"
 )
 
-func (h HTTPHandler) reportError(w http.ResponseWriter, err error) {
-	var status int
+// statusOf is the HTTP status of every kind of domain error
+var statusOf = map[customErrors.DomainError]int{
+	customErrors.NotFound:           http.StatusNotFound,
+	customErrors.InvalidArgument:    http.StatusBadRequest,
+	customErrors.Conflict:           http.StatusConflict,
+	customErrors.FailedPrecondition: http.StatusUnprocessableEntity,
+	customErrors.PermissionDenied:   http.StatusForbidden,
+	customErrors.Unavailable:        http.StatusServiceUnavailable,
+}
 
+// httpStatus picks the status of err, a few specific errors have a more precise one than their kind
+func httpStatus(err error) int {
 	switch {
-	case errors.Is(err, customErrors.UUIDError) || errors.Is(err, customErrors.JSONError) || errors.Is(err, customErrors.AlreadyExists):
-		status = http.StatusBadRequest
-	case errors.Is(err, customErrors.NotFound):
-		status = http.StatusNotFound
+	case errors.Is(err, customErrors.VersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, customErrors.AccountsNotEmpty):
+		return http.StatusConflict
+	case errors.Is(err, customErrors.InvalidToken):
+		return http.StatusUnauthorized
 	case errors.Is(err, customErrors.DeadlineExceeded):
-		status = http.StatusGatewayTimeout
-	default:
-		status = http.StatusInternalServerError
+		return http.StatusGatewayTimeout
 	}
 
-	w.WriteHeader(status)
+	if kind, ok := customErrors.KindOf(err); ok {
+		return statusOf[kind]
+	}
 
-	if status == http.StatusInternalServerError {
-		log.Error(err)
-		return
+	return http.StatusInternalServerError
+}
+
+// reportError answers with the problem of err, server errors are logged as only the log gets their text
+func (h HTTPHandler) reportError(w http.ResponseWriter, req *http.Request, err error) {
+	status := httpStatus(err)
+	if status >= http.StatusInternalServerError {
+		h.Logger.Error(req.Context(), "internal error", "status", status, "error", err)
 	}
 
-	res, err := json.Marshal(err)
-	if err != nil {
-		return
+	problem.Error(w, req, status, err)
+}
+
+// decodeError names the field of a request body holding a value of the wrong type, other failures are a JSONError
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return customErrors.Field(typeErr.Field, "must be of type "+typeErr.Type.String())
 	}
 
-	w.Write(res) //nolint:errcheck
+	return fmt.Errorf("%s: %w", err, customErrors.JSONError)
 }
@@ -0,0 +1,48 @@
+package httphandler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+)
+
+// the version of an account or a user is its entity tag, a PUT with If-Match only applies to that version
+const (
+	etagHeader    = "ETag"
+	ifMatchHeader = "If-Match"
+)
+
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set(etagHeader, strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// expectedVersion reads If-Match, falls back to the version of the body when there is none.
+// "*" matches any version, 0 means no check.
+func expectedVersion(req *http.Request, fallback int64) (int64, error) {
+	tag := strings.TrimSpace(req.Header.Get(ifMatchHeader))
+	switch tag {
+	case "":
+		return fallback, nil
+	case "*":
+		return 0, nil
+	}
+
+	// If-Match compares strongly, a weak tag never matches (RFC 7232, 3.1)
+	if strings.HasPrefix(tag, "W/") {
+		return 0, fmt.Errorf("if-match %s: weak tag: %w", tag, customErrors.VersionMismatch)
+	}
+
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, fmt.Errorf("if-match %s: %w", tag, customErrors.VersionMismatch)
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("if-match %s: %w", tag, customErrors.VersionMismatch)
+	}
+
+	return version, nil
+}
@@ -3,16 +3,24 @@ package httphandler
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stasBigunenko/monorepa/customErrors"
 	mocks "github.com/stasBigunenko/monorepa/mocks/pkg/http/handler"
 	"github.com/stasBigunenko/monorepa/model"
+	httpservice "github.com/stasBigunenko/monorepa/service/http"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/problem"
 )
 
 const (
@@ -20,16 +28,24 @@ const (
 )
 
 type MockTokenService struct {
+	caller model.Identity
 }
 
-func (s MockTokenService) ParseToken(tokenHeader string) (string, error) {
-	return "", nil
+func (s MockTokenService) ParseToken(tokenHeader string) (model.Identity, error) {
+	return s.caller, nil
 }
 
-type MockLoggingService struct {
-}
+var admin = model.Identity{Name: "root", Roles: []string{model.RoleAdmin}}
 
-func (s MockLoggingService) WriteLog(ctx context.Context, message string) {}
+// credentials disable and enable the logins of every user
+var credentials = &mocks.MockCredentialService{
+	MockDisableUser: func(_ context.Context, _ uuid.UUID) error {
+		return nil
+	},
+	MockEnableUser: func(_ context.Context, _ uuid.UUID) error {
+		return nil
+	},
+}
 
 func TestHTTPHandler(t *testing.T) {
 	type fields struct {
@@ -46,7 +62,8 @@ func TestHTTPHandler(t *testing.T) {
 	}
 
 	type resp struct {
-		code int
+		code     int
+		location string
 	}
 
 	tests := []struct {
@@ -59,8 +76,8 @@ func TestHTTPHandler(t *testing.T) {
 			name: "POST /accounts OK",
 			fields: fields{
 				AccountsService: &mocks.MockAccountsGrpcServer{
-					MockCreateAccount: func(_ context.Context, _ uuid.UUID) (uuid.UUID, error) {
-						return uuid.New(), nil
+					MockCreateAccount: func(_ context.Context, _ uuid.UUID, _ string) (uuid.UUID, error) {
+						return uuid.MustParse("42b56c48-1b96-11ec-adc6-23ffd7a72bbb"), nil
 					},
 				},
 			},
@@ -69,13 +86,13 @@ func TestHTTPHandler(t *testing.T) {
 				method: "POST",
 				body:   []byte(`{"id":"32b56c48-1b96-11ec-adc6-23ffd7a72bbb"}`),
 			},
-			want: resp{code: http.StatusCreated},
+			want: resp{code: http.StatusCreated, location: "/accounts/42b56c48-1b96-11ec-adc6-23ffd7a72bbb"},
 		},
 		{
 			name: "POST /accounts !OK",
 			fields: fields{
 				AccountsService: &mocks.MockAccountsGrpcServer{
-					MockCreateAccount: func(_ context.Context, _ uuid.UUID) (uuid.UUID, error) {
+					MockCreateAccount: func(_ context.Context, _ uuid.UUID, _ string) (uuid.UUID, error) {
 						return uuid.Nil, customErrors.AlreadyExists
 					},
 				},
@@ -85,7 +102,23 @@ func TestHTTPHandler(t *testing.T) {
 				method: "POST",
 				body:   []byte(`{"id":"32b56c48-1b96-11ec-adc6-23ffd7a72bbb"}`),
 			},
-			want: resp{code: http.StatusBadRequest},
+			want: resp{code: http.StatusConflict},
+		},
+		{
+			name: "POST /accounts for a missing user",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockCreateAccount: func(_ context.Context, _ uuid.UUID, _ string) (uuid.UUID, error) {
+						return uuid.Nil, fmt.Errorf("failed to create account: account owner does not exist: %w", customErrors.FailedPrecondition)
+					},
+				},
+			},
+			args: args{
+				url:    "/accounts",
+				method: "POST",
+				body:   []byte(`{"user_id":"32b56c48-1b96-11ec-adc6-23ffd7a72bbb","currency":"EUR"}`),
+			},
+			want: resp{code: http.StatusUnprocessableEntity},
 		},
 		{
 			name: "GET /accounts/{id} OK",
@@ -121,8 +154,8 @@ func TestHTTPHandler(t *testing.T) {
 			name: "GET /accounts OK",
 			fields: fields{
 				AccountsService: &mocks.MockAccountsGrpcServer{
-					MockGetAllAccounts: func(_ context.Context) ([]model.Account, error) {
-						return []model.Account{}, nil
+					MockGetAllAccounts: func(_ context.Context, _ model.AccountFilter) (model.AccountPage, error) {
+						return model.AccountPage{}, nil
 					},
 				},
 			},
@@ -136,8 +169,8 @@ func TestHTTPHandler(t *testing.T) {
 			name: "GET /accounts !OK",
 			fields: fields{
 				AccountsService: &mocks.MockAccountsGrpcServer{
-					MockGetAllAccounts: func(_ context.Context) ([]model.Account, error) {
-						return nil, errors.New("strange error")
+					MockGetAllAccounts: func(_ context.Context, _ model.AccountFilter) (model.AccountPage, error) {
+						return model.AccountPage{}, errors.New("strange error")
 					},
 				},
 			},
@@ -151,31 +184,47 @@ func TestHTTPHandler(t *testing.T) {
 			name: "PUT /accounts/{id} OK",
 			fields: fields{
 				AccountsService: &mocks.MockAccountsGrpcServer{
-					MockUpdateAccount: func(_ context.Context, _ model.Account) error {
-						return nil
+					MockUpdateAccount: func(_ context.Context, account model.Account) (model.Account, error) {
+						return account, nil
 					},
 				},
 			},
 			args: args{
 				url:    "/accounts/32b56c48-1b96-11ec-adc6-23ffd7a72bbb",
 				method: "PUT",
-				body:   []byte(`{"balance":100}`),
+				body:   []byte(`{"overdraft_limit":100}`),
 			},
 			want: resp{code: http.StatusOK},
 		},
+		{
+			name: "PUT /accounts/{id} negative overdraft limit",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockUpdateAccount: func(_ context.Context, account model.Account) (model.Account, error) {
+						return account, nil
+					},
+				},
+			},
+			args: args{
+				url:    "/accounts/32b56c48-1b96-11ec-adc6-23ffd7a72bbb",
+				method: "PUT",
+				body:   []byte(`{"overdraft_limit":-100}`),
+			},
+			want: resp{code: http.StatusBadRequest},
+		},
 		{
 			name: "PUT /accounts/{id} !OK",
 			fields: fields{
 				AccountsService: &mocks.MockAccountsGrpcServer{
-					MockUpdateAccount: func(_ context.Context, _ model.Account) error {
-						return customErrors.DeadlineExceeded
+					MockUpdateAccount: func(_ context.Context, _ model.Account) (model.Account, error) {
+						return model.Account{}, customErrors.DeadlineExceeded
 					},
 				},
 			},
 			args: args{
 				url:    "/accounts/32b56c48-1b96-11ec-adc6-23ffd7a72bbb",
 				method: "PUT",
-				body:   []byte(`{"balance":100}`),
+				body:   []byte(`{"overdraft_limit":100}`),
 			},
 			want: resp{code: http.StatusGatewayTimeout},
 		},
@@ -209,13 +258,145 @@ func TestHTTPHandler(t *testing.T) {
 			},
 			want: resp{code: http.StatusNotFound},
 		},
+		{
+			name: "GET /accounts/{id}/transactions OK",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockListTransactions: func(_ context.Context, filter model.TransactionFilter) (model.TransactionPage, error) {
+						if filter.PageSize != 10 || filter.From.IsZero() {
+							return model.TransactionPage{}, customErrors.InvalidArgument
+						}
+						return model.TransactionPage{}, nil
+					},
+				},
+			},
+			args: args{
+				url:    "/accounts/32b56c48-1b96-11ec-adc6-23ffd7a72bbb/transactions?from=2021-10-01T00:00:00Z&page_size=10",
+				method: "GET",
+			},
+			want: resp{code: http.StatusOK},
+		},
+		{
+			name: "GET /accounts/{id}/transactions bad range",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{},
+			},
+			args: args{
+				url:    "/accounts/32b56c48-1b96-11ec-adc6-23ffd7a72bbb/transactions?from=yesterday",
+				method: "GET",
+			},
+			want: resp{code: http.StatusBadRequest},
+		},
+		{
+			name: "GET /accounts/{id}/transactions !OK",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockListTransactions: func(_ context.Context, _ model.TransactionFilter) (model.TransactionPage, error) {
+						return model.TransactionPage{}, customErrors.NotFound
+					},
+				},
+			},
+			args: args{
+				url:    "/accounts/32b56c48-1b96-11ec-adc6-23ffd7a72bbb/transactions",
+				method: "GET",
+			},
+			want: resp{code: http.StatusNotFound},
+		},
+		{
+			name: "POST /accounts/{id}/deposit OK",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockDeposit: func(_ context.Context, id uuid.UUID, amount int64) (model.Account, error) {
+						return model.Account{ID: id, Balance: amount}, nil
+					},
+				},
+			},
+			args: args{
+				url:    "/accounts/32b56c48-1b96-11ec-adc6-23ffd7a72bbb/deposit",
+				method: "POST",
+				body:   []byte(`{"amount": 10}`),
+			},
+			want: resp{code: http.StatusOK},
+		},
+		{
+			name: "POST /accounts/{id}/withdraw insufficient funds",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockWithdraw: func(_ context.Context, _ uuid.UUID, _ int64) (model.Account, error) {
+						return model.Account{}, customErrors.FailedPrecondition
+					},
+				},
+			},
+			args: args{
+				url:    "/accounts/32b56c48-1b96-11ec-adc6-23ffd7a72bbb/withdraw",
+				method: "POST",
+				body:   []byte(`{"amount": 10}`),
+			},
+			want: resp{code: http.StatusUnprocessableEntity},
+		},
+		{
+			name: "POST /accounts/{id}/withdraw bad json",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{},
+			},
+			args: args{
+				url:    "/accounts/32b56c48-1b96-11ec-adc6-23ffd7a72bbb/withdraw",
+				method: "POST",
+				body:   []byte(`{"amount": "ten"}`),
+			},
+			want: resp{code: http.StatusBadRequest},
+		},
+		{
+			name: "POST /transfers OK",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockTransfer: func(_ context.Context, _ model.Transfer) (model.TransferResult, error) {
+						return model.TransferResult{}, nil
+					},
+				},
+			},
+			args: args{
+				url:    "/transfers",
+				method: "POST",
+				body:   []byte(`{"from":"32b56c48-1b96-11ec-adc6-23ffd7a72bbb","to":"32b56c48-1b96-11ec-adc6-23ffd7a72bbc","amount":10}`),
+			},
+			want: resp{code: http.StatusOK},
+		},
+		{
+			name: "POST /transfers !OK",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockTransfer: func(_ context.Context, _ model.Transfer) (model.TransferResult, error) {
+						return model.TransferResult{}, customErrors.FailedPrecondition
+					},
+				},
+			},
+			args: args{
+				url:    "/transfers",
+				method: "POST",
+				body:   []byte(`{"from":"32b56c48-1b96-11ec-adc6-23ffd7a72bbb","to":"32b56c48-1b96-11ec-adc6-23ffd7a72bbc","amount":10}`),
+			},
+			want: resp{code: http.StatusUnprocessableEntity},
+		},
+		{
+			name: "POST /transfers bad json",
+			fields: fields{
+				AccountsService: &mocks.MockAccountsGrpcServer{},
+			},
+			args: args{
+				url:    "/transfers",
+				method: "POST",
+				body:   []byte(`{"from":`),
+			},
+			want: resp{code: http.StatusBadRequest},
+		},
 		/*----------Testing Users---------*/
 		{
 			name: "POST /users OK",
 			fields: fields{
 				UsersService: &mocks.MockUsersGrpcServer{
 					MockCreateUser: func(_ context.Context, _ string) (uuid.UUID, error) {
-						return uuid.New(), nil
+						return uuid.MustParse("32b56c48-1b96-11ec-adc6-23ffd7a72bbb"), nil
 					},
 				},
 			},
@@ -224,7 +405,7 @@ func TestHTTPHandler(t *testing.T) {
 				method: "POST",
 				body:   []byte(`{"id":"32b56c48-1b96-11ec-adc6-23ffd7a72bbb"}`),
 			},
-			want: resp{code: http.StatusCreated},
+			want: resp{code: http.StatusCreated, location: "/users/32b56c48-1b96-11ec-adc6-23ffd7a72bbb"},
 		},
 		{
 			name: "POST /users !OK",
@@ -240,7 +421,7 @@ func TestHTTPHandler(t *testing.T) {
 				method: "POST",
 				body:   []byte(`{"id":"32b56c48-1b96-11ec-adc6-23ffd7a72bbb"}`),
 			},
-			want: resp{code: http.StatusBadRequest},
+			want: resp{code: http.StatusConflict},
 		},
 		{
 			name: "GET /users/{id} OK",
@@ -276,8 +457,8 @@ func TestHTTPHandler(t *testing.T) {
 			name: "GET /users OK",
 			fields: fields{
 				UsersService: &mocks.MockUsersGrpcServer{
-					MockGetAllUsers: func(_ context.Context) ([]model.UserHTTP, error) {
-						return []model.UserHTTP{}, nil
+					MockGetAllUsers: func(_ context.Context, _ model.UserFilter) (model.UserPage, error) {
+						return model.UserPage{}, nil
 					},
 				},
 			},
@@ -291,8 +472,8 @@ func TestHTTPHandler(t *testing.T) {
 			name: "GET /users !OK",
 			fields: fields{
 				UsersService: &mocks.MockUsersGrpcServer{
-					MockGetAllUsers: func(_ context.Context) ([]model.UserHTTP, error) {
-						return nil, errors.New("strange error")
+					MockGetAllUsers: func(_ context.Context, _ model.UserFilter) (model.UserPage, error) {
+						return model.UserPage{}, errors.New("strange error")
 					},
 				},
 			},
@@ -306,8 +487,8 @@ func TestHTTPHandler(t *testing.T) {
 			name: "PUT /users/{id} OK",
 			fields: fields{
 				UsersService: &mocks.MockUsersGrpcServer{
-					MockUpdateUser: func(_ context.Context, _ model.UserHTTP) error {
-						return nil
+					MockUpdateUser: func(_ context.Context, user model.UserHTTP) (model.UserHTTP, error) {
+						return user, nil
 					},
 				},
 			},
@@ -322,8 +503,8 @@ func TestHTTPHandler(t *testing.T) {
 			name: "PUT /users/{id} !OK",
 			fields: fields{
 				UsersService: &mocks.MockUsersGrpcServer{
-					MockUpdateUser: func(_ context.Context, _ model.UserHTTP) error {
-						return customErrors.DeadlineExceeded
+					MockUpdateUser: func(_ context.Context, _ model.UserHTTP) (model.UserHTTP, error) {
+						return model.UserHTTP{}, customErrors.DeadlineExceeded
 					},
 				},
 			},
@@ -338,10 +519,18 @@ func TestHTTPHandler(t *testing.T) {
 			name: "DELETE /users/{id} OK",
 			fields: fields{
 				UsersService: &mocks.MockUsersGrpcServer{
+					MockSoftDeleteUser: func(_ context.Context, _ uuid.UUID) error {
+						return nil
+					},
 					MockDeleteUser: func(_ context.Context, _ uuid.UUID) error {
 						return nil
 					},
 				},
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockCloseUserAccounts: func(_ context.Context, _ uuid.UUID, _ bool) ([]model.Account, error) {
+						return []model.Account{}, nil
+					},
+				},
 			},
 			args: args{
 				url:    "/users/32b56c48-1b96-11ec-adc6-23ffd7a72bbb",
@@ -353,7 +542,7 @@ func TestHTTPHandler(t *testing.T) {
 			name: "DELETE /users/{id} !OK",
 			fields: fields{
 				UsersService: &mocks.MockUsersGrpcServer{
-					MockDeleteUser: func(_ context.Context, _ uuid.UUID) error {
+					MockSoftDeleteUser: func(_ context.Context, _ uuid.UUID) error {
 						return customErrors.NotFound
 					},
 				},
@@ -408,10 +597,11 @@ func TestHTTPHandler(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &HTTPHandler{
-				AccountsService: tt.fields.AccountsService,
-				UsersService:    tt.fields.UsersService,
-				TokenService:    MockTokenService{},
-				LoggingService:  MockLoggingService{},
+				AccountsService:   tt.fields.AccountsService,
+				UsersService:      tt.fields.UsersService,
+				TokenService:      MockTokenService{caller: admin},
+				CredentialService: credentials,
+				Logger:            loggingservice.LoggingService{},
 			}
 
 			hs := httptest.NewServer(s.GetRouter())
@@ -433,6 +623,585 @@ func TestHTTPHandler(t *testing.T) {
 					t.Errorf("%s %s = %v, want %v", tt.args.method, tt.args.url, r.StatusCode, tt.want.code)
 				}
 			}
+			if tt.want.location != "" && r.Header.Get("Location") != tt.want.location {
+				t.Errorf("Location = %q, want %q", r.Header.Get("Location"), tt.want.location)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_Policy(t *testing.T) {
+	bobID := uuid.MustParse("32b56c48-1b96-11ec-adc6-23ffd7a72bbb")
+	bob := model.Identity{Name: "bob", UserID: bobID, Roles: []string{model.RoleUser}}
+	aliceID := uuid.MustParse("32b56c48-1b96-11ec-adc6-23ffd7a72ccc")
+	bobAccount := uuid.MustParse("42b56c48-1b96-11ec-adc6-23ffd7a72bbb")
+	aliceAccount := uuid.MustParse("42b56c48-1b96-11ec-adc6-23ffd7a72ccc")
+
+	users := &mocks.MockUsersGrpcServer{
+		MockGetUser: func(_ context.Context, id uuid.UUID) (model.UserHTTP, error) {
+			if id == bobID {
+				return model.UserHTTP{ID: id, Name: "bob"}, nil
+			}
+			return model.UserHTTP{ID: id, Name: "alice"}, nil
+		},
+		MockGetAllUsers: func(_ context.Context, _ model.UserFilter) (model.UserPage, error) {
+			return model.UserPage{}, nil
+		},
+		MockSoftDeleteUser: func(_ context.Context, _ uuid.UUID) error {
+			return nil
+		},
+		MockDeleteUser: func(_ context.Context, _ uuid.UUID) error {
+			return nil
+		},
+	}
+	accounts := &mocks.MockAccountsGrpcServer{
+		MockCloseUserAccounts: func(_ context.Context, _ uuid.UUID, _ bool) ([]model.Account, error) {
+			return []model.Account{}, nil
+		},
+		MockGetAccount: func(_ context.Context, id uuid.UUID) (model.Account, error) {
+			if id == bobAccount {
+				return model.Account{ID: id, UserID: bobID}, nil
+			}
+			return model.Account{ID: id, UserID: aliceID}, nil
+		},
+		MockGetAllAccounts: func(_ context.Context, _ model.AccountFilter) (model.AccountPage, error) {
+			return model.AccountPage{}, nil
+		},
+		MockUpdateAccount: func(_ context.Context, account model.Account) (model.Account, error) {
+			return account, nil
+		},
+		MockTransfer: func(_ context.Context, transfer model.Transfer) (model.TransferResult, error) {
+			return model.TransferResult{}, nil
+		},
+	}
+
+	tests := []struct {
+		name   string
+		caller model.Identity
+		method string
+		url    string
+		body   string
+		code   int
+	}{
+		{name: "user lists users", caller: bob, method: "GET", url: "/users", code: http.StatusForbidden},
+		{name: "admin lists users", caller: admin, method: "GET", url: "/users", code: http.StatusOK},
+		{name: "user lists accounts", caller: bob, method: "GET", url: "/accounts", code: http.StatusForbidden},
+		{name: "user deletes user", caller: bob, method: "DELETE", url: "/users/" + bobID.String(), code: http.StatusForbidden},
+		{name: "admin deletes user", caller: admin, method: "DELETE", url: "/users/" + aliceID.String(), code: http.StatusOK},
+		{name: "user reads himself", caller: bob, method: "GET", url: "/users/" + bobID.String(), code: http.StatusOK},
+		{name: "user reads another user", caller: bob, method: "GET", url: "/users/" + aliceID.String(), code: http.StatusForbidden},
+		{name: "owner reads account", caller: bob, method: "GET", url: "/accounts/" + bobAccount.String(), code: http.StatusOK},
+		{name: "user reads foreign account", caller: bob, method: "GET", url: "/accounts/" + aliceAccount.String(), code: http.StatusForbidden},
+		{name: "admin reads foreign account", caller: admin, method: "GET", url: "/accounts/" + aliceAccount.String(), code: http.StatusOK},
+		{name: "user updates foreign account", caller: bob, method: "PUT", url: "/accounts/" + aliceAccount.String(), body: `{"balance":100}`, code: http.StatusForbidden},
+		{name: "owner updates account", caller: bob, method: "PUT", url: "/accounts/" + bobAccount.String(), body: `{"user_id":"` + bobID.String() + `","overdraft_limit":0}`, code: http.StatusOK},
+		{name: "owner changes balance", caller: bob, method: "PUT", url: "/accounts/" + bobAccount.String(), body: `{"balance":100}`, code: http.StatusUnprocessableEntity},
+		{name: "owner changes overdraft limit", caller: bob, method: "PUT", url: "/accounts/" + bobAccount.String(), body: `{"overdraft_limit":1000}`, code: http.StatusForbidden},
+		{name: "admin changes balance", caller: admin, method: "PUT", url: "/accounts/" + bobAccount.String(), body: `{"balance":100}`, code: http.StatusUnprocessableEntity},
+		{name: "admin changes overdraft limit", caller: admin, method: "PUT", url: "/accounts/" + bobAccount.String(), body: `{"overdraft_limit":1000}`, code: http.StatusOK},
+		{name: "user transfers from own account", caller: bob, method: "POST", url: "/transfers", body: `{"from":"` + bobAccount.String() + `","to":"` + aliceAccount.String() + `","amount":1}`, code: http.StatusOK},
+		{name: "user transfers from foreign account", caller: bob, method: "POST", url: "/transfers", body: `{"from":"` + aliceAccount.String() + `","to":"` + bobAccount.String() + `","amount":1}`, code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &HTTPHandler{
+				AccountsService:   accounts,
+				UsersService:      users,
+				TokenService:      MockTokenService{caller: tt.caller},
+				CredentialService: credentials,
+				Logger:            loggingservice.LoggingService{},
+			}
+
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", headerString)
+			rec := httptest.NewRecorder()
+			s.GetRouter().ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("%s %s = %v, want %v", tt.method, tt.url, rec.Code, tt.code)
+			}
+
+			if tt.code == http.StatusForbidden {
+				var denied problem.Problem
+				if err := json.NewDecoder(rec.Body).Decode(&denied); err != nil || denied.Type != problem.TypePrefix+"permission-denied" ||
+					denied.Detail == "" || denied.RequestID == "" {
+					t.Errorf("unexpected body of denied request: %+v %v", denied, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_ListPages(t *testing.T) {
+	ownerID := uuid.New()
+
+	var gotAccounts model.AccountFilter
+	var gotUsers model.UserFilter
+	s := &HTTPHandler{
+		AccountsService: &mocks.MockAccountsGrpcServer{
+			MockGetAllAccounts: func(_ context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+				gotAccounts = filter
+				return model.AccountPage{Accounts: []model.Account{{UserID: ownerID}}, NextPageToken: "acc-next"}, nil
+			},
+		},
+		UsersService: &mocks.MockUsersGrpcServer{
+			MockGetAllUsers: func(_ context.Context, filter model.UserFilter) (model.UserPage, error) {
+				gotUsers = filter
+				return model.UserPage{Users: []model.UserHTTP{{Name: "bob"}}}, nil
+			},
+		},
+		TokenService: MockTokenService{caller: admin},
+		Logger:       loggingservice.LoggingService{},
+	}
+
+	serve := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", headerString)
+		rec := httptest.NewRecorder()
+		s.GetRouter().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/accounts?user_id=" + ownerID.String() + "&min_balance=10&sort=-balance&page_size=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /accounts = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if gotAccounts.UserID != ownerID || gotAccounts.MinBalance == nil || *gotAccounts.MinBalance != 10 ||
+		gotAccounts.MaxBalance != nil || gotAccounts.Sort != "-balance" || gotAccounts.PageSize != 1 {
+		t.Errorf("unexpected account filter: %+v", gotAccounts)
+	}
+	if got := rec.Header().Get("X-Next-Page-Token"); got != "acc-next" {
+		t.Errorf("X-Next-Page-Token = %q, want %q", got, "acc-next")
+	}
+	wantLink := `</accounts?min_balance=10&page_size=1&page_token=acc-next&sort=-balance&user_id=` + ownerID.String() + `>; rel="next"`
+	if got := rec.Header().Get("Link"); got != wantLink {
+		t.Errorf("Link = %q, want %q", got, wantLink)
+	}
+	var accounts []model.Account
+	if err := json.NewDecoder(rec.Body).Decode(&accounts); err != nil || len(accounts) != 1 {
+		t.Errorf("accounts are not a json array: %v %v", accounts, err)
+	}
+
+	rec = serve("/users?name_prefix=bo&page_token=abc")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /users = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if gotUsers.NamePrefix != "bo" || gotUsers.PageToken != "abc" {
+		t.Errorf("unexpected user filter: %+v", gotUsers)
+	}
+	if rec.Header().Get("Link") != "" || rec.Header().Get("X-Next-Page-Token") != "" {
+		t.Errorf("the last page must not link to a next one: %v", rec.Header())
+	}
+
+	for _, url := range []string{"/accounts?min_balance=lots", "/accounts?user_id=bob", "/users?page_size=ten"} {
+		if rec = serve(url); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %v, want %v", url, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestHTTPHandler_DeleteUserPolicies(t *testing.T) {
+	userID := uuid.New()
+	closed := []model.Account{{ID: uuid.New(), UserID: userID}}
+
+	tests := []struct {
+		name          string
+		policy        string
+		disableErr    error
+		softDeleteErr error
+		closeErr      error
+		deleteErr     error
+		restoreErr    error
+		code          int
+		calls         []string
+		restoredFunds []model.Account
+	}{
+		{name: "restrict closes empty accounts", policy: "", code: http.StatusOK,
+			calls: []string{"disable", "soft delete", "close false", "delete"}},
+		{name: "restrict refuses funded accounts", policy: "restrict", closeErr: customErrors.AccountsNotEmpty, code: http.StatusConflict,
+			calls: []string{"disable", "soft delete", "close false", "restore user", "enable"}},
+		{name: "cascade forces the close", policy: "cascade", code: http.StatusOK,
+			calls: []string{"disable", "soft delete", "close true", "delete"}},
+		{name: "soft keeps the accounts", policy: "soft", code: http.StatusOK,
+			calls: []string{"disable", "soft delete"}},
+		{name: "failed soft delete enables the logins", policy: "soft", softDeleteErr: customErrors.NotFound, code: http.StatusNotFound,
+			calls: []string{"disable", "soft delete", "enable"}},
+		{name: "failed disable deletes nothing", policy: "cascade", disableErr: customErrors.Unavailable, code: http.StatusServiceUnavailable,
+			calls: []string{"disable"}},
+		{name: "failed delete restores accounts and user", policy: "cascade", deleteErr: errors.New("storage down"), code: http.StatusInternalServerError,
+			calls: []string{"disable", "soft delete", "close true", "delete", "restore accounts", "restore user", "enable"}, restoredFunds: closed},
+		{name: "failed undo keeps the cause", policy: "restrict", closeErr: customErrors.AccountsNotEmpty, restoreErr: errors.New("user service down"), code: http.StatusConflict,
+			calls: []string{"disable", "soft delete", "close false", "restore user"}},
+		{name: "unknown policy", policy: "shred", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			var restored []model.Account
+			s := &HTTPHandler{
+				UsersService: &mocks.MockUsersGrpcServer{
+					MockSoftDeleteUser: func(_ context.Context, _ uuid.UUID) error {
+						calls = append(calls, "soft delete")
+						return tt.softDeleteErr
+					},
+					MockDeleteUser: func(_ context.Context, _ uuid.UUID) error {
+						calls = append(calls, "delete")
+						return tt.deleteErr
+					},
+					MockRestoreUser: func(c context.Context, _ uuid.UUID) error {
+						calls = append(calls, "restore user")
+						if _, ok := c.Deadline(); !ok {
+							t.Error("undo steps must be bounded in time")
+						}
+						return tt.restoreErr
+					},
+				},
+				AccountsService: &mocks.MockAccountsGrpcServer{
+					MockCloseUserAccounts: func(_ context.Context, _ uuid.UUID, force bool) ([]model.Account, error) {
+						calls = append(calls, fmt.Sprintf("close %v", force))
+						if tt.closeErr != nil {
+							return nil, tt.closeErr
+						}
+						return closed, nil
+					},
+					MockRestoreAccounts: func(_ context.Context, accounts []model.Account) error {
+						calls = append(calls, "restore accounts")
+						restored = accounts
+						return nil
+					},
+				},
+				CredentialService: &mocks.MockCredentialService{
+					MockDisableUser: func(_ context.Context, _ uuid.UUID) error {
+						calls = append(calls, "disable")
+						return tt.disableErr
+					},
+					MockEnableUser: func(_ context.Context, _ uuid.UUID) error {
+						calls = append(calls, "enable")
+						return nil
+					},
+				},
+				TokenService: MockTokenService{caller: admin},
+				Logger:       loggingservice.LoggingService{},
+			}
+
+			url := "/users/" + userID.String()
+			if tt.policy != "" {
+				url += "?policy=" + tt.policy
+			}
+			req := httptest.NewRequest("DELETE", url, nil)
+			req.Header.Set("Authorization", headerString)
+			rec := httptest.NewRecorder()
+			s.GetRouter().ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("DELETE %s = %v, want %v", url, rec.Code, tt.code)
+			}
+			if !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("calls = %v, want %v", calls, tt.calls)
+			}
+			if !reflect.DeepEqual(restored, tt.restoredFunds) {
+				t.Errorf("restored accounts = %v, want %v", restored, tt.restoredFunds)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_ETag(t *testing.T) {
+	id := uuid.MustParse("32b56c48-1b96-11ec-adc6-23ffd7a72bbb")
+	stored := model.Account{ID: id, Balance: 100, Version: 3}
+
+	var gotVersion int64
+	s := &HTTPHandler{
+		AccountsService: &mocks.MockAccountsGrpcServer{
+			MockGetAccount: func(_ context.Context, _ uuid.UUID) (model.Account, error) {
+				return stored, nil
+			},
+			MockUpdateAccount: func(_ context.Context, account model.Account) (model.Account, error) {
+				gotVersion = account.Version
+				if account.Version != 0 && account.Version != stored.Version {
+					return model.Account{}, fmt.Errorf("failed to update account: %w", customErrors.VersionMismatch)
+				}
+				account.Version = stored.Version + 1
+				return account, nil
+			},
+		},
+		TokenService: MockTokenService{caller: admin},
+		Logger:       loggingservice.LoggingService{},
+	}
+
+	serve := func(method, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/accounts/"+id.String(), bytes.NewReader([]byte(body)))
+		req.Header.Set("Authorization", headerString)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		s.GetRouter().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("GET", "", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Fatalf("GET = %v with ETag %q, want %v with %q", rec.Code, rec.Header().Get("ETag"), http.StatusOK, `"3"`)
+	}
+
+	tests := []struct {
+		name        string
+		ifMatch     string
+		body        string
+		wantCode    int
+		wantVersion int64
+		wantETag    string
+	}{
+		{name: "current version", ifMatch: `"3"`, body: `{"overdraft_limit":50}`, wantCode: http.StatusOK, wantVersion: 3, wantETag: `"4"`},
+		{name: "stale version", ifMatch: `"2"`, body: `{"overdraft_limit":50}`, wantCode: http.StatusPreconditionFailed, wantVersion: 2},
+		{name: "header wins over body", ifMatch: `"2"`, body: `{"overdraft_limit":50,"version":3}`, wantCode: http.StatusPreconditionFailed, wantVersion: 2},
+		{name: "any version", ifMatch: "*", body: `{"overdraft_limit":50,"version":2}`, wantCode: http.StatusOK, wantVersion: 0, wantETag: `"4"`},
+		{name: "version of the body", body: `{"overdraft_limit":50,"version":2}`, wantCode: http.StatusPreconditionFailed, wantVersion: 2},
+		{name: "unparsable tag", ifMatch: "three", body: `{"overdraft_limit":50}`, wantCode: http.StatusPreconditionFailed, wantVersion: -1},
+		{name: "weak tag", ifMatch: `W/"3"`, body: `{"overdraft_limit":50}`, wantCode: http.StatusPreconditionFailed, wantVersion: -1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotVersion = -1
+			rec := serve("PUT", tc.ifMatch, tc.body)
+			if rec.Code != tc.wantCode {
+				t.Errorf("PUT = %v, want %v", rec.Code, tc.wantCode)
+			}
+			if gotVersion != tc.wantVersion {
+				t.Errorf("expected version = %v, want %v", gotVersion, tc.wantVersion)
+			}
+			if got := rec.Header().Get("ETag"); got != tc.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tc.wantETag)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_Idempotency(t *testing.T) {
+	created := 0
+	failing := true
+	s := &HTTPHandler{
+		UsersService: &mocks.MockUsersGrpcServer{
+			MockCreateUser: func(_ context.Context, _ string) (uuid.UUID, error) {
+				if failing {
+					failing = false
+					return uuid.Nil, customErrors.DeadlineExceeded
+				}
+				created++
+				return uuid.New(), nil
+			},
+		},
+		TokenService: MockTokenService{caller: admin},
+		Logger:       loggingservice.LoggingService{},
+		Idempotency:  httpservice.NewIdempotencyCache(time.Hour),
+	}
+
+	serve := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/users", bytes.NewReader([]byte(body)))
+		req.Header.Set("Authorization", headerString)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		s.GetRouter().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("key", `{"name":"bob"}`); rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("POST = %v, want %v", rec.Code, http.StatusGatewayTimeout)
+	}
+
+	first := serve("key", `{"name":"bob"}`)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("retry after a timeout = %v, want a fresh %v", first.Code, http.StatusCreated)
+	}
+
+	retry := serve("key", `{"name":"bob"}`)
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry = %v replayed %q, want a replayed %v", retry.Code, retry.Header().Get("Idempotent-Replayed"), http.StatusCreated)
+	}
+	if retry.Header().Get("Location") != first.Header().Get("Location") {
+		t.Errorf("Location = %q, want %q", retry.Header().Get("Location"), first.Header().Get("Location"))
+	}
+	if created != 1 {
+		t.Errorf("created %d users, want 1", created)
+	}
+
+	if rec := serve("key", `{"name":"alice"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key = %v, want %v", rec.Code, http.StatusUnprocessableEntity)
+	}
+
+	serve("", `{"name":"bob"}`)
+	serve("", `{"name":"bob"}`)
+	if created != 3 {
+		t.Errorf("created %d users, want 3 without a key", created)
+	}
+}
+
+func TestHTTPHandler_IdempotencyPanic(t *testing.T) {
+	panicking := true
+	s := &HTTPHandler{
+		UsersService: &mocks.MockUsersGrpcServer{
+			MockCreateUser: func(_ context.Context, _ string) (uuid.UUID, error) {
+				if panicking {
+					panicking = false
+					panic("boom")
+				}
+				return uuid.New(), nil
+			},
+		},
+		TokenService: MockTokenService{caller: admin},
+		Logger:       loggingservice.LoggingService{},
+		Idempotency:  httpservice.NewIdempotencyCache(time.Hour),
+	}
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/users", bytes.NewReader([]byte(`{"name":"bob"}`)))
+		req.Header.Set("Authorization", headerString)
+		req.Header.Set("Idempotency-Key", "key")
+		rec := httptest.NewRecorder()
+		s.GetRouter().ServeHTTP(rec, req)
+		return rec
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the panic of the handler", r)
+			}
+		}()
+		serve()
+	}()
+
+	if rec := serve(); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a panic = %v, want a fresh %v", rec.Code, http.StatusCreated)
+	}
+}
+
+func TestHTTPHandler_ListAudit(t *testing.T) {
+	id := uuid.MustParse("32b56c48-1b96-11ec-adc6-23ffd7a72bbb")
+	from := time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+	bob := model.Identity{Name: "bob", UserID: id, Roles: []string{model.RoleUser}}
+
+	var got model.AuditFilter
+	audit := &mocks.MockAuditGrpcServer{
+		MockListAudit: func(_ context.Context, filter model.AuditFilter) (model.AuditPage, error) {
+			got = filter
+			if filter.Resource != model.AuditResourceAccount && filter.Resource != model.AuditResourceUser {
+				return model.AuditPage{}, fmt.Errorf("unknown resource %q: %w", filter.Resource, customErrors.InvalidArgument)
+			}
+			return model.AuditPage{Entries: []model.AuditEntry{{ResourceID: id, Operation: model.AuditUpdate}}, NextPageToken: "1"}, nil
+		},
+	}
+
+	tests := []struct {
+		name   string
+		caller model.Identity
+		url    string
+		code   int
+		want   model.AuditFilter
+	}{
+		{
+			name:   "all filters",
+			caller: admin,
+			url:    "/audit?resource=account&resource_id=" + id.String() + "&actor=root&operation=update&from=2021-09-01T00:00:00Z&page_size=10&page_token=20",
+			code:   http.StatusOK,
+			want: model.AuditFilter{Resource: model.AuditResourceAccount, ResourceID: id, Actor: "root", Operation: model.AuditUpdate,
+				From: from, PageSize: 10, PageToken: "20"},
+		},
+		{name: "not an admin", caller: bob, url: "/audit?resource=account", code: http.StatusForbidden},
+		{name: "unknown resource", caller: admin, url: "/audit?resource=transfer", code: http.StatusBadRequest,
+			want: model.AuditFilter{Resource: "transfer"}},
+		{name: "bad resource id", caller: admin, url: "/audit?resource=user&resource_id=1", code: http.StatusBadRequest},
+		{name: "bad time", caller: admin, url: "/audit?resource=user&to=yesterday", code: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got = model.AuditFilter{}
+			s := &HTTPHandler{
+				AuditService: audit,
+				TokenService: MockTokenService{caller: tc.caller},
+				Logger:       loggingservice.LoggingService{},
+			}
+
+			req := httptest.NewRequest("GET", tc.url, nil)
+			req.Header.Set("Authorization", headerString)
+			rec := httptest.NewRecorder()
+			s.GetRouter().ServeHTTP(rec, req)
+
+			if rec.Code != tc.code {
+				t.Errorf("GET %s = %v, want %v", tc.url, rec.Code, tc.code)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("filter = %+v, want %+v", got, tc.want)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var page model.AuditPage
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil || len(page.Entries) != 1 || page.NextPageToken != "1" {
+				t.Errorf("page = %+v, %v", page, err)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_Problems(t *testing.T) {
+	s := &HTTPHandler{
+		AccountsService: &mocks.MockAccountsGrpcServer{
+			MockGetAccount: func(_ context.Context, _ uuid.UUID) (model.Account, error) {
+				return model.Account{}, errors.New("pq: password authentication failed for user bank")
+			},
+		},
+		TokenService: MockTokenService{caller: admin},
+		Logger:       loggingservice.LoggingService{},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		auth   bool
+		status int
+		typ    string
+		fields []customErrors.FieldError
+	}{
+		{name: "field", url: "/accounts?page_size=ten", auth: true, status: http.StatusBadRequest, typ: problem.TypePrefix + "invalid-argument",
+			fields: []customErrors.FieldError{{Field: "page_size", Message: "must be an integer"}}},
+		{name: "no token", url: "/accounts", status: http.StatusForbidden, typ: problem.TypePrefix + "permission-denied"},
+		{name: "internal", url: "/accounts/" + uuid.New().String(), auth: true, status: http.StatusInternalServerError, typ: "about:blank"},
+		{name: "bad body", method: "POST", url: "/users", body: `{"name":`, auth: true, status: http.StatusBadRequest, typ: problem.TypePrefix + "invalid-json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req := httptest.NewRequest(method, tt.url, strings.NewReader(tt.body))
+			if tt.auth {
+				req.Header.Set("Authorization", headerString)
+			}
+			rec := httptest.NewRecorder()
+			s.GetRouter().ServeHTTP(rec, req)
+
+			if rec.Code != tt.status || rec.Header().Get("Content-Type") != problem.ContentType {
+				t.Fatalf("%s %s = %v %q, want %v %q", method, tt.url, rec.Code, rec.Header().Get("Content-Type"), tt.status, problem.ContentType)
+			}
+
+			var got problem.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Type != tt.typ || got.Status != tt.status || got.Title != http.StatusText(tt.status) ||
+				got.Instance != req.URL.Path || got.RequestID == "" || !reflect.DeepEqual(got.Errors, tt.fields) {
+				t.Errorf("unexpected problem: %+v", got)
+			}
+			if strings.Contains(got.Detail, "pq:") {
+				t.Errorf("the detail leaks the internal error: %q", got.Detail)
+			}
 		})
 	}
 }
@@ -0,0 +1,114 @@
+package httphandler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyMiddleware answers a retried POST, PUT or DELETE with the response to the first attempt.
+// Keys belong to the caller, the same key with another method, path or body is refused.
+func (h HTTPHandler) IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(idempotencyKeyHeader)
+		if h.Idempotency == nil || key == "" || !mutating(req.Method) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			h.reportError(w, req, customErrors.Field(idempotencyKeyHeader, fmt.Sprintf("must be at most %d characters", maxIdempotencyKeyLength)))
+			return
+		}
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			h.reportError(w, req, err)
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		caller, _ := req.Context().Value(model.IdentityKey).(model.Identity)
+		key = caller.Name + "\x00" + key
+
+		stored, err := h.Idempotency.Begin(key, fingerprint(req, body))
+		if err != nil {
+			h.reportError(w, req, err)
+			return
+		}
+		if stored != nil {
+			replay(w, *stored)
+			return
+		}
+
+		// a panicking handler would otherwise keep the key in progress until it expires
+		defer func() {
+			if r := recover(); r != nil {
+				h.Idempotency.Release(key)
+				panic(r)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, req)
+
+		// failures of the gateway or the services are worth a retry, they are not kept
+		if rec.status >= http.StatusInternalServerError {
+			h.Idempotency.Release(key)
+			return
+		}
+		h.Idempotency.Finish(key, model.StoredResponse{Status: rec.status, Header: rec.Header().Clone(), Body: rec.body.Bytes()})
+	})
+}
+
+func mutating(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodDelete
+}
+
+func fingerprint(req *http.Request, body []byte) string {
+	sum := sha256.Sum256(body)
+	return req.Method + " " + req.URL.RequestURI() + " " + hex.EncodeToString(sum[:])
+}
+
+func replay(w http.ResponseWriter, resp model.StoredResponse) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(replayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body) //nolint:errcheck
+}
+
+// responseRecorder passes the response through and keeps a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -8,26 +8,46 @@ import (
 )
 
 type AccountGrpcService interface {
-	CreateAccount(ctx context.Context, userID uuid.UUID) (uuid.UUID, error)
+	CreateAccount(ctx context.Context, userID uuid.UUID, currency string) (uuid.UUID, error)
 	GetAccount(ctx context.Context, id uuid.UUID) (model.Account, error)
 	GetUserAccounts(ctx context.Context, userID uuid.UUID) ([]model.Account, error)
-	GetAllAccounts(ctx context.Context) ([]model.Account, error)
-	UpdateAccount(ctx context.Context, account model.Account) error
+	GetAllAccounts(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error)
+	UpdateAccount(ctx context.Context, account model.Account) (model.Account, error)
 	DeleteAccount(ctx context.Context, id uuid.UUID) error
+	CloseUserAccounts(ctx context.Context, userID uuid.UUID, force bool) ([]model.Account, error)
+	RestoreAccounts(ctx context.Context, accounts []model.Account) error
+	Transfer(ctx context.Context, transfer model.Transfer) (model.TransferResult, error)
+	ListTransactions(ctx context.Context, filter model.TransactionFilter) (model.TransactionPage, error)
+	Deposit(ctx context.Context, id uuid.UUID, amount int64) (model.Account, error)
+	Withdraw(ctx context.Context, id uuid.UUID, amount int64) (model.Account, error)
 }
 
 type UserGrpcService interface {
 	CreateUser(ctx context.Context, name string) (uuid.UUID, error)
 	GetUser(ctx context.Context, id uuid.UUID) (model.UserHTTP, error)
-	GetAllUsers(ctx context.Context) ([]model.UserHTTP, error)
-	UpdateUser(ctx context.Context, user model.UserHTTP) error
+	GetAllUsers(ctx context.Context, filter model.UserFilter) (model.UserPage, error)
+	UpdateUser(ctx context.Context, user model.UserHTTP) (model.UserHTTP, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	SoftDeleteUser(ctx context.Context, id uuid.UUID) error
+	RestoreUser(ctx context.Context, id uuid.UUID) error
+}
+
+type AuditGrpcService interface {
+	ListAudit(ctx context.Context, filter model.AuditFilter) (model.AuditPage, error)
+}
+
+// CredentialService disables the logins of deleted users, it is implemented by the auth service client
+type CredentialService interface {
+	DisableUser(ctx context.Context, id uuid.UUID) error
+	EnableUser(ctx context.Context, id uuid.UUID) error
 }
 
 type TokenService interface {
-	ParseToken(tokenPart string) (string, error)
+	ParseToken(tokenPart string) (model.Identity, error)
 }
 
-type LoggingService interface {
-	WriteLog(ctx context.Context, message string)
+type IdempotencyStore interface {
+	Begin(key, fingerprint string) (*model.StoredResponse, error)
+	Finish(key string, resp model.StoredResponse)
+	Release(key string)
 }
@@ -0,0 +1,91 @@
+package httphandler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// list responses stay plain json arrays, the next page is advertised in the headers
+const nextPageTokenHeader = "X-Next-Page-Token"
+
+func userFilter(query url.Values) (model.UserFilter, error) {
+	filter := model.UserFilter{
+		NamePrefix: query.Get("name_prefix"),
+		Sort:       query.Get("sort"),
+		PageToken:  query.Get("page_token"),
+	}
+
+	var err error
+	if size := query.Get("page_size"); size != "" {
+		if filter.PageSize, err = strconv.Atoi(size); err != nil {
+			return model.UserFilter{}, customErrors.Field("page_size", "must be an integer")
+		}
+	}
+
+	return filter, nil
+}
+
+func accountFilter(query url.Values) (model.AccountFilter, error) {
+	filter := model.AccountFilter{
+		Sort:      query.Get("sort"),
+		PageToken: query.Get("page_token"),
+	}
+
+	var err error
+	if userID := query.Get("user_id"); userID != "" {
+		if filter.UserID, err = uuid.Parse(userID); err != nil {
+			return model.AccountFilter{}, customErrors.Field("user_id", "must be a uuid")
+		}
+	}
+
+	if filter.MinBalance, err = intParam(query, "min_balance"); err != nil {
+		return model.AccountFilter{}, err
+	}
+
+	if filter.MaxBalance, err = intParam(query, "max_balance"); err != nil {
+		return model.AccountFilter{}, err
+	}
+
+	if size := query.Get("page_size"); size != "" {
+		if filter.PageSize, err = strconv.Atoi(size); err != nil {
+			return model.AccountFilter{}, customErrors.Field("page_size", "must be an integer")
+		}
+	}
+
+	return filter, nil
+}
+
+// intParam returns nil when the parameter is absent
+func intParam(query url.Values, name string) (*int64, error) {
+	v := query.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, customErrors.Field(name, "must be an integer")
+	}
+
+	return &n, nil
+}
+
+// setNextPage points the client at the following page, keeping the other query parameters
+func setNextPage(w http.ResponseWriter, req *http.Request, token string) {
+	if token == "" {
+		return
+	}
+
+	query := req.URL.Query()
+	query.Set("page_token", token)
+
+	w.Header().Set(nextPageTokenHeader, token)
+	w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, req.URL.Path, query.Encode()))
+}
@@ -2,12 +2,13 @@ package httphandler
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
 
+	"github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/model"
 )
 
@@ -17,41 +18,69 @@ import (
 
 func (h HTTPHandler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		log.Info("Auth starting...")
+		h.Logger.Debug(req.Context(), "auth starting")
 
 		tokenHeader := req.Header.Get("Authorization")
 		if tokenHeader == "" {
-			w.WriteHeader(http.StatusForbidden)
+			h.reportError(w, req, fmt.Errorf("no authorization header: %w", customErrors.PermissionDenied))
 			return
 		}
 
-		name, err := h.TokenService.ParseToken(tokenHeader)
+		caller, err := h.TokenService.ParseToken(tokenHeader)
 		if err != nil {
-			h.reportError(w, err)
+			h.reportError(w, req, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 
-		ctx := context.WithValue(req.Context(), model.NameKey, name)
+		ctx := context.WithValue(req.Context(), model.NameKey, caller.Name)
+		ctx = context.WithValue(ctx, model.IdentityKey, caller)
 		next.ServeHTTP(w, req.WithContext(ctx))
 	})
 }
 
+// RequestIDMiddleware runs before the auth, so the problems of refused requests carry the id too
 func (h HTTPHandler) RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		ctx := req.Context()
-		name, ok := ctx.Value(model.NameKey).(string)
-		if !ok {
-			h.reportError(w, errors.New("failed to generate context value"))
+		ctx := context.WithValue(req.Context(), model.ContextKeyRequestID, uuid.New().String())
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// LoggingMiddleware logs every request with its status and latency, server failures as errors
+func (h HTTPHandler) LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+
+		keyvals := []interface{}{"method", req.Method, "path", req.URL.Path, "status", sw.status, "latency", time.Since(start).String()}
+		if sw.status >= http.StatusInternalServerError {
+			h.Logger.Error(req.Context(), "request failed", keyvals...)
 			return
 		}
+		h.Logger.Info(req.Context(), "request", keyvals...)
+	})
+}
 
-		requestID := name + "_" + uuid.New().String()
+// statusWriter remembers the status of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
 
-		ctx = context.WithValue(ctx, model.ContextKeyRequestID, requestID)
-		next.ServeHTTP(w, req.WithContext(ctx))
-	})
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
 }
 
 func (h HTTPHandler) AccessControlMiddleware(next http.Handler) http.Handler {
@@ -59,6 +88,7 @@ func (h HTTPHandler) AccessControlMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET,PUT,POST,DELETE,PATCH,OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "Link, "+etagHeader+", "+replayedHeader+", "+nextPageTokenHeader)
 
 		if r.Method == "OPTIONS" {
 			return
@@ -0,0 +1,160 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// ****** //
+// Policy //
+// ****** //
+
+// policy decides whether the caller may use a route, a denied request gets customErrors.PermissionDenied
+type policy func(h HTTPHandler, req *http.Request, caller model.Identity) error
+
+// authorize runs the handler only when the policy allows the caller
+func (h HTTPHandler) authorize(p policy, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		caller, ok := req.Context().Value(model.IdentityKey).(model.Identity)
+		if !ok {
+			h.reportError(w, req, fmt.Errorf("no caller identity: %w", customErrors.PermissionDenied))
+			return
+		}
+
+		if err := p(h, req, caller); err != nil {
+			h.reportError(w, req, err)
+			return
+		}
+
+		next(w, req)
+	}
+}
+
+// authenticated allows any caller with a valid token
+func authenticated(HTTPHandler, *http.Request, model.Identity) error {
+	return nil
+}
+
+func adminOnly(_ HTTPHandler, _ *http.Request, caller model.Identity) error {
+	if caller.HasRole(model.RoleAdmin) {
+		return nil
+	}
+
+	return fmt.Errorf("admin role required: %w", customErrors.PermissionDenied)
+}
+
+// userOwner allows the user named by the {id} path variable and admins
+func userOwner(h HTTPHandler, req *http.Request, caller model.Identity) error {
+	if caller.HasRole(model.RoleAdmin) {
+		return nil
+	}
+
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		return fmt.Errorf("%s: %w", err, customErrors.UUIDError)
+	}
+
+	return checkOwner(id, caller)
+}
+
+// accountOwner allows the owner of the account named by the {id} path variable and admins
+func accountOwner(h HTTPHandler, req *http.Request, caller model.Identity) error {
+	if caller.HasRole(model.RoleAdmin) {
+		return nil
+	}
+
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		return fmt.Errorf("%s: %w", err, customErrors.UUIDError)
+	}
+
+	return h.checkAccountOwner(req, id, caller)
+}
+
+// accountUpdater lets owners change the non-financial fields of their account, the balance is refused
+// for everyone and a new overdraft limit needs the admin role
+func accountUpdater(h HTTPHandler, req *http.Request, caller model.Identity) error {
+	p, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	// the handler reads the body again
+	req.Body = ioutil.NopCloser(bytes.NewReader(p))
+
+	var update accountUpdate
+	if err = json.Unmarshal(p, &update); err != nil {
+		return decodeError(err)
+	}
+
+	var account model.Account
+	if !caller.HasRole(model.RoleAdmin) {
+		id, err := uuid.Parse(mux.Vars(req)["id"])
+		if err != nil {
+			return fmt.Errorf("%s: %w", err, customErrors.UUIDError)
+		}
+
+		if account, err = h.AccountsService.GetAccount(req.Context(), id); err != nil {
+			return err
+		}
+		if err = checkOwner(account.UserID, caller); err != nil {
+			return err
+		}
+		if update.OverdraftLimit != nil && *update.OverdraftLimit != account.OverdraftLimit {
+			return fmt.Errorf("admin role required to change the overdraft limit: %w", customErrors.PermissionDenied)
+		}
+	}
+
+	if update.Balance != nil {
+		return fmt.Errorf("the balance changes through deposits, withdrawals and transfers only: %w", customErrors.FailedPrecondition)
+	}
+
+	return nil
+}
+
+// transferOwner allows the owner of the account money is taken from and admins
+func transferOwner(h HTTPHandler, req *http.Request, caller model.Identity) error {
+	if caller.HasRole(model.RoleAdmin) {
+		return nil
+	}
+
+	p, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	// the handler reads the body again
+	req.Body = ioutil.NopCloser(bytes.NewReader(p))
+
+	var transfer model.Transfer
+	if err = json.Unmarshal(p, &transfer); err != nil {
+		return fmt.Errorf("%s: %w", err, customErrors.JSONError)
+	}
+
+	return h.checkAccountOwner(req, transfer.From, caller)
+}
+
+func (h HTTPHandler) checkAccountOwner(req *http.Request, id uuid.UUID, caller model.Identity) error {
+	account, err := h.AccountsService.GetAccount(req.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return checkOwner(account.UserID, caller)
+}
+
+// checkOwner compares the user id of the token subject with the owner
+func checkOwner(userID uuid.UUID, caller model.Identity) error {
+	if caller.UserID == uuid.Nil || caller.UserID != userID {
+		return fmt.Errorf("resource belongs to another user: %w", customErrors.PermissionDenied)
+	}
+
+	return nil
+}
@@ -1,50 +1,80 @@
 package httphandler
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 
 	tokenservice "github.com/stasBigunenko/monorepa/service/http"
 	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
+	"github.com/stasBigunenko/monorepa/service/problem"
 )
 
 type HTTPHandler struct {
-	AccountsService AccountGrpcService
-	UsersService    UserGrpcService
-	TokenService    TokenService
-	LoggingService  LoggingService
-	JwtServiceAddr  string
+	AccountsService   AccountGrpcService
+	UsersService      UserGrpcService
+	AuditService      AuditGrpcService
+	TokenService      TokenService
+	CredentialService CredentialService
+	Logger            loggingservice.Logger
+	Idempotency       IdempotencyStore
+	Metrics           *metrics.HTTPMetrics
 }
 
-func New(accountService AccountGrpcService, userService UserGrpcService, loggingService LoggingService, addr string) *HTTPHandler {
+func New(accountService AccountGrpcService, userService UserGrpcService, auditService AuditGrpcService, tokenService TokenService,
+	credentialService CredentialService, logger loggingservice.Logger, idempotencyTTL time.Duration, httpMetrics *metrics.HTTPMetrics) *HTTPHandler {
 	return &HTTPHandler{
-		AccountsService: accountService,
-		UsersService:    userService,
-		TokenService: tokenservice.HTTPService{
-			JwtServiceAddr: addr,
-		},
-		LoggingService: loggingservice.LoggingService{},
+		AccountsService:   accountService,
+		UsersService:      userService,
+		AuditService:      auditService,
+		TokenService:      tokenService,
+		CredentialService: credentialService,
+		Logger:            logger.With("component", "http"),
+		Idempotency:       tokenservice.NewIdempotencyCache(idempotencyTTL),
+		Metrics:           httpMetrics,
 	}
 }
 
 func (h HTTPHandler) GetRouter() *mux.Router {
 	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(problem.NotFound)
+	router.MethodNotAllowedHandler = http.HandlerFunc(problem.MethodNotAllowed)
 
-	router.HandleFunc("/users", h.AddUser).Methods("POST")
-	router.HandleFunc("/users/{id}", h.GetUser).Methods("GET")
-	router.HandleFunc("/users", h.ListUsers).Methods("GET")
-	router.HandleFunc("/users/{id}", h.UpdateUser).Methods("PUT")
-	router.HandleFunc("/users/{id}", h.DeleteUser).Methods("DELETE")
+	// users are registered through the auth service, the gateway creates them for admins only
+	router.HandleFunc("/users", h.authorize(adminOnly, h.AddUser)).Methods("POST")
+	router.HandleFunc("/users/{id}", h.authorize(userOwner, h.GetUser)).Methods("GET")
+	router.HandleFunc("/users", h.authorize(adminOnly, h.ListUsers)).Methods("GET")
+	router.HandleFunc("/users/{id}", h.authorize(userOwner, h.UpdateUser)).Methods("PUT")
+	router.HandleFunc("/users/{id}", h.authorize(adminOnly, h.DeleteUser)).Methods("DELETE")
 
-	router.HandleFunc("/accounts", h.AddAccount).Methods("POST")
-	router.HandleFunc("/accounts/{id}", h.GetAccount).Methods("GET")
-	router.HandleFunc("/accounts/{id}", h.UpdateAccount).Methods("PUT")
-	router.HandleFunc("/accounts/{id}", h.DeleteAccount).Methods("DELETE")
-	router.HandleFunc("/accounts", h.ListAccounts).Methods("GET")
+	router.HandleFunc("/accounts", h.authorize(authenticated, h.AddAccount)).Methods("POST")
+	router.HandleFunc("/accounts/{id}", h.authorize(accountOwner, h.GetAccount)).Methods("GET")
+	router.HandleFunc("/accounts/{id}", h.authorize(accountUpdater, h.UpdateAccount)).Methods("PUT")
+	router.HandleFunc("/accounts/{id}", h.authorize(accountOwner, h.DeleteAccount)).Methods("DELETE")
+	router.HandleFunc("/accounts", h.authorize(adminOnly, h.ListAccounts)).Methods("GET")
+	router.HandleFunc("/accounts/{id}/transactions", h.authorize(accountOwner, h.ListTransactions)).Methods("GET")
+	router.HandleFunc("/accounts/{id}/deposit", h.authorize(accountOwner, h.Deposit)).Methods("POST")
+	router.HandleFunc("/accounts/{id}/withdraw", h.authorize(accountOwner, h.Withdraw)).Methods("POST")
 
-	router.HandleFunc("/accounts_and_user/{id}", h.GetAggregate).Methods("GET")
+	router.HandleFunc("/transfers", h.authorize(transferOwner, h.Transfer)).Methods("POST")
 
-	router.Use(h.AuthMiddleware)
+	router.HandleFunc("/accounts_and_user/{id}", h.authorize(userOwner, h.GetAggregate)).Methods("GET")
+
+	router.HandleFunc("/audit", h.authorize(adminOnly, h.ListAudit)).Methods("GET")
+
+	// a server span per route, continuing the trace of the caller
+	router.Use(otelmux.Middleware("http"))
+	if h.Metrics != nil {
+		router.Use(h.Metrics.Middleware)
+	}
 	router.Use(h.RequestIDMiddleware)
+	router.Use(h.AuthMiddleware)
+	// after the request id, so the access log has it and the user
+	router.Use(h.LoggingMiddleware)
+	router.Use(h.IdempotencyMiddleware)
 
 	return router
 }
@@ -0,0 +1,41 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+func (h HTTPHandler) Transfer(w http.ResponseWriter, req *http.Request) {
+	h.Logger.Debug(req.Context(), "command received", "method", "Transfer")
+
+	p, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		h.reportError(w, req, err)
+		return
+	}
+
+	var transfer model.Transfer
+	if err = json.Unmarshal(p, &transfer); err != nil {
+		h.reportError(w, req, decodeError(err))
+		return
+	}
+
+	result, err := h.AccountsService.Transfer(req.Context(), transfer)
+	if err != nil {
+		h.reportError(w, req, err)
+		return
+	}
+
+	res, err := json.Marshal(result)
+	if err != nil {
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		return
+	}
+
+	w.Write(res) //nolint:errcheck
+}
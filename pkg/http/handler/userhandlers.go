@@ -18,102 +18,113 @@ import (
 // ***** //
 
 func (h HTTPHandler) AddUser(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command AddUSer received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "AddUSer")
 
 	name, err := ioutil.ReadAll(req.Body)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
 	var user model.UserHTTP
 	if err = json.Unmarshal(name, &user); err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, decodeError(err))
 		return
 	}
 
-	accountID, err := h.UsersService.CreateUser(req.Context(), user.Name)
+	userID, err := h.UsersService.CreateUser(req.Context(), user.Name)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
-	w.Header().Set("Location", fmt.Sprintf("/accounts/%d", accountID))
+	w.Header().Set("Location", fmt.Sprintf("/users/%s", userID))
 	w.WriteHeader(http.StatusCreated)
 
 }
 
 func (h HTTPHandler) GetUser(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command GetUser received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "GetUser")
 
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
 		return
 	}
 
 	user, err := h.UsersService.GetUser(req.Context(), id)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
 	u, err := json.Marshal(user)
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.JSONError))
 		return
 	}
 
+	setETag(w, user.Version)
 	w.Write(u) //nolint:errcheck
 
 }
 
 func (h HTTPHandler) UpdateUser(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command UpdateUser received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "UpdateUser")
 
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
 		return
 	}
 
 	p, err := ioutil.ReadAll(req.Body)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
 	user := model.UserHTTP{}
 	if err = json.Unmarshal(p, &user); err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		h.reportError(w, req, decodeError(err))
 		return
 	}
 
 	user.ID = id
+	if user.Version, err = expectedVersion(req, user.Version); err != nil {
+		h.reportError(w, req, err)
+		return
+	}
 
-	err = h.UsersService.UpdateUser(req.Context(), user)
+	updated, err := h.UsersService.UpdateUser(req.Context(), user)
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
+	setETag(w, updated.Version)
 	w.WriteHeader(http.StatusOK)
 }
 
 func (h HTTPHandler) DeleteUser(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command DeleteUser received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "DeleteUser")
 
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.UUIDError))
 		return
 	}
 
-	if err := h.UsersService.DeleteUser(req.Context(), id); err != nil {
-		h.reportError(w, err)
+	policy := req.URL.Query().Get("policy")
+	if policy == "" {
+		policy = deleteRestrict
+	}
+
+	if err := h.deleteUser(req.Context(), id, policy); err != nil {
+		h.reportError(w, req, err)
 		return
 	}
 
@@ -121,19 +132,27 @@ func (h HTTPHandler) DeleteUser(w http.ResponseWriter, req *http.Request) {
 }
 
 func (h HTTPHandler) ListUsers(w http.ResponseWriter, req *http.Request) {
-	h.LoggingService.WriteLog(req.Context(), "HTTTP: Command ListUsers received...")
+	h.Logger.Debug(req.Context(), "command received", "method", "ListUsers")
 
-	users, err := h.UsersService.GetAllUsers(req.Context())
+	filter, err := userFilter(req.URL.Query())
 	if err != nil {
-		h.reportError(w, err)
+		h.reportError(w, req, err)
 		return
 	}
 
-	res, err := json.Marshal(users)
+	page, err := h.UsersService.GetAllUsers(req.Context(), filter)
 	if err != nil {
-		h.reportError(w, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		h.reportError(w, req, err)
 		return
 	}
 
+	res, err := json.Marshal(page.Users)
+	if err != nil {
+		h.reportError(w, req, fmt.Errorf("%s: %w", err, customErrors.JSONError))
+		return
+	}
+
+	setNextPage(w, req, page.NextPageToken)
+
 	w.Write(res) //nolint:errcheck
 }
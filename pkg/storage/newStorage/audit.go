@@ -0,0 +1,161 @@
+package newStorage
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+func (sdb *StorageDB) AppendAudit(c context.Context, entry model.AuditEntry) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "AppendAudit")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "AppendAudit")
+
+	sdb.audit = append(sdb.audit, entry)
+
+	return nil
+}
+
+func (sdb *StorageDB) ListAudit(c context.Context, filter model.AuditFilter) (model.AuditPage, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "ListAudit")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "ListAudit")
+
+	offset, err := pageOffset(filter.PageToken)
+	if err != nil {
+		return model.AuditPage{}, err
+	}
+
+	var matched []model.AuditEntry
+	for _, entry := range sdb.audit {
+		if auditMatches(entry, filter) {
+			matched = append(matched, entry)
+		}
+	}
+
+	res := model.AuditPage{
+		Entries:       []model.AuditEntry{},
+		NextPageToken: nextPageToken(offset, filter.PageSize, len(matched)-offset),
+	}
+	if offset < len(matched) {
+		end := offset + filter.PageSize
+		if end > len(matched) {
+			end = len(matched)
+		}
+		res.Entries = append(res.Entries, matched[offset:end]...)
+	}
+
+	return res, nil
+}
+
+func auditMatches(entry model.AuditEntry, filter model.AuditFilter) bool {
+	switch {
+	case filter.Resource != "" && entry.Resource != filter.Resource:
+		return false
+	case filter.ResourceID != uuid.Nil && entry.ResourceID != filter.ResourceID:
+		return false
+	case filter.Actor != "" && entry.Actor != filter.Actor:
+		return false
+	case filter.Operation != "" && entry.Operation != filter.Operation:
+		return false
+	case !filter.From.IsZero() && entry.Time.Before(filter.From):
+		return false
+	case !filter.To.IsZero() && !entry.Time.Before(filter.To):
+		return false
+	}
+
+	return true
+}
+
+func (s *StorageSQL) AppendAudit(c context.Context, entry model.AuditEntry) error {
+	c, span := s.startSpan(c, "AppendAudit")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "AppendAudit")
+
+	_, err := s.db.ExecContext(c, `INSERT INTO audit_log (id, created_at, actor, request_id, operation, resource, resource_id, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		entry.ID, entry.Time.UnixNano(), entry.Actor, entry.RequestID, entry.Operation, entry.Resource, entry.ResourceID,
+		nullJSON(entry.Before), nullJSON(entry.After))
+
+	return err
+}
+
+func (s *StorageSQL) ListAudit(c context.Context, filter model.AuditFilter) (model.AuditPage, error) {
+	c, span := s.startSpan(c, "ListAudit")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "ListAudit")
+
+	offset, err := pageOffset(filter.PageToken)
+	if err != nil {
+		return model.AuditPage{}, err
+	}
+
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+	if !filter.From.IsZero() {
+		from = filter.From.UnixNano()
+	}
+	if !filter.To.IsZero() {
+		to = filter.To.UnixNano()
+	}
+
+	// empty filters match everything, one extra row tells whether there is a next page
+	rows, err := s.db.QueryContext(c, `SELECT id, created_at, actor, request_id, operation, resource, resource_id, before, after FROM audit_log
+		WHERE ($1 = '' OR resource = $1) AND (NOT $2 OR resource_id = $3) AND ($4 = '' OR actor = $4) AND ($5 = '' OR operation = $5)
+		AND created_at >= $6 AND created_at < $7
+		ORDER BY created_at, id LIMIT $8 OFFSET $9`,
+		filter.Resource, filter.ResourceID != uuid.Nil, filter.ResourceID, filter.Actor, filter.Operation, from, to, filter.PageSize+1, offset)
+	if err != nil {
+		return model.AuditPage{}, err
+	}
+	defer rows.Close()
+
+	res := model.AuditPage{
+		Entries: []model.AuditEntry{},
+	}
+	found := 0
+	for rows.Next() {
+		var (
+			entry         model.AuditEntry
+			createdAt     int64
+			before, after sql.NullString
+		)
+		err = rows.Scan(&entry.ID, &createdAt, &entry.Actor, &entry.RequestID, &entry.Operation, &entry.Resource, &entry.ResourceID, &before, &after)
+		if err != nil {
+			return model.AuditPage{}, err
+		}
+		found++
+		if found > filter.PageSize {
+			break
+		}
+		entry.Time = time.Unix(0, createdAt).UTC()
+		if before.Valid {
+			entry.Before = []byte(before.String)
+		}
+		if after.Valid {
+			entry.After = []byte(after.String)
+		}
+		res.Entries = append(res.Entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return model.AuditPage{}, err
+	}
+
+	res.NextPageToken = nextPageToken(offset, filter.PageSize, found)
+
+	return res, nil
+}
+
+func nullJSON(p []byte) sql.NullString {
+	return sql.NullString{String: string(p), Valid: len(p) > 0}
+}
@@ -0,0 +1,210 @@
+package newStorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// CredentialStore keeps password hashes of registered login names
+type CredentialStore interface {
+	GetCredential(context.Context, string) (model.Credential, error)
+	CreateCredential(context.Context, model.Credential) error
+	UpdatePassword(context.Context, string, string) error
+	UpdateRole(context.Context, string, string) error
+	// SetDisabled disables or enables every credential of the user and returns their names
+	SetDisabled(context.Context, uuid.UUID, bool) ([]string, error)
+}
+
+func (sdb *StorageDB) GetCredential(c context.Context, name string) (model.Credential, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "GetCredential")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "GetCredential")
+
+	cred, ok := sdb.credentials[name]
+	if !ok {
+		return model.Credential{}, customErrors.NotFound
+	}
+
+	return cred, nil
+}
+
+func (sdb *StorageDB) CreateCredential(c context.Context, cred model.Credential) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "CreateCredential")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "CreateCredential")
+
+	if _, ok := sdb.credentials[cred.Name]; ok {
+		return customErrors.AlreadyExists
+	}
+
+	sdb.credentials[cred.Name] = cred
+
+	return nil
+}
+
+func (sdb *StorageDB) UpdatePassword(c context.Context, name, hash string) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "UpdatePassword")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "UpdatePassword")
+
+	cred, ok := sdb.credentials[name]
+	if !ok {
+		return customErrors.NotFound
+	}
+
+	cred.PasswordHash = hash
+	sdb.credentials[name] = cred
+
+	return nil
+}
+
+func (sdb *StorageDB) UpdateRole(c context.Context, name, role string) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "UpdateRole")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "UpdateRole")
+
+	cred, ok := sdb.credentials[name]
+	if !ok {
+		return customErrors.NotFound
+	}
+
+	cred.Role = role
+	sdb.credentials[name] = cred
+
+	return nil
+}
+
+func (sdb *StorageDB) SetDisabled(c context.Context, userID uuid.UUID, disabled bool) ([]string, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "SetDisabled")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "SetDisabled")
+
+	names := []string{}
+	for name, cred := range sdb.credentials {
+		if cred.UserID != userID {
+			continue
+		}
+		cred.Disabled = disabled
+		sdb.credentials[name] = cred
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+func (s *StorageSQL) GetCredential(c context.Context, name string) (model.Credential, error) {
+	c, span := s.startSpan(c, "GetCredential")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "GetCredential")
+
+	var cred model.Credential
+	err := s.db.QueryRowContext(c, "SELECT name, user_id, password_hash, role, disabled FROM credentials WHERE name = $1", name).
+		Scan(&cred.Name, &cred.UserID, &cred.PasswordHash, &cred.Role, &cred.Disabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Credential{}, customErrors.NotFound
+	}
+	if err != nil {
+		return model.Credential{}, err
+	}
+
+	return cred, nil
+}
+
+func (s *StorageSQL) CreateCredential(c context.Context, cred model.Credential) error {
+	c, span := s.startSpan(c, "CreateCredential")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "CreateCredential")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var exists int
+	err = tx.QueryRowContext(c, "SELECT 1 FROM credentials WHERE name = $1", cred.Name).Scan(&exists)
+	if err == nil {
+		return customErrors.AlreadyExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// the primary key still rejects a name registered concurrently
+	_, err = tx.ExecContext(c, "INSERT INTO credentials (name, user_id, password_hash, role) VALUES ($1, $2, $3, $4)", cred.Name, cred.UserID, cred.PasswordHash, cred.Role)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *StorageSQL) UpdatePassword(c context.Context, name, hash string) error {
+	c, span := s.startSpan(c, "UpdatePassword")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "UpdatePassword")
+
+	result, err := s.db.ExecContext(c, "UPDATE credentials SET password_hash = $1 WHERE name = $2", hash, name)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+func (s *StorageSQL) UpdateRole(c context.Context, name, role string) error {
+	c, span := s.startSpan(c, "UpdateRole")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "UpdateRole")
+
+	result, err := s.db.ExecContext(c, "UPDATE credentials SET role = $1 WHERE name = $2", role, name)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+func (s *StorageSQL) SetDisabled(c context.Context, userID uuid.UUID, disabled bool) ([]string, error) {
+	c, span := s.startSpan(c, "SetDisabled")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "SetDisabled")
+
+	rows, err := s.db.QueryContext(c, "UPDATE credentials SET disabled = $1 WHERE user_id = $2 RETURNING name", disabled, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
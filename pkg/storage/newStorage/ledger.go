@@ -0,0 +1,65 @@
+package newStorage
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+func newEntry(accountID uuid.UUID, entryType model.EntryType, amount, balance int64) model.LedgerEntry {
+	return model.LedgerEntry{
+		ID:        uuid.New(),
+		AccountID: accountID,
+		Type:      entryType,
+		Amount:    amount,
+		Balance:   balance,
+		// stored as unix nanoseconds, so drop the monotonic reading and location
+		CreatedAt: time.Unix(0, time.Now().UnixNano()).UTC(),
+	}
+}
+
+func deltaEntryType(delta int64) model.EntryType {
+	if delta < 0 {
+		return model.EntryWithdrawal
+	}
+
+	return model.EntryDeposit
+}
+
+// pageOffset decodes a page token, which is the offset of the first entry of the page
+func pageOffset(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	offset, err := strconv.Atoi(token)
+	if err != nil || offset < 0 {
+		return 0, customErrors.InvalidArgument
+	}
+
+	return offset, nil
+}
+
+func nextPageToken(offset, pageSize, found int) string {
+	if found <= pageSize {
+		return ""
+	}
+
+	return strconv.Itoa(offset + pageSize)
+}
+
+func inRange(entry model.LedgerEntry, filter model.TransactionFilter) bool {
+	if !filter.From.IsZero() && entry.CreatedAt.Before(filter.From) {
+		return false
+	}
+
+	if !filter.To.IsZero() && !entry.CreatedAt.Before(filter.To) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,392 @@
+package newStorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// cursor is the sort key and id of the last item of a page, the next page starts right after it
+type cursor struct {
+	Sort string    `json:"s"`
+	Key  string    `json:"k"`
+	ID   uuid.UUID `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	p, _ := json.Marshal(c) //nolint:errcheck
+
+	return base64.RawURLEncoding.EncodeToString(p)
+}
+
+// decodeCursor reads a page token, a token issued for another sort order is rejected
+func decodeCursor(token, sort string) (*cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	p, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, customErrors.InvalidArgument
+	}
+
+	var c cursor
+	if err = json.Unmarshal(p, &c); err != nil || c.Sort != sort {
+		return nil, customErrors.InvalidArgument
+	}
+
+	return &c, nil
+}
+
+// after tells whether an item with the key comparison result cmp and the id lies after the cursor
+func (c *cursor) after(cmp int, id uuid.UUID, desc bool) bool {
+	if cmp == 0 {
+		cmp = bytes.Compare(id[:], c.ID[:])
+	}
+	if desc {
+		cmp = -cmp
+	}
+
+	return cmp > 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func (sdb *StorageDB) ListUsers(c context.Context, filter model.UserFilter) (model.UserPage, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "ListUsers")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "ListUsers")
+
+	if filter.PageSize <= 0 {
+		return model.UserPage{}, customErrors.InvalidArgument
+	}
+
+	field, desc := model.ParseSort(filter.Sort)
+	cur, err := decodeCursor(filter.PageToken, filter.Sort)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+
+	// compare orders users by the sort key only, ties are broken by id
+	compare := func(a model.UserHTTP, name string) int {
+		if field == model.SortByName {
+			return strings.Compare(a.Name, name)
+		}
+		return 0
+	}
+
+	users := []model.UserHTTP{}
+	for _, user := range sdb.users {
+		if !strings.HasPrefix(user.Name, filter.NamePrefix) {
+			continue
+		}
+		if cur != nil && !cur.after(compare(user, cur.Key), user.ID, desc) {
+			continue
+		}
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		cmp := compare(users[i], users[j].Name)
+		if cmp == 0 {
+			cmp = bytes.Compare(users[i].ID[:], users[j].ID[:])
+		}
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	res := model.UserPage{Users: users}
+	if len(users) > filter.PageSize {
+		res.Users = users[:filter.PageSize]
+		last := res.Users[filter.PageSize-1]
+		res.NextPageToken = encodeCursor(cursor{Sort: filter.Sort, Key: last.Name, ID: last.ID})
+	}
+
+	return res, nil
+}
+
+func (sdb *StorageDB) ListAccounts(c context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "ListAccounts")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "ListAccounts")
+
+	if filter.PageSize <= 0 {
+		return model.AccountPage{}, customErrors.InvalidArgument
+	}
+
+	field, desc := model.ParseSort(filter.Sort)
+	cur, err := decodeCursor(filter.PageToken, filter.Sort)
+	if err != nil {
+		return model.AccountPage{}, err
+	}
+
+	var curBalance int64
+	if cur != nil && field == model.SortByBalance {
+		if curBalance, err = strconv.ParseInt(cur.Key, 10, 64); err != nil {
+			return model.AccountPage{}, customErrors.InvalidArgument
+		}
+	}
+
+	compare := func(a model.Account, balance int64) int {
+		if field == model.SortByBalance {
+			return compareInts(a.Balance, balance)
+		}
+		return 0
+	}
+
+	accounts := []model.Account{}
+	for _, acc := range sdb.filteredAccounts(filter.UserID) {
+		if !accountMatches(acc, filter) {
+			continue
+		}
+		if cur != nil && !cur.after(compare(acc, curBalance), acc.ID, desc) {
+			continue
+		}
+		accounts = append(accounts, acc)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		cmp := compare(accounts[i], accounts[j].Balance)
+		if cmp == 0 {
+			cmp = bytes.Compare(accounts[i].ID[:], accounts[j].ID[:])
+		}
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	res := model.AccountPage{Accounts: accounts}
+	if len(accounts) > filter.PageSize {
+		res.Accounts = accounts[:filter.PageSize]
+		last := res.Accounts[filter.PageSize-1]
+		res.NextPageToken = encodeCursor(cursor{Sort: filter.Sort, Key: strconv.FormatInt(last.Balance, 10), ID: last.ID})
+	}
+
+	return res, nil
+}
+
+// filteredAccounts goes through the index when the accounts of one user are wanted, the caller holds sdb.mu
+func (sdb *StorageDB) filteredAccounts(userID uuid.UUID) map[uuid.UUID]model.Account {
+	if userID == uuid.Nil {
+		return sdb.accounts
+	}
+
+	res := make(map[uuid.UUID]model.Account, len(sdb.userAccounts[userID]))
+	for id := range sdb.userAccounts[userID] {
+		res[id] = sdb.accounts[id]
+	}
+
+	return res
+}
+
+func accountMatches(acc model.Account, filter model.AccountFilter) bool {
+	if filter.UserID != uuid.Nil && acc.UserID != filter.UserID {
+		return false
+	}
+	if filter.MinBalance != nil && acc.Balance < *filter.MinBalance {
+		return false
+	}
+	if filter.MaxBalance != nil && acc.Balance > *filter.MaxBalance {
+		return false
+	}
+
+	return true
+}
+
+// where collects conditions and their $N arguments
+type where struct {
+	conds []string
+	args  []interface{}
+}
+
+func (w *where) arg(v interface{}) string {
+	w.args = append(w.args, v)
+
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+func (w *where) add(cond string) {
+	w.conds = append(w.conds, cond)
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// keyset adds the condition of rows after the cursor and returns the ORDER BY clause,
+// column is the sort key or empty when sorting by id only
+func (w *where) keyset(column string, key interface{}, cur *cursor, desc bool) string {
+	op, dir := ">", "ASC"
+	if desc {
+		op, dir = "<", "DESC"
+	}
+
+	if column == "" {
+		if cur != nil {
+			w.add(fmt.Sprintf("id %s %s", op, w.arg(cur.ID)))
+		}
+		return " ORDER BY id " + dir
+	}
+
+	if cur != nil {
+		k := w.arg(key)
+		w.add(fmt.Sprintf("(%s %s %s OR (%s = %s AND id %s %s))", column, op, k, column, k, op, w.arg(cur.ID)))
+	}
+
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", column, dir, dir)
+}
+
+func (s *StorageSQL) ListUsers(c context.Context, filter model.UserFilter) (model.UserPage, error) {
+	c, span := s.startSpan(c, "ListUsers")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "ListUsers")
+
+	if filter.PageSize <= 0 {
+		return model.UserPage{}, customErrors.InvalidArgument
+	}
+
+	field, desc := model.ParseSort(filter.Sort)
+	cur, err := decodeCursor(filter.PageToken, filter.Sort)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+
+	w := where{conds: []string{"deleted_at IS NULL"}}
+	if filter.NamePrefix != "" {
+		// LIKE ignores case in sqlite, the prefix is compared as is everywhere
+		w.add(fmt.Sprintf("substr(name, 1, %s) = %s", w.arg(utf8.RuneCountInString(filter.NamePrefix)), w.arg(filter.NamePrefix)))
+	}
+
+	column := ""
+	var key interface{}
+	if field == model.SortByName {
+		column = "name"
+		if cur != nil {
+			key = cur.Key
+		}
+	}
+	order := w.keyset(column, key, cur, desc)
+
+	// one extra row tells whether there is a next page
+	rows, err := s.db.QueryContext(c, "SELECT id, name, version FROM users"+w.String()+order+" LIMIT "+w.arg(filter.PageSize+1), w.args...)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+	defer rows.Close()
+
+	res := model.UserPage{Users: []model.UserHTTP{}}
+	for rows.Next() {
+		var user model.UserHTTP
+		if err = rows.Scan(&user.ID, &user.Name, &user.Version); err != nil {
+			return model.UserPage{}, err
+		}
+		res.Users = append(res.Users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return model.UserPage{}, err
+	}
+
+	if len(res.Users) > filter.PageSize {
+		res.Users = res.Users[:filter.PageSize]
+		last := res.Users[filter.PageSize-1]
+		res.NextPageToken = encodeCursor(cursor{Sort: filter.Sort, Key: last.Name, ID: last.ID})
+	}
+
+	return res, nil
+}
+
+func (s *StorageSQL) ListAccounts(c context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+	c, span := s.startSpan(c, "ListAccounts")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "ListAccounts")
+
+	if filter.PageSize <= 0 {
+		return model.AccountPage{}, customErrors.InvalidArgument
+	}
+
+	field, desc := model.ParseSort(filter.Sort)
+	cur, err := decodeCursor(filter.PageToken, filter.Sort)
+	if err != nil {
+		return model.AccountPage{}, err
+	}
+
+	var w where
+	if filter.UserID != uuid.Nil {
+		w.add("user_id = " + w.arg(filter.UserID))
+	}
+	if filter.MinBalance != nil {
+		w.add("balance >= " + w.arg(*filter.MinBalance))
+	}
+	if filter.MaxBalance != nil {
+		w.add("balance <= " + w.arg(*filter.MaxBalance))
+	}
+
+	column := ""
+	var key interface{}
+	if field == model.SortByBalance {
+		column = "balance"
+		if cur != nil {
+			balance, err := strconv.ParseInt(cur.Key, 10, 64)
+			if err != nil {
+				return model.AccountPage{}, customErrors.InvalidArgument
+			}
+			key = balance
+		}
+	}
+	order := w.keyset(column, key, cur, desc)
+
+	rows, err := s.db.QueryContext(c, "SELECT id, user_id, currency, balance, overdraft_limit, version FROM accounts"+w.String()+order+" LIMIT "+w.arg(filter.PageSize+1), w.args...)
+	if err != nil {
+		return model.AccountPage{}, err
+	}
+
+	accounts, err := scanAccounts(rows)
+	if err != nil {
+		return model.AccountPage{}, err
+	}
+
+	res := model.AccountPage{Accounts: accounts}
+	if res.Accounts == nil {
+		res.Accounts = []model.Account{}
+	}
+	if len(res.Accounts) > filter.PageSize {
+		res.Accounts = res.Accounts[:filter.PageSize]
+		last := res.Accounts[filter.PageSize-1]
+		res.NextPageToken = encodeCursor(cursor{Sort: filter.Sort, Key: strconv.FormatInt(last.Balance, 10), ID: last.ID})
+	}
+
+	return res, nil
+}
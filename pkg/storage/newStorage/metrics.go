@@ -0,0 +1,37 @@
+package newStorage
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var entriesDesc = prometheus.NewDesc("storage_memory_entries", "Entries kept by the in-memory storage by map.", []string{"map"}, nil)
+
+// Describe and Collect make StorageDB a prometheus.Collector of its operation counts and the size of its maps
+func (sdb *StorageDB) Describe(ch chan<- *prometheus.Desc) {
+	sdb.operations.Describe(ch)
+	ch <- entriesDesc
+}
+
+func (sdb *StorageDB) Collect(ch chan<- prometheus.Metric) {
+	sdb.operations.Collect(ch)
+
+	sdb.mu.Lock()
+	ledger := 0
+	for _, entries := range sdb.ledger {
+		ledger += len(entries)
+	}
+	sizes := map[string]int{
+		"users":          len(sdb.users),
+		"deleted_users":  len(sdb.deletedUsers),
+		"accounts":       len(sdb.accounts),
+		"transfers":      len(sdb.transfers),
+		"ledger":         ledger,
+		"credentials":    len(sdb.credentials),
+		"refresh_tokens": len(sdb.refreshTokens),
+		"revoked":        len(sdb.revoked),
+		"audit":          len(sdb.audit),
+	}
+	sdb.mu.Unlock()
+
+	for name, size := range sizes {
+		ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(size), name)
+	}
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS users (
+    id   UUID PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS accounts (
+    id      UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users (id),
+    balance INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS accounts_user_id_idx ON accounts (user_id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS transfers (
+    id              UUID PRIMARY KEY,
+    idempotency_key TEXT UNIQUE,
+    from_id         UUID NOT NULL,
+    to_id           UUID NOT NULL,
+    amount          INTEGER NOT NULL,
+    from_balance    INTEGER NOT NULL,
+    to_balance      INTEGER NOT NULL
+);
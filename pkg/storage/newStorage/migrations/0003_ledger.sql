@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS ledger (
+    id         UUID PRIMARY KEY,
+    account_id UUID NOT NULL,
+    type       TEXT NOT NULL,
+    amount     INTEGER NOT NULL,
+    balance    INTEGER NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ledger_account_id_created_at_idx ON ledger (account_id, created_at);
+
+-- opening entries keep balances of existing accounts derivable from the ledger
+INSERT INTO ledger (id, account_id, type, amount, balance, created_at)
+SELECT id, id, 'adjustment', balance, balance, 0 FROM accounts WHERE balance <> 0;
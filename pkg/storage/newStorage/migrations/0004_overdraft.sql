@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN overdraft_limit INTEGER NOT NULL DEFAULT 0;
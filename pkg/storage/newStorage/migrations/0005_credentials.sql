@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS credentials (
+    name          TEXT PRIMARY KEY,
+    user_id       UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    password_hash TEXT NOT NULL
+);
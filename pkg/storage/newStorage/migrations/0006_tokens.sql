@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    hash              TEXT PRIMARY KEY,
+    family            UUID NOT NULL,
+    name              TEXT NOT NULL,
+    state             TEXT NOT NULL,
+    expires_at        BIGINT NOT NULL,
+    access_jti        TEXT NOT NULL,
+    access_expires_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens (family);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        TEXT PRIMARY KEY,
+    expires_at BIGINT NOT NULL
+);
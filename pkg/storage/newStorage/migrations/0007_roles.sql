@@ -0,0 +1 @@
+ALTER TABLE credentials ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
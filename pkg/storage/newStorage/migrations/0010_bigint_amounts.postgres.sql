@@ -0,0 +1,4 @@
+-- sqlite integers are 64 bit already
+ALTER TABLE accounts ALTER COLUMN balance TYPE BIGINT, ALTER COLUMN overdraft_limit TYPE BIGINT;
+ALTER TABLE transfers ALTER COLUMN amount TYPE BIGINT, ALTER COLUMN from_balance TYPE BIGINT, ALTER COLUMN to_balance TYPE BIGINT;
+ALTER TABLE ledger ALTER COLUMN amount TYPE BIGINT, ALTER COLUMN balance TYPE BIGINT;
@@ -0,0 +1,4 @@
+ALTER TABLE transfers ADD COLUMN credit BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE transfers ADD COLUMN rate TEXT NOT NULL DEFAULT '1';
+
+UPDATE transfers SET credit = amount;
@@ -0,0 +1,5 @@
+ALTER TABLE accounts ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE transfers ADD COLUMN from_version BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE transfers ADD COLUMN to_version BIGINT NOT NULL DEFAULT 0;
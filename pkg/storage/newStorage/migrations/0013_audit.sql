@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          UUID PRIMARY KEY,
+    created_at  BIGINT NOT NULL,
+    actor       TEXT NOT NULL,
+    request_id  TEXT NOT NULL,
+    operation   TEXT NOT NULL,
+    resource    TEXT NOT NULL,
+    resource_id UUID NOT NULL,
+    before      TEXT,
+    after       TEXT
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_resource_created_at_idx ON audit_log (resource, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_resource_id_idx ON audit_log (resource_id);
@@ -0,0 +1,3 @@
+ALTER TABLE credentials ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_name_idx ON refresh_tokens (name);
@@ -0,0 +1,5 @@
+-- accounts opened while ONE_ACCOUNT_PER_CURRENCY is set hold the currency of their owner exclusively,
+-- the index settles concurrent opens the check before the insert can not see
+ALTER TABLE accounts ADD COLUMN exclusive_currency BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS accounts_user_id_currency_exclusive_idx ON accounts (user_id, currency) WHERE exclusive_currency;
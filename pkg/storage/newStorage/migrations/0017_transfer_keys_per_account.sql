@@ -0,0 +1,23 @@
+-- idempotency keys belong to the account money leaves, two owners may pick the same key.
+-- The table is rebuilt since sqlite can not drop the unique constraint of a column.
+CREATE TABLE transfers_per_account (
+    id              UUID PRIMARY KEY,
+    idempotency_key TEXT,
+    from_id         UUID NOT NULL,
+    to_id           UUID NOT NULL,
+    amount          BIGINT NOT NULL,
+    from_balance    BIGINT NOT NULL,
+    to_balance      BIGINT NOT NULL,
+    credit          BIGINT NOT NULL DEFAULT 0,
+    rate            TEXT NOT NULL DEFAULT '1',
+    from_version    BIGINT NOT NULL DEFAULT 0,
+    to_version      BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO transfers_per_account (id, idempotency_key, from_id, to_id, amount, from_balance, to_balance, credit, rate, from_version, to_version)
+SELECT id, idempotency_key, from_id, to_id, amount, from_balance, to_balance, credit, rate, from_version, to_version FROM transfers;
+
+DROP TABLE transfers;
+ALTER TABLE transfers_per_account RENAME TO transfers;
+
+CREATE UNIQUE INDEX IF NOT EXISTS transfers_from_id_idempotency_key_idx ON transfers (from_id, idempotency_key);
@@ -0,0 +1,55 @@
+package newStorage
+
+import loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+
+const (
+	MemoryStorage   = "memory"
+	PostgresStorage = "postgres"
+)
+
+// AuthStore is everything the auth service keeps
+type AuthStore interface {
+	CredentialStore
+	TokenStore
+}
+
+// store is implemented by both StorageDB and StorageSQL
+type store interface {
+	UserRepository
+	AccountRepository
+	AuthStore
+	AuditLog
+}
+
+// open returns the store selected by storageType, in-memory storage is used by default
+func open(storageType, dsn string, logger loggingservice.Logger) (store, error) {
+	if storageType == PostgresStorage {
+		db, err := OpenSQL(PostgresStorage, dsn, logger)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+
+	return NewDB(logger), nil
+}
+
+// NewUserRepository returns the user repository selected by storageType
+func NewUserRepository(storageType, dsn string, logger loggingservice.Logger) (UserRepository, error) {
+	return open(storageType, dsn, logger)
+}
+
+// NewAccountRepository returns the account repository selected by storageType
+func NewAccountRepository(storageType, dsn string, logger loggingservice.Logger) (AccountRepository, error) {
+	return open(storageType, dsn, logger)
+}
+
+// NewAuthStore returns the credential and token store selected by storageType
+func NewAuthStore(storageType, dsn string, logger loggingservice.Logger) (AuthStore, error) {
+	return open(storageType, dsn, logger)
+}
+
+// NewAuditLog returns the audit trail selected by storageType. The in-memory trail is lost on restart.
+func NewAuditLog(storageType, dsn string, logger loggingservice.Logger) (AuditLog, error) {
+	return open(storageType, dsn, logger)
+}
@@ -2,166 +2,419 @@ package newStorage
 
 import (
 	"context"
-	"errors"
 	"github.com/stasBigunenko/monorepa/customErrors"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/stasBigunenko/monorepa/model"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 )
 
-type LoggingService interface {
-	WriteLog(ctx context.Context, message string)
+type StorageDB struct {
+	users           map[uuid.UUID]model.UserHTTP
+	deletedUsers    map[uuid.UUID]model.UserHTTP
+	accounts        map[uuid.UUID]model.Account
+	userAccounts    map[uuid.UUID]map[uuid.UUID]struct{}
+	exclusive       map[uuid.UUID]struct{}
+	transfers       map[transferKey]transferRecord
+	transfersPurged time.Time
+	ledger          map[uuid.UUID][]model.LedgerEntry
+	credentials     map[string]model.Credential
+	refreshTokens   map[string]refreshRecord
+	revoked         map[string]time.Time
+	audit           []model.AuditEntry
+	mu              sync.Mutex
+	logger          loggingservice.Logger
+	operations      *prometheus.CounterVec
 }
 
-type StorageDB struct {
-	Data           map[uuid.UUID]interface{}
-	mu             sync.Mutex
-	loggingService LoggingService
+// transfers are replayed by their idempotency key for this long
+const transferKeyTTL = 24 * time.Hour
+
+// transferKey scopes an idempotency key to the account money leaves, two owners may pick the same key
+type transferKey struct {
+	from uuid.UUID
+	key  string
 }
 
-func NewDB(loggingService LoggingService) *StorageDB {
+// transferRecord remembers a completed transfer by its idempotency key
+type transferRecord struct {
+	transfer  model.Transfer
+	result    model.TransferResult
+	expiresAt time.Time
+}
+
+func NewDB(logger loggingservice.Logger) *StorageDB {
 	sdb := StorageDB{}
-	sdb.Data = make(map[uuid.UUID]interface{})
-	sdb.loggingService = loggingService
+	sdb.users = make(map[uuid.UUID]model.UserHTTP)
+	sdb.deletedUsers = make(map[uuid.UUID]model.UserHTTP)
+	sdb.accounts = make(map[uuid.UUID]model.Account)
+	sdb.userAccounts = make(map[uuid.UUID]map[uuid.UUID]struct{})
+	sdb.exclusive = make(map[uuid.UUID]struct{})
+	sdb.transfers = make(map[transferKey]transferRecord)
+	sdb.transfersPurged = time.Now()
+	sdb.ledger = make(map[uuid.UUID][]model.LedgerEntry)
+	sdb.credentials = make(map[string]model.Credential)
+	sdb.refreshTokens = make(map[string]refreshRecord)
+	sdb.revoked = make(map[string]time.Time)
+	sdb.logger = logger.With("component", "storage")
+	sdb.operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "storage_operations_total",
+		Help: "Operations of the in-memory storage by method.",
+	}, []string{"method"})
 	return &sdb
 }
 
-func (sdb *StorageDB) Get(c context.Context, id uuid.UUID) (interface{}, error) {
+func (sdb *StorageDB) GetAccount(c context.Context, id uuid.UUID) (model.Account, error) {
 	sdb.mu.Lock()
 	defer sdb.mu.Unlock()
 
-	sdb.loggingService.WriteLog(c, "Storage: Command Get received...")
+	c, span := sdb.startSpan(c, "GetAccount")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "GetAccount")
 
-	val, ok := sdb.Data[id]
+	acc, ok := sdb.accounts[id]
 	if !ok {
-		return nil, customErrors.NotFound
+		return model.Account{}, customErrors.NotFound
 	}
 
-	return val, nil
+	return acc, nil
 }
 
-func (sdb *StorageDB) GetUserAccounts(c context.Context, userID uuid.UUID) (interface{}, error) {
+func (sdb *StorageDB) GetUserAccounts(c context.Context, userID uuid.UUID) ([]model.Account, error) {
 	sdb.mu.Lock()
 	defer sdb.mu.Unlock()
 
-	sdb.loggingService.WriteLog(c, "Storage: Command GetUserAccounts received...")
+	c, span := sdb.startSpan(c, "GetUserAccounts")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "GetUserAccounts")
 
 	var res []model.Account
+	for id := range sdb.userAccounts[userID] {
+		res = append(res, sdb.accounts[id])
+	}
+
+	return res, nil
+}
+
+func (sdb *StorageDB) CreateAccount(c context.Context, acc model.Account) (model.Account, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "CreateAccount")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "CreateAccount")
+
+	acc.ID = uuid.New()
+	acc.Version = 1
+	sdb.putAccount(acc)
 
-	for _, account := range sdb.Data {
-		acc, ok := account.(model.Account)
-		if !ok {
-			return nil, errors.New("invalid userID")
+	return acc, nil
+}
+
+func (sdb *StorageDB) CreateUniqueAccount(c context.Context, acc model.Account) (model.Account, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "CreateUniqueAccount")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "CreateUniqueAccount")
+
+	for id := range sdb.userAccounts[acc.UserID] {
+		if sdb.accounts[id].Currency == acc.Currency {
+			return model.Account{}, customErrors.CurrencyTaken
 		}
-		if userID == acc.UserID {
-			res = append(res, acc)
+	}
+
+	acc.ID = uuid.New()
+	acc.Version = 1
+	sdb.putAccount(acc)
+	sdb.exclusive[acc.ID] = struct{}{}
+
+	return acc, nil
+}
+
+func (sdb *StorageDB) UpdateAccount(c context.Context, acc model.Account) (model.Account, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "UpdateAccount")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "UpdateAccount")
+
+	stored, ok := sdb.accounts[acc.ID]
+	if !ok {
+		return model.Account{}, customErrors.NotFound
+	}
+
+	if acc.Version != 0 && acc.Version != stored.Version {
+		return model.Account{}, customErrors.VersionMismatch
+	}
+	if acc.UserID == uuid.Nil {
+		acc.UserID = stored.UserID
+	}
+	// the new owner holds the currency exclusively
+	if acc.UserID != stored.UserID && sdb.holdsExclusively(acc.UserID, stored) {
+		return model.Account{}, customErrors.CurrencyTaken
+	}
+	// the balance only moves through deposits, withdrawals and transfers, each with its ledger entry
+	acc.Balance = stored.Balance
+	acc.Currency = stored.Currency
+	acc.Version = stored.Version + 1
+	sdb.putAccount(acc)
+
+	return acc, nil
+}
+
+func (sdb *StorageDB) DeleteAccount(c context.Context, id uuid.UUID) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "DeleteAccount")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "DeleteAccount")
+
+	acc, ok := sdb.accounts[id]
+	if !ok {
+		return customErrors.NotFound
+	}
+
+	delete(sdb.accounts, id)
+	delete(sdb.exclusive, id)
+	sdb.unindexAccount(acc)
+
+	return nil
+}
+
+// CloseUserAccounts removes all accounts of the user at once, unless force is set
+// it refuses while any of them holds money or debt. A closing entry zeroes the balance
+// of every account that still has one, so balances stay derivable from the ledger.
+func (sdb *StorageDB) CloseUserAccounts(c context.Context, userID uuid.UUID, force bool) ([]model.Account, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "CloseUserAccounts")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "CloseUserAccounts")
+
+	closed := []model.Account{}
+	for id := range sdb.userAccounts[userID] {
+		acc := sdb.accounts[id]
+		if !force && acc.Balance != 0 {
+			return nil, customErrors.AccountsNotEmpty
 		}
+		closed = append(closed, acc)
 	}
-	return res, nil
+
+	for _, acc := range closed {
+		if acc.Balance != 0 {
+			sdb.appendEntry(acc.ID, model.EntryClosing, -acc.Balance, 0)
+		}
+		delete(sdb.accounts, acc.ID)
+		delete(sdb.exclusive, acc.ID)
+	}
+	delete(sdb.userAccounts, userID)
+
+	return closed, nil
 }
 
-func (sdb *StorageDB) GetAll(c context.Context) (interface{}, error) {
+// RestoreAccounts puts back accounts removed by CloseUserAccounts, reopening entries undo the closing ones
+func (sdb *StorageDB) RestoreAccounts(c context.Context, accounts []model.Account) error {
 	sdb.mu.Lock()
 	defer sdb.mu.Unlock()
 
-	sdb.loggingService.WriteLog(c, "Storage: Command GetAll received...")
+	c, span := sdb.startSpan(c, "RestoreAccounts")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "RestoreAccounts")
 
-	var users []model.UserHTTP
-	var accounts []model.Account
+	for _, acc := range accounts {
+		if _, ok := sdb.accounts[acc.ID]; ok {
+			return customErrors.AlreadyExists
+		}
+	}
 
-	for _, val := range sdb.Data {
-		res, ok := val.(model.UserHTTP)
-		if ok {
-			users = append(users, res)
-			continue
+	for _, acc := range accounts {
+		if acc.Balance != 0 {
+			sdb.appendEntry(acc.ID, model.EntryReopening, acc.Balance, acc.Balance)
 		}
+		sdb.putAccount(acc)
+	}
+
+	return nil
+}
+
+// holdsExclusively tells whether both acc and another account of the user in its currency
+// were opened by CreateUniqueAccount, the caller holds sdb.mu
+func (sdb *StorageDB) holdsExclusively(userID uuid.UUID, acc model.Account) bool {
+	if _, ok := sdb.exclusive[acc.ID]; !ok {
+		return false
+	}
 
-		res2, ok := val.(model.Account)
-		if ok {
-			accounts = append(accounts, res2)
+	for id := range sdb.userAccounts[userID] {
+		if _, ok := sdb.exclusive[id]; ok && id != acc.ID && sdb.accounts[id].Currency == acc.Currency {
+			return true
 		}
 	}
 
-	if users != nil { //nolint
-		return users, nil
-	} else if accounts != nil {
-		return accounts, nil
-	} else {
-		return nil, nil
+	return false
+}
+
+// putAccount stores the account and keeps the index by owner in step, the caller holds sdb.mu
+func (sdb *StorageDB) putAccount(acc model.Account) {
+	if prev, ok := sdb.accounts[acc.ID]; ok && prev.UserID != acc.UserID {
+		sdb.unindexAccount(prev)
+	}
+
+	sdb.accounts[acc.ID] = acc
+
+	ids, ok := sdb.userAccounts[acc.UserID]
+	if !ok {
+		ids = make(map[uuid.UUID]struct{})
+		sdb.userAccounts[acc.UserID] = ids
 	}
+	ids[acc.ID] = struct{}{}
 }
 
-func (sdb *StorageDB) Create(c context.Context, i interface{}) (interface{}, error) {
+func (sdb *StorageDB) unindexAccount(acc model.Account) {
+	ids := sdb.userAccounts[acc.UserID]
+	delete(ids, acc.ID)
+	if len(ids) == 0 {
+		delete(sdb.userAccounts, acc.UserID)
+	}
+}
+
+func (sdb *StorageDB) Transfer(c context.Context, t model.Transfer) (model.TransferResult, error) {
 	sdb.mu.Lock()
 	defer sdb.mu.Unlock()
 
-	sdb.loggingService.WriteLog(c, "Storage: Command Create received...")
+	c, span := sdb.startSpan(c, "Transfer")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "Transfer")
+
+	now := time.Now()
+	sdb.purgeTransfers(now)
+
+	key := transferKey{from: t.From, key: t.IdempotencyKey}
+	if t.IdempotencyKey != "" {
+		if rec, ok := sdb.transfers[key]; ok && now.Before(rec.expiresAt) {
+			if !rec.transfer.SameRequest(t) {
+				return model.TransferResult{}, customErrors.IdempotencyKeyReused
+			}
+			res := rec.result
+			res.Replayed = true
+			return res, nil
+		}
+	}
+
+	from, ok := sdb.accounts[t.From]
+	if !ok {
+		return model.TransferResult{}, customErrors.NotFound
+	}
 
-	id := uuid.New()
+	to, ok := sdb.accounts[t.To]
+	if !ok {
+		return model.TransferResult{}, customErrors.NotFound
+	}
 
-	res, ok := i.(model.UserHTTP)
-	if ok {
-		res.ID = id
-		sdb.Data[id] = res
-		return res, nil
+	if from.Balance-t.Amount < -from.OverdraftLimit {
+		return model.TransferResult{}, customErrors.InsufficientFunds
 	}
 
-	res2, ok := i.(model.Account)
-	if ok {
+	from.Balance -= t.Amount
+	to.Balance += t.Credit
+	from.Version++
+	to.Version++
+	sdb.putAccount(from)
+	sdb.putAccount(to)
+	sdb.appendEntry(from.ID, model.EntryTransferOut, -t.Amount, from.Balance)
+	sdb.appendEntry(to.ID, model.EntryTransferIn, t.Credit, to.Balance)
+
+	res := model.TransferResult{From: from, To: to, Credit: t.Credit, Rate: t.Rate}
+	if t.IdempotencyKey != "" {
+		sdb.transfers[key] = transferRecord{transfer: t, result: res, expiresAt: now.Add(transferKeyTTL)}
+	}
 
-		res2.ID = id
-		sdb.Data[id] = res2
-		return res2, nil
+	return res, nil
+}
+
+// purgeTransfers drops expired idempotency keys, at most once per TTL
+func (sdb *StorageDB) purgeTransfers(now time.Time) {
+	if now.Sub(sdb.transfersPurged) < transferKeyTTL {
+		return
 	}
+	sdb.transfersPurged = now
 
-	return nil, errors.New("invalid data")
+	for key, rec := range sdb.transfers {
+		if !now.Before(rec.expiresAt) {
+			delete(sdb.transfers, key)
+		}
+	}
 }
 
-func (sdb *StorageDB) Update(c context.Context, i interface{}) (interface{}, error) {
+func (sdb *StorageDB) ApplyDelta(c context.Context, id uuid.UUID, delta int64) (model.Account, error) {
 	sdb.mu.Lock()
 	defer sdb.mu.Unlock()
 
-	sdb.loggingService.WriteLog(c, "Storage: Command Update received...")
+	c, span := sdb.startSpan(c, "ApplyDelta")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "ApplyDelta")
 
-	res, ok := i.(model.UserHTTP)
-	if ok {
-		if _, ok := sdb.Data[res.ID]; !ok { //nolint
-			return nil, customErrors.NotFound
-		}
-		sdb.Data[res.ID] = res
-		return res, nil
+	acc, ok := sdb.accounts[id]
+	if !ok {
+		return model.Account{}, customErrors.NotFound
 	}
 
-	res2, ok := i.(model.Account)
-	if ok {
-		if _, ok := sdb.Data[res2.ID]; !ok {
-			return nil, errors.New("not found in DB")
-		}
-		var val model.Account
-		if res2.UserID == uuid.Nil {
-			val, _ = sdb.Data[res2.ID].(model.Account)
-			res2.UserID = val.UserID
-		}
-		sdb.Data[res2.ID] = res2
-		return res2, nil
+	if delta < 0 && acc.Balance+delta < -acc.OverdraftLimit {
+		return model.Account{}, customErrors.InsufficientFunds
 	}
 
-	return nil, errors.New("not found")
+	acc.Balance += delta
+	acc.Version++
+	sdb.putAccount(acc)
+	sdb.appendEntry(id, deltaEntryType(delta), delta, acc.Balance)
+
+	return acc, nil
 }
 
-func (sdb *StorageDB) Delete(c context.Context, id uuid.UUID) error {
+func (sdb *StorageDB) ListTransactions(c context.Context, filter model.TransactionFilter) (model.TransactionPage, error) {
 	sdb.mu.Lock()
 	defer sdb.mu.Unlock()
 
-	sdb.loggingService.WriteLog(c, "Storage: Command Delete received...")
+	c, span := sdb.startSpan(c, "ListTransactions")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "ListTransactions")
 
-	if _, ok := sdb.Data[id]; !ok {
-		return customErrors.NotFound
+	offset, err := pageOffset(filter.PageToken)
+	if err != nil {
+		return model.TransactionPage{}, err
 	}
 
-	delete(sdb.Data, id)
+	var matched []model.LedgerEntry
+	for _, entry := range sdb.ledger[filter.AccountID] {
+		if inRange(entry, filter) {
+			matched = append(matched, entry)
+		}
+	}
 
-	return nil
+	res := model.TransactionPage{
+		Transactions:  []model.LedgerEntry{},
+		NextPageToken: nextPageToken(offset, filter.PageSize, len(matched)-offset),
+	}
+	if offset < len(matched) {
+		end := offset + filter.PageSize
+		if end > len(matched) {
+			end = len(matched)
+		}
+		res.Transactions = append(res.Transactions, matched[offset:end]...)
+	}
+
+	return res, nil
+}
+
+// appendEntry records a balance change, the caller holds sdb.mu
+func (sdb *StorageDB) appendEntry(accountID uuid.UUID, entryType model.EntryType, amount, balance int64) {
+	sdb.ledger[accountID] = append(sdb.ledger[accountID], newEntry(accountID, entryType, amount, balance))
 }
@@ -2,155 +2,1019 @@ package newStorage
 
 import (
 	"context"
-	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
 
 	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/model"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
 	"github.com/stretchr/testify/assert"
 )
 
-type MockLoggingService struct {
+// testStore is one store implementation with a way to put an account or user into it directly
+type testStore struct {
+	name       string
+	store      store
+	addAccount func(t *testing.T, acc model.Account)
+	addUser    func(t *testing.T, id uuid.UUID)
 }
 
-func (s MockLoggingService) WriteLog(ctx context.Context, message string) {}
+// newTestStores returns the in-memory store, a sqlite stand-in for postgres
+// and, when TEST_POSTGRES_DSN is set, a real postgres store
+func newTestStores(t *testing.T) []testStore {
+	loggingService := loggingservice.LoggingService{}
 
-func TestStorageDB_Get(t *testing.T) {
-	loggingService := MockLoggingService{}
-	acc := NewDB(loggingService)
-	id := uuid.New()
-	userID := uuid.New()
-	m := model.Account{ID: id, UserID: userID, Balance: 0}
-	acc.Data[id] = m
-
-	tests := []struct {
-		name    string
-		param   uuid.UUID
-		want    model.Account
-		wantErr string
-	}{
+	mem := NewDB(loggingService)
+	stores := []testStore{
 		{
-			name:  "Everything ok",
-			param: id,
-			want:  m,
+			name:  "memory",
+			store: mem,
+			addAccount: func(t *testing.T, acc model.Account) {
+				mem.putAccount(acc)
+			},
+			addUser: func(t *testing.T, id uuid.UUID) {
+				mem.users[id] = model.UserHTTP{ID: id, Name: "owner"}
+			},
 		},
 	}
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			got, err := acc.Get(context.Background(), tc.param)
-			if (err != nil) && err.Error() != tc.wantErr {
-				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
-				return
+
+	lite, err := OpenSQL("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=1", loggingService)
+	require.NoError(t, err)
+	t.Cleanup(func() { lite.Close() })
+	stores = append(stores, sqlTestStore("sqlite", lite))
+
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		pg, err := OpenSQL(PostgresStorage, dsn, loggingService)
+		require.NoError(t, err)
+		t.Cleanup(func() { pg.Close() })
+
+		_, err = pg.db.Exec("DELETE FROM ledger; DELETE FROM transfers; DELETE FROM accounts; DELETE FROM users;")
+		require.NoError(t, err)
+		stores = append(stores, sqlTestStore(PostgresStorage, pg))
+	}
+
+	return stores
+}
+
+func sqlTestStore(name string, s *StorageSQL) testStore {
+	addUser := func(t *testing.T, id uuid.UUID) {
+		_, err := s.db.Exec("INSERT INTO users (id, name, version) VALUES ($1, $2, 0) ON CONFLICT DO NOTHING", id, "owner")
+		require.NoError(t, err)
+	}
+
+	return testStore{
+		name:    name,
+		store:   s,
+		addUser: addUser,
+		addAccount: func(t *testing.T, acc model.Account) {
+			addUser(t, acc.UserID)
+			_, err := s.db.Exec("INSERT INTO accounts (id, user_id, currency, balance, overdraft_limit, version) VALUES ($1, $2, $3, $4, $5, $6)",
+				acc.ID, acc.UserID, acc.Currency, acc.Balance, acc.OverdraftLimit, acc.Version)
+			require.NoError(t, err)
+		},
+	}
+}
+
+func TestStorageDB_Get(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		acc := s.store
+		id := uuid.New()
+		userID := uuid.New()
+		m := model.Account{ID: id, UserID: userID, Balance: 0}
+		s.addAccount(t, m)
+
+		tests := []struct {
+			name    string
+			param   uuid.UUID
+			want    model.Account
+			wantErr string
+		}{
+			{
+				name:  "Everything ok",
+				param: id,
+				want:  m,
+			},
+			{
+				name:    "Not found",
+				param:   uuid.New(),
+				want:    model.Account{},
+				wantErr: "not found",
+			},
+		}
+		for _, tc := range tests {
+			t.Run(s.name+"/"+tc.name, func(t *testing.T) {
+				got, err := acc.GetAccount(context.Background(), tc.param)
+				if (err != nil) && err.Error() != tc.wantErr {
+					t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
+					return
+				}
+				assert.Equal(t, tc.want, got)
+			})
+		}
+	}
+}
+
+func TestStorageDB_GetUser(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		acc := s.store
+		id := uuid.New()
+		userID := uuid.New()
+		m := model.Account{ID: id, UserID: userID, Balance: 0}
+		s.addAccount(t, m)
+		res := []model.Account{
+			m,
+		}
+
+		tests := []struct {
+			name string
+			want []model.Account
+		}{
+			{
+				name: "Everything ok",
+				want: res,
+			},
+		}
+		for _, tc := range tests {
+			t.Run(s.name+"/"+tc.name, func(t *testing.T) {
+				got, _ := acc.GetUserAccounts(context.Background(), userID)
+				assert.Equal(t, tc.want, got)
+			})
+		}
+	}
+}
+
+func TestStorageDB_SharedStore(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			owner, err := s.store.CreateUser(ctx, model.UserHTTP{Name: "owner"})
+			require.NoError(t, err)
+			heir, err := s.store.CreateUser(ctx, model.UserHTTP{Name: "heir"})
+			require.NoError(t, err)
+			acc, err := s.store.CreateAccount(ctx, model.Account{UserID: owner.ID})
+			require.NoError(t, err)
+
+			// users and accounts live side by side without showing up as each other
+			_, err = s.store.GetAccount(ctx, owner.ID)
+			assert.ErrorIs(t, err, customErrors.NotFound)
+			_, err = s.store.GetUser(ctx, acc.ID)
+			assert.ErrorIs(t, err, customErrors.NotFound)
+			assert.ErrorIs(t, s.store.DeleteUser(ctx, acc.ID), customErrors.NotFound)
+
+			users, err := s.store.ListUsers(ctx, model.UserFilter{Sort: model.SortByID, PageSize: 10})
+			require.NoError(t, err)
+			assert.Len(t, users.Users, 2)
+			accounts, err := s.store.ListAccounts(ctx, model.AccountFilter{Sort: model.SortByID, PageSize: 10})
+			require.NoError(t, err)
+			assert.Equal(t, []model.Account{acc}, accounts.Accounts)
+
+			// the index by owner follows a change of owner and a delete
+			acc.UserID = heir.ID
+			acc, err = s.store.UpdateAccount(ctx, acc)
+			require.NoError(t, err)
+			got, err := s.store.GetUserAccounts(ctx, owner.ID)
+			require.NoError(t, err)
+			assert.Empty(t, got)
+			got, err = s.store.GetUserAccounts(ctx, heir.ID)
+			require.NoError(t, err)
+			assert.Equal(t, []model.Account{acc}, got)
+
+			require.NoError(t, s.store.DeleteAccount(ctx, acc.ID))
+			got, err = s.store.GetUserAccounts(ctx, heir.ID)
+			require.NoError(t, err)
+			assert.Empty(t, got)
+		})
+	}
+}
+
+func TestStorageDB_SoftDeleteUser(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			user, err := s.store.CreateUser(ctx, model.UserHTTP{Name: "gone"})
+			require.NoError(t, err)
+
+			assert.ErrorIs(t, s.store.RestoreUser(ctx, user.ID), customErrors.NotFound, "only deleted users are restored")
+			require.NoError(t, s.store.SoftDeleteUser(ctx, user.ID))
+			assert.ErrorIs(t, s.store.SoftDeleteUser(ctx, user.ID), customErrors.NotFound)
+
+			_, err = s.store.GetUser(ctx, user.ID)
+			assert.ErrorIs(t, err, customErrors.NotFound)
+			_, err = s.store.UpdateUser(ctx, model.UserHTTP{ID: user.ID, Name: "back"})
+			assert.ErrorIs(t, err, customErrors.NotFound)
+			page, err := s.store.ListUsers(ctx, model.UserFilter{Sort: model.SortByID, PageSize: 10})
+			require.NoError(t, err)
+			assert.Empty(t, page.Users)
+
+			require.NoError(t, s.store.RestoreUser(ctx, user.ID))
+			got, err := s.store.GetUser(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, user, got)
+
+			require.NoError(t, s.store.SoftDeleteUser(ctx, user.ID))
+			require.NoError(t, s.store.DeleteUser(ctx, user.ID), "soft deleted users can be removed for good")
+			assert.ErrorIs(t, s.store.RestoreUser(ctx, user.ID), customErrors.NotFound)
+		})
+	}
+}
+
+func TestStorageDB_CreateUniqueAccount(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			userID := uuid.New()
+			s.addUser(t, userID)
+			s.addAccount(t, model.Account{ID: uuid.New(), UserID: userID, Currency: "EUR"})
+
+			_, err := s.store.CreateUniqueAccount(ctx, model.Account{UserID: userID, Currency: "EUR"})
+			assert.ErrorIs(t, err, customErrors.CurrencyTaken, "an account opened before the option counts")
+
+			_, err = s.store.CreateUniqueAccount(ctx, model.Account{UserID: userID, Currency: "USD"})
+			require.NoError(t, err)
+			_, err = s.store.CreateUniqueAccount(ctx, model.Account{UserID: userID, Currency: "USD"})
+			assert.ErrorIs(t, err, customErrors.CurrencyTaken)
+
+			// an open that passed the check concurrently is rejected by the index
+			if sqlStore, ok := s.store.(*StorageSQL); ok {
+				_, err = sqlStore.db.Exec("INSERT INTO accounts (id, user_id, currency, exclusive_currency) VALUES ($1, $2, $3, TRUE)", uuid.New(), userID, "USD")
+				assert.True(t, isUniqueViolation(err), "unexpected error %v", err)
+			}
+
+			_, err = s.store.CreateAccount(ctx, model.Account{UserID: userID, Currency: "USD"})
+			assert.NoError(t, err, "without the option a second account in a currency is fine")
+		})
+	}
+}
+
+func TestStorageDB_UpdateAccountExclusiveCurrency(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			owner, heir, other := uuid.New(), uuid.New(), uuid.New()
+			for _, id := range []uuid.UUID{owner, heir, other} {
+				s.addUser(t, id)
 			}
-			assert.Equal(t, tc.want, got)
+
+			acc, err := s.store.CreateUniqueAccount(ctx, model.Account{UserID: owner, Currency: "USD"})
+			require.NoError(t, err)
+			_, err = s.store.CreateUniqueAccount(ctx, model.Account{UserID: heir, Currency: "USD"})
+			require.NoError(t, err)
+			_, err = s.store.CreateAccount(ctx, model.Account{UserID: other, Currency: "USD"})
+			require.NoError(t, err)
+
+			acc.UserID = heir
+			_, err = s.store.UpdateAccount(ctx, acc)
+			assert.ErrorIs(t, err, customErrors.CurrencyTaken)
+
+			stored, err := s.store.GetAccount(ctx, acc.ID)
+			require.NoError(t, err)
+			assert.Equal(t, owner, stored.UserID, "a refused update keeps the owner")
+
+			acc.UserID = other
+			_, err = s.store.UpdateAccount(ctx, acc)
+			assert.NoError(t, err, "an account opened without the option does not hold the currency")
 		})
 	}
 }
 
-//
-func TestStorageDB_GetUser(t *testing.T) {
-	loggingService := MockLoggingService{}
-	acc := NewDB(loggingService)
-	id := uuid.New()
+func TestStorageDB_CreateUniqueAccountConcurrently(t *testing.T) {
+	store := NewDB(loggingservice.LoggingService{})
 	userID := uuid.New()
-	m := model.Account{ID: id, UserID: userID, Balance: 0}
-	acc.Data[id] = m
-	res := []model.Account{
-		m,
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = store.CreateUniqueAccount(context.Background(), model.Account{UserID: userID, Currency: "USD"})
+		}(i)
 	}
+	wg.Wait()
 
-	tests := []struct {
-		name string
-		want []model.Account
-	}{
-		{
-			name: "Everything ok",
-			want: res,
-		},
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.ErrorIs(t, err, customErrors.CurrencyTaken)
 	}
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			got, _ := acc.GetUserAccounts(context.Background(), userID)
-			assert.Equal(t, tc.want, got)
+	assert.Equal(t, 1, created, "only one of concurrent opens in the same currency succeeds")
+}
+
+func TestStorageDB_CloseUserAccounts(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			userID := uuid.New()
+			empty := model.Account{ID: uuid.New(), UserID: userID}
+			funded := model.Account{ID: uuid.New(), UserID: userID, Balance: 10, OverdraftLimit: 5}
+			other := model.Account{ID: uuid.New(), UserID: uuid.New(), Balance: 3}
+			for _, acc := range []model.Account{empty, funded, other} {
+				s.addAccount(t, acc)
+			}
+
+			_, err := s.store.CloseUserAccounts(ctx, userID, false)
+			assert.ErrorIs(t, err, customErrors.AccountsNotEmpty)
+			got, err := s.store.GetUserAccounts(ctx, userID)
+			require.NoError(t, err)
+			assert.Len(t, got, 2, "a refused close keeps every account")
+
+			closed, err := s.store.CloseUserAccounts(ctx, userID, true)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []model.Account{empty, funded}, closed)
+			got, err = s.store.GetUserAccounts(ctx, userID)
+			require.NoError(t, err)
+			assert.Empty(t, got)
+			_, err = s.store.GetAccount(ctx, other.ID)
+			assert.NoError(t, err, "accounts of other users stay")
+			assert.Equal(t, []model.EntryType{model.EntryClosing}, entryTypes(t, s.store, funded.ID), "the closing entry zeroes the balance")
+			assert.Empty(t, entryTypes(t, s.store, empty.ID), "empty accounts close without an entry")
+
+			closed, err = s.store.CloseUserAccounts(ctx, userID, false)
+			require.NoError(t, err)
+			assert.Empty(t, closed)
+
+			require.NoError(t, s.store.RestoreAccounts(ctx, []model.Account{empty, funded}))
+			got, err = s.store.GetUserAccounts(ctx, userID)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []model.Account{empty, funded}, got)
+			assert.Error(t, s.store.RestoreAccounts(ctx, []model.Account{empty}), "an account is restored once")
+			assert.Equal(t, []model.EntryType{model.EntryClosing, model.EntryReopening}, entryTypes(t, s.store, funded.ID))
+
+			page, err := s.store.ListTransactions(ctx, model.TransactionFilter{AccountID: funded.ID, PageSize: 100})
+			require.NoError(t, err)
+			var sum int64
+			for _, entry := range page.Transactions {
+				sum += entry.Amount
+			}
+			assert.Equal(t, int64(0), sum, "the reopening entry undoes the closing one")
+			assert.Equal(t, funded.Balance, page.Transactions[len(page.Transactions)-1].Balance)
 		})
 	}
 }
 
-func TestStorageDB_GetAll(t *testing.T) {
-	loggingService := MockLoggingService{}
-	acc := NewDB(loggingService)
-	id := uuid.New()
-	userID := uuid.New()
-	m := model.Account{ID: id, UserID: userID, Balance: 0}
-	acc.Data[id] = m
-	res := []model.Account{
-		m,
+// entryTypes lists the types of the ledger entries of the account in order
+func entryTypes(t *testing.T, store store, id uuid.UUID) []model.EntryType {
+	page, err := store.ListTransactions(context.Background(), model.TransactionFilter{AccountID: id, PageSize: 100})
+	require.NoError(t, err)
+
+	var types []model.EntryType
+	for _, entry := range page.Transactions {
+		types = append(types, entry.Type)
 	}
 
-	tests := []struct {
-		name string
-		want []model.Account
-	}{
-		{
-			name: "Everything ok",
-			want: res,
-		},
+	return types
+}
+
+func TestStorageDB_ListAccounts(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			userID := uuid.New()
+			other := uuid.New()
+			var accounts []model.Account
+			for i, balance := range []int64{30, 10, 20, 10, 40} {
+				acc := model.Account{ID: uuid.New(), UserID: userID, Balance: balance}
+				if i == 4 {
+					acc.UserID = other
+				}
+				s.addAccount(t, acc)
+				accounts = append(accounts, acc)
+			}
+
+			// pages of two, following the tokens, must return every match once in order
+			list := func(filter model.AccountFilter) []int64 {
+				var balances []int64
+				filter.PageSize = 2
+				for {
+					page, err := s.store.ListAccounts(ctx, filter)
+					require.NoError(t, err)
+					assert.LessOrEqual(t, len(page.Accounts), 2)
+					for _, acc := range page.Accounts {
+						balances = append(balances, acc.Balance)
+					}
+					if page.NextPageToken == "" {
+						return balances
+					}
+					filter.PageToken = page.NextPageToken
+				}
+			}
+
+			assert.Equal(t, []int64{10, 10, 20, 30, 40}, list(model.AccountFilter{Sort: model.SortByBalance}))
+			assert.Equal(t, []int64{40, 30, 20, 10, 10}, list(model.AccountFilter{Sort: "-" + model.SortByBalance}))
+			assert.Equal(t, []int64{10, 10, 20, 30}, list(model.AccountFilter{UserID: userID, Sort: model.SortByBalance}))
+
+			min, max := int64(15), int64(35)
+			assert.Equal(t, []int64{20, 30}, list(model.AccountFilter{MinBalance: &min, MaxBalance: &max, Sort: model.SortByBalance}))
+			assert.Len(t, list(model.AccountFilter{Sort: model.SortByID}), len(accounts))
+
+			page, err := s.store.ListAccounts(ctx, model.AccountFilter{Sort: model.SortByBalance, PageSize: 2})
+			require.NoError(t, err)
+			_, err = s.store.ListAccounts(ctx, model.AccountFilter{Sort: model.SortByID, PageSize: 2, PageToken: page.NextPageToken})
+			assert.ErrorIs(t, err, customErrors.InvalidArgument, "a token is bound to its sort order")
+
+			_, err = s.store.ListAccounts(ctx, model.AccountFilter{Sort: model.SortByID, PageSize: 2, PageToken: "garbage"})
+			assert.ErrorIs(t, err, customErrors.InvalidArgument)
+		})
 	}
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			got, _ := acc.GetAll(context.Background())
-			assert.Equal(t, tc.want, got)
+}
+
+func TestStorageDB_ListUsers(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, name := range []string{"bob", "alice", "bobby", "dave", "carl"} {
+				_, err := s.store.CreateUser(ctx, model.UserHTTP{Name: name})
+				require.NoError(t, err)
+			}
+
+			list := func(filter model.UserFilter) []string {
+				var names []string
+				filter.PageSize = 2
+				for {
+					page, err := s.store.ListUsers(ctx, filter)
+					require.NoError(t, err)
+					for _, user := range page.Users {
+						names = append(names, user.Name)
+					}
+					if page.NextPageToken == "" {
+						return names
+					}
+					filter.PageToken = page.NextPageToken
+				}
+			}
+
+			assert.Equal(t, []string{"alice", "bob", "bobby", "carl", "dave"}, list(model.UserFilter{Sort: model.SortByName}))
+			assert.Equal(t, []string{"dave", "carl", "bobby", "bob", "alice"}, list(model.UserFilter{Sort: "-" + model.SortByName}))
+			assert.Equal(t, []string{"bob", "bobby"}, list(model.UserFilter{NamePrefix: "bob", Sort: model.SortByName}))
+			assert.Len(t, list(model.UserFilter{Sort: model.SortByID}), 5)
 		})
 	}
 }
 
 func TestStorageDB_Create(t *testing.T) {
-	loggingService := MockLoggingService{}
-	acc := NewDB(loggingService)
-	m := model.UserHTTP{Name: "Jim"}
-
-	tests := []struct {
-		name  string
-		param model.UserHTTP
-	}{
-		{
-			name:  "Everything ok",
-			param: m,
-		},
+	for _, s := range newTestStores(t) {
+		acc := s.store
+		m := model.UserHTTP{Name: "Jim"}
+
+		tests := []struct {
+			name  string
+			param model.UserHTTP
+		}{
+			{
+				name:  "Everything ok",
+				param: m,
+			},
+		}
+		for _, tc := range tests {
+			t.Run(s.name+"/"+tc.name, func(t *testing.T) {
+
+				val, err := acc.CreateUser(context.Background(), tc.param)
+				require.NoError(t, err)
+				assert.Equal(t, tc.param.Name, val.Name)
+
+				got, err := acc.GetUser(context.Background(), val.ID)
+				require.NoError(t, err)
+				assert.Equal(t, val, got)
+			})
+		}
+	}
+}
+
+func TestStorageDB_Update(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		acc := s.store
+		id := uuid.New()
+		userID := uuid.New()
+		s.addAccount(t, model.Account{ID: id, UserID: userID, Balance: 0})
+
+		tests := []struct {
+			name  string
+			param model.Account
+			want  model.Account
+		}{
+			{
+				name:  "Keeps owner and balance",
+				param: model.Account{ID: id, Balance: 100, OverdraftLimit: 20},
+				want:  model.Account{ID: id, UserID: userID, OverdraftLimit: 20, Version: 1},
+			},
+		}
+		for _, tc := range tests {
+			t.Run(s.name+"/"+tc.name, func(t *testing.T) {
+				got, err := acc.UpdateAccount(context.Background(), tc.param)
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+
+				stored, err := acc.GetAccount(context.Background(), id)
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, stored)
+			})
+		}
 	}
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
+}
+
+func TestStorageDB_UpdateVersion(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			user, err := s.store.CreateUser(ctx, model.UserHTTP{Name: "owner"})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), user.Version)
+			acc, err := s.store.CreateAccount(ctx, model.Account{UserID: user.ID})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), acc.Version)
+
+			acc.Balance = 10
+			updated, err := s.store.UpdateAccount(ctx, acc)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), updated.Version)
+			_, err = s.store.UpdateAccount(ctx, acc)
+			assert.ErrorIs(t, err, customErrors.VersionMismatch, "the stale version must not overwrite the update")
+
+			user.Name = "renamed"
+			renamed, err := s.store.UpdateUser(ctx, user)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), renamed.Version)
+			_, err = s.store.UpdateUser(ctx, user)
+			assert.ErrorIs(t, err, customErrors.VersionMismatch)
 
-			val, _ := acc.Create(context.Background(), tc.param)
-			require.NotNil(t, val, "should be no error")
+			stored, err := s.store.GetUser(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, renamed, stored)
 		})
 	}
 }
 
 func TestStorageDB_Delete(t *testing.T) {
-	loggingService := MockLoggingService{}
-	acc := NewDB(loggingService)
-	id := uuid.New()
-	userID := uuid.New()
-	m := model.Account{ID: id, UserID: userID, Balance: 0}
-	acc.Data[id] = m
-
-	tests := []struct {
-		name    string
-		param   uuid.UUID
-		want    []byte
-		wantErr string
-	}{
-		{
-			name:  "Everything ok",
-			param: id,
-			want:  nil,
-		},
+	for _, s := range newTestStores(t) {
+		acc := s.store
+		id := uuid.New()
+		userID := uuid.New()
+		m := model.Account{ID: id, UserID: userID, Balance: 0}
+		s.addAccount(t, m)
+
+		tests := []struct {
+			name    string
+			param   uuid.UUID
+			want    []byte
+			wantErr string
+		}{
+			{
+				name:  "Everything ok",
+				param: id,
+				want:  nil,
+			},
+		}
+		for _, tc := range tests {
+			t.Run(s.name+"/"+tc.name, func(t *testing.T) {
+				err := acc.DeleteAccount(context.Background(), id)
+				assert.Empty(t, err)
+			})
+		}
+	}
+}
+
+func TestStorageDB_Transfer(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		acc := s.store
+		userID := uuid.New()
+		from := model.Account{ID: uuid.New(), UserID: userID, Balance: 100}
+		to := model.Account{ID: uuid.New(), UserID: userID, Balance: 5}
+		other := model.Account{ID: uuid.New(), UserID: uuid.New(), Balance: 100}
+		s.addAccount(t, from)
+		s.addAccount(t, to)
+		s.addAccount(t, other)
+
+		tests := []struct {
+			name    string
+			param   model.Transfer
+			want    model.TransferResult
+			wantErr error
+		}{
+			{
+				name:  "Everything ok",
+				param: model.Transfer{From: from.ID, To: to.ID, Amount: 40, IdempotencyKey: "key", Credit: 40, Rate: "1"},
+				want: model.TransferResult{
+					From:   model.Account{ID: from.ID, UserID: userID, Balance: 60, Version: 1},
+					To:     model.Account{ID: to.ID, UserID: userID, Balance: 45, Version: 1},
+					Credit: 40,
+					Rate:   "1",
+				},
+			},
+			{
+				name:  "Replayed by idempotency key at another rate",
+				param: model.Transfer{From: from.ID, To: to.ID, Amount: 40, IdempotencyKey: "key", Credit: 44, Rate: "1.1"},
+				want: model.TransferResult{
+					From:     model.Account{ID: from.ID, UserID: userID, Balance: 60, Version: 1},
+					To:       model.Account{ID: to.ID, UserID: userID, Balance: 45, Version: 1},
+					Credit:   40,
+					Rate:     "1",
+					Replayed: true,
+				},
+			},
+			{
+				name:    "Idempotency key reused",
+				param:   model.Transfer{From: from.ID, To: to.ID, Amount: 1, IdempotencyKey: "key", Credit: 1, Rate: "1"},
+				wantErr: customErrors.IdempotencyKeyReused,
+			},
+			{
+				name:  "Converted",
+				param: model.Transfer{From: from.ID, To: to.ID, Amount: 10, Credit: 1495, Rate: "149.5"},
+				want: model.TransferResult{
+					From:   model.Account{ID: from.ID, UserID: userID, Balance: 50, Version: 2},
+					To:     model.Account{ID: to.ID, UserID: userID, Balance: 1540, Version: 2},
+					Credit: 1495,
+					Rate:   "149.5",
+				},
+			},
+			{
+				name:    "Insufficient funds",
+				param:   model.Transfer{From: from.ID, To: to.ID, Amount: 51, Credit: 51, Rate: "1"},
+				wantErr: customErrors.InsufficientFunds,
+			},
+			{
+				name:  "Same idempotency key of another owner",
+				param: model.Transfer{From: other.ID, To: to.ID, Amount: 5, IdempotencyKey: "key", Credit: 5, Rate: "1"},
+				want: model.TransferResult{
+					From:   model.Account{ID: other.ID, UserID: other.UserID, Balance: 95, Version: 1},
+					To:     model.Account{ID: to.ID, UserID: userID, Balance: 1545, Version: 3},
+					Credit: 5,
+					Rate:   "1",
+				},
+			},
+			{
+				name:    "Not found",
+				param:   model.Transfer{From: from.ID, To: uuid.New(), Amount: 1, Credit: 1, Rate: "1"},
+				wantErr: customErrors.NotFound,
+			},
+		}
+		for _, tc := range tests {
+			t.Run(s.name+"/"+tc.name, func(t *testing.T) {
+				got, err := acc.Transfer(context.Background(), tc.param)
+				if tc.wantErr != nil {
+					assert.ErrorIs(t, err, tc.wantErr)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+			})
+		}
+
+		stored, err := acc.GetAccount(context.Background(), from.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(50), stored.Balance, "failed transfers must not change balances")
+	}
+}
+
+func TestStorageDB_TransferKeyExpiry(t *testing.T) {
+	sdb := NewDB(loggingservice.LoggingService{})
+	from := model.Account{ID: uuid.New(), UserID: uuid.New(), Balance: 100}
+	to := model.Account{ID: uuid.New(), UserID: uuid.New()}
+	sdb.putAccount(from)
+	sdb.putAccount(to)
+
+	transfer := model.Transfer{From: from.ID, To: to.ID, Amount: 10, IdempotencyKey: "key", Credit: 10, Rate: "1"}
+	_, err := sdb.Transfer(context.Background(), transfer)
+	require.NoError(t, err)
+
+	key := transferKey{from: from.ID, key: "key"}
+	rec := sdb.transfers[key]
+	rec.expiresAt = time.Now().Add(-time.Second)
+	sdb.transfers[key] = rec
+	sdb.transfers[transferKey{from: from.ID, key: "other"}] = transferRecord{expiresAt: time.Now().Add(-time.Second)}
+	sdb.transfersPurged = time.Now().Add(-transferKeyTTL)
+
+	res, err := sdb.Transfer(context.Background(), transfer)
+	require.NoError(t, err)
+	assert.False(t, res.Replayed, "an expired key is free again")
+	assert.Equal(t, int64(80), res.From.Balance)
+	assert.NotContains(t, sdb.transfers, transferKey{from: from.ID, key: "other"}, "expired keys are purged")
+}
+
+func TestStorageDB_ApplyDelta(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		acc := s.store
+		userID := uuid.New()
+		plain := model.Account{ID: uuid.New(), UserID: userID, Balance: 10}
+		overdraft := model.Account{ID: uuid.New(), UserID: userID, Balance: 10, OverdraftLimit: 50}
+		s.addAccount(t, plain)
+		s.addAccount(t, overdraft)
+
+		tests := []struct {
+			name    string
+			id      uuid.UUID
+			delta   int64
+			want    model.Account
+			wantErr error
+		}{
+			{
+				name:  "Deposit",
+				id:    plain.ID,
+				delta: 15,
+				want:  model.Account{ID: plain.ID, UserID: userID, Balance: 25, Version: 1},
+			},
+			{
+				name:  "Withdraw",
+				id:    plain.ID,
+				delta: -25,
+				want:  model.Account{ID: plain.ID, UserID: userID, Balance: 0, Version: 2},
+			},
+			{
+				name:    "Insufficient funds",
+				id:      plain.ID,
+				delta:   -1,
+				wantErr: customErrors.InsufficientFunds,
+			},
+			{
+				name:  "Withdraw into overdraft",
+				id:    overdraft.ID,
+				delta: -60,
+				want:  model.Account{ID: overdraft.ID, UserID: userID, Balance: -50, OverdraftLimit: 50, Version: 1},
+			},
+			{
+				name:    "Overdraft limit exceeded",
+				id:      overdraft.ID,
+				delta:   -1,
+				wantErr: customErrors.InsufficientFunds,
+			},
+			{
+				name:    "Not found",
+				id:      uuid.New(),
+				delta:   1,
+				wantErr: customErrors.NotFound,
+			},
+		}
+		for _, tc := range tests {
+			t.Run(s.name+"/"+tc.name, func(t *testing.T) {
+				got, err := acc.ApplyDelta(context.Background(), tc.id, tc.delta)
+				if tc.wantErr != nil {
+					assert.ErrorIs(t, err, tc.wantErr)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+			})
+		}
+
+		page, err := acc.ListTransactions(context.Background(), model.TransactionFilter{AccountID: plain.ID, PageSize: 10})
+		require.NoError(t, err)
+		require.Len(t, page.Transactions, 2)
+		assert.Equal(t, model.EntryDeposit, page.Transactions[0].Type)
+		assert.Equal(t, model.EntryWithdrawal, page.Transactions[1].Type)
 	}
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			err := acc.Delete(context.Background(), id)
-			assert.Empty(t, err)
+}
+
+func TestStorageDB_ListTransactions(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		acc := s.store
+		ctx := context.Background()
+		userID := uuid.New()
+		from := model.Account{ID: uuid.New(), UserID: userID}
+		to := model.Account{ID: uuid.New(), UserID: userID}
+		s.addAccount(t, from)
+		s.addAccount(t, to)
+
+		start := time.Now().Add(-time.Second)
+		_, err := acc.ApplyDelta(ctx, from.ID, 100)
+		require.NoError(t, err)
+		_, err = acc.Transfer(ctx, model.Transfer{From: from.ID, To: to.ID, Amount: 30, Credit: 30, Rate: "1"})
+		require.NoError(t, err)
+		_, err = acc.Transfer(ctx, model.Transfer{From: to.ID, To: from.ID, Amount: 10, Credit: 10, Rate: "1"})
+		require.NoError(t, err)
+
+		t.Run(s.name+"/Pages", func(t *testing.T) {
+			first, err := acc.ListTransactions(ctx, model.TransactionFilter{AccountID: from.ID, PageSize: 2})
+			require.NoError(t, err)
+			require.Len(t, first.Transactions, 2)
+			assert.Equal(t, model.EntryDeposit, first.Transactions[0].Type)
+			assert.Equal(t, model.EntryTransferOut, first.Transactions[1].Type)
+			require.NotEmpty(t, first.NextPageToken)
+
+			second, err := acc.ListTransactions(ctx, model.TransactionFilter{AccountID: from.ID, PageSize: 2, PageToken: first.NextPageToken})
+			require.NoError(t, err)
+			require.Len(t, second.Transactions, 1)
+			assert.Equal(t, model.EntryTransferIn, second.Transactions[0].Type)
+			assert.Empty(t, second.NextPageToken)
+		})
+
+		t.Run(s.name+"/Balance derivable", func(t *testing.T) {
+			for _, id := range []uuid.UUID{from.ID, to.ID} {
+				page, err := acc.ListTransactions(ctx, model.TransactionFilter{AccountID: id, PageSize: 100})
+				require.NoError(t, err)
+
+				sum := int64(0)
+				for _, entry := range page.Transactions {
+					sum += entry.Amount
+					assert.Equal(t, sum, entry.Balance)
+				}
+
+				stored, err := acc.GetAccount(ctx, id)
+				require.NoError(t, err)
+				assert.Equal(t, stored.Balance, sum)
+			}
+		})
+
+		t.Run(s.name+"/Time range", func(t *testing.T) {
+			page, err := acc.ListTransactions(ctx, model.TransactionFilter{AccountID: from.ID, From: start, To: time.Now().Add(time.Second), PageSize: 100})
+			require.NoError(t, err)
+			assert.Len(t, page.Transactions, 3)
+
+			page, err = acc.ListTransactions(ctx, model.TransactionFilter{AccountID: from.ID, To: start, PageSize: 100})
+			require.NoError(t, err)
+			assert.Empty(t, page.Transactions)
 		})
 	}
 }
+
+func TestStorageDB_Credentials(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			creds, ok := s.store.(CredentialStore)
+			require.True(t, ok)
+
+			userID := uuid.New()
+			s.addUser(t, userID)
+			cred := model.Credential{Name: "bob", UserID: userID, PasswordHash: "hash", Role: model.RoleUser}
+
+			_, err := creds.GetCredential(context.Background(), "bob")
+			assert.ErrorIs(t, err, customErrors.NotFound)
+
+			require.NoError(t, creds.CreateCredential(context.Background(), cred))
+			assert.ErrorIs(t, creds.CreateCredential(context.Background(), cred), customErrors.AlreadyExists)
+
+			require.NoError(t, creds.UpdatePassword(context.Background(), "bob", "new hash"))
+			assert.ErrorIs(t, creds.UpdatePassword(context.Background(), "alice", "hash"), customErrors.NotFound)
+
+			require.NoError(t, creds.UpdateRole(context.Background(), "bob", model.RoleAdmin))
+			assert.ErrorIs(t, creds.UpdateRole(context.Background(), "alice", model.RoleAdmin), customErrors.NotFound)
+
+			got, err := creds.GetCredential(context.Background(), "bob")
+			require.NoError(t, err)
+			assert.Equal(t, model.Credential{Name: "bob", UserID: userID, PasswordHash: "new hash", Role: model.RoleAdmin}, got)
+
+			names, err := creds.SetDisabled(context.Background(), userID, true)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"bob"}, names)
+			got, err = creds.GetCredential(context.Background(), "bob")
+			require.NoError(t, err)
+			assert.True(t, got.Disabled)
+
+			_, err = creds.SetDisabled(context.Background(), userID, false)
+			require.NoError(t, err)
+			got, err = creds.GetCredential(context.Background(), "bob")
+			require.NoError(t, err)
+			assert.False(t, got.Disabled)
+
+			names, err = creds.SetDisabled(context.Background(), uuid.New(), true)
+			require.NoError(t, err)
+			assert.Empty(t, names, "a user without credentials has nothing to disable")
+		})
+	}
+}
+
+func TestStorageDB_Tokens(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			tokens, ok := s.store.(TokenStore)
+			require.True(t, ok)
+
+			ctx := context.Background()
+			family := uuid.New()
+			now := time.Now()
+			first := model.RefreshToken{Hash: "first", Family: family, Name: "bob", ExpiresAt: now.Add(time.Hour), AccessJTI: "a1", AccessExpiresAt: now.Add(time.Minute)}
+			second := model.RefreshToken{Hash: "second", Family: family, Name: "bob", ExpiresAt: now.Add(time.Hour), AccessJTI: "a2", AccessExpiresAt: now.Add(time.Minute)}
+			expired := model.RefreshToken{Hash: "expired", Family: uuid.New(), Name: "bob", ExpiresAt: now.Add(-time.Hour), AccessJTI: "a3", AccessExpiresAt: now.Add(-time.Hour)}
+
+			require.NoError(t, tokens.CreateRefreshToken(ctx, first))
+			require.NoError(t, tokens.CreateRefreshToken(ctx, second))
+			require.NoError(t, tokens.CreateRefreshToken(ctx, expired))
+
+			_, err := tokens.UseRefreshToken(ctx, "unknown")
+			assert.ErrorIs(t, err, customErrors.NotFound)
+
+			_, err = tokens.UseRefreshToken(ctx, "expired")
+			assert.ErrorIs(t, err, customErrors.InvalidToken)
+
+			got, err := tokens.UseRefreshToken(ctx, "first")
+			require.NoError(t, err)
+			assert.Equal(t, family, got.Family)
+			assert.Equal(t, "bob", got.Name)
+
+			got, err = tokens.UseRefreshToken(ctx, "first")
+			assert.ErrorIs(t, err, customErrors.TokenReused)
+			assert.Equal(t, family, got.Family, "reused token must name its family")
+
+			require.NoError(t, tokens.RevokeFamily(ctx, family))
+
+			_, err = tokens.UseRefreshToken(ctx, "second")
+			assert.ErrorIs(t, err, customErrors.InvalidToken)
+
+			require.NoError(t, tokens.RevokeAccessToken(ctx, model.RevokedToken{JTI: "old", ExpiresAt: now.Add(-time.Minute)}))
+
+			alice := model.RefreshToken{Hash: "alice", Family: uuid.New(), Name: "alice", ExpiresAt: now.Add(time.Hour), AccessJTI: "a4", AccessExpiresAt: now.Add(time.Minute)}
+			require.NoError(t, tokens.CreateRefreshToken(ctx, alice))
+			require.NoError(t, tokens.RevokeName(ctx, "alice"))
+
+			_, err = tokens.UseRefreshToken(ctx, "alice")
+			assert.ErrorIs(t, err, customErrors.InvalidToken)
+
+			revoked, err := tokens.ListRevokedTokens(ctx)
+			require.NoError(t, err)
+			jtis := make([]string, 0, len(revoked))
+			for _, r := range revoked {
+				jtis = append(jtis, r.JTI)
+			}
+			assert.ElementsMatch(t, []string{"a1", "a2", "a4"}, jtis, "expired access tokens are not listed")
+		})
+	}
+}
+
+func TestStorageSQL_ForeignKey(t *testing.T) {
+	lite, err := OpenSQL("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=1", loggingservice.LoggingService{})
+	require.NoError(t, err)
+	defer lite.Close()
+
+	_, err = lite.CreateAccount(context.Background(), model.Account{UserID: uuid.New()})
+	assert.Error(t, err, "account of a missing user should be rejected")
+}
+
+func TestStorageDB_Audit(t *testing.T) {
+	for _, s := range newTestStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			accountID := uuid.New()
+			start := time.Unix(0, time.Now().UnixNano()).UTC()
+			entries := []model.AuditEntry{
+				{ID: uuid.New(), Time: start, Actor: "bob", RequestID: "bob_1", Operation: model.AuditCreate,
+					Resource: model.AuditResourceAccount, ResourceID: accountID, After: []byte(`{"balance":0}`)},
+				{ID: uuid.New(), Time: start.Add(time.Second), Actor: "root", Operation: model.AuditUpdate,
+					Resource: model.AuditResourceAccount, ResourceID: accountID, Before: []byte(`{"balance":0}`), After: []byte(`{"balance":10}`)},
+				{ID: uuid.New(), Time: start.Add(2 * time.Second), Actor: "root", Operation: model.AuditCreate,
+					Resource: model.AuditResourceUser, ResourceID: uuid.New(), After: []byte(`{"name":"bob"}`)},
+			}
+			for _, entry := range entries {
+				require.NoError(t, s.store.AppendAudit(ctx, entry))
+			}
+
+			tests := []struct {
+				name      string
+				filter    model.AuditFilter
+				want      []model.AuditEntry
+				wantToken string
+			}{
+				{name: "Everything", filter: model.AuditFilter{PageSize: 10}, want: entries},
+				{name: "By resource", filter: model.AuditFilter{Resource: model.AuditResourceAccount, PageSize: 10}, want: entries[:2]},
+				{name: "By resource id", filter: model.AuditFilter{ResourceID: accountID, PageSize: 10}, want: entries[:2]},
+				{name: "By actor and operation", filter: model.AuditFilter{Actor: "root", Operation: model.AuditCreate, PageSize: 10}, want: entries[2:]},
+				{name: "By time", filter: model.AuditFilter{From: start.Add(time.Second), To: start.Add(2 * time.Second), PageSize: 10}, want: entries[1:2]},
+				{name: "First page", filter: model.AuditFilter{PageSize: 2}, want: entries[:2], wantToken: "2"},
+				{name: "Last page", filter: model.AuditFilter{PageSize: 2, PageToken: "2"}, want: entries[2:]},
+			}
+			for _, tc := range tests {
+				t.Run(tc.name, func(t *testing.T) {
+					got, err := s.store.ListAudit(ctx, tc.filter)
+					require.NoError(t, err)
+					assert.Equal(t, tc.want, got.Entries)
+					assert.Equal(t, tc.wantToken, got.NextPageToken)
+				})
+			}
+		})
+	}
+}
+
+func TestStorageDB_Collect(t *testing.T) {
+	sdb := NewDB(loggingservice.LoggingService{})
+	reg := prometheus.NewRegistry()
+	metrics.RegisterStore(reg, "users", sdb)
+
+	ctx := context.Background()
+	user, err := sdb.CreateUser(ctx, model.UserHTTP{Name: "bob"})
+	require.NoError(t, err)
+	_, err = sdb.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	_, err = sdb.GetUser(ctx, uuid.New())
+	require.Error(t, err)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	got := rec.Body.String()
+
+	assert.Contains(t, got, `storage_operations_total{method="CreateUser",store="users"} 1`)
+	assert.Contains(t, got, `storage_operations_total{method="GetUser",store="users"} 2`)
+	assert.Contains(t, got, `storage_memory_entries{map="users",store="users"} 1`)
+	assert.Contains(t, got, `storage_memory_entries{map="accounts",store="users"} 0`)
+}
@@ -3,13 +3,42 @@ package newStorage
 import (
 	"context"
 	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/model"
 )
 
-type NewStore interface {
-	Get(context.Context, uuid.UUID) (interface{}, error)
-	GetUserAccounts(context.Context, uuid.UUID) (interface{}, error)
-	GetAll(context.Context) (interface{}, error)
-	Create(context.Context, interface{}) (interface{}, error)
-	Update(context.Context, interface{}) (interface{}, error)
-	Delete(context.Context, uuid.UUID) error
+// UserRepository keeps the users of the user service, soft deleted users
+// are hidden from everything but DeleteUser and RestoreUser
+type UserRepository interface {
+	GetUser(context.Context, uuid.UUID) (model.UserHTTP, error)
+	ListUsers(context.Context, model.UserFilter) (model.UserPage, error)
+	CreateUser(context.Context, model.UserHTTP) (model.UserHTTP, error)
+	UpdateUser(context.Context, model.UserHTTP) (model.UserHTTP, error)
+	DeleteUser(context.Context, uuid.UUID) error
+	SoftDeleteUser(context.Context, uuid.UUID) error
+	RestoreUser(context.Context, uuid.UUID) error
+}
+
+// AccountRepository keeps accounts and the ledger of their balance changes
+type AccountRepository interface {
+	GetAccount(context.Context, uuid.UUID) (model.Account, error)
+	GetUserAccounts(context.Context, uuid.UUID) ([]model.Account, error)
+	ListAccounts(context.Context, model.AccountFilter) (model.AccountPage, error)
+	CreateAccount(context.Context, model.Account) (model.Account, error)
+	// CreateUniqueAccount creates the account unless its owner has one in the currency already,
+	// customErrors.CurrencyTaken otherwise
+	CreateUniqueAccount(context.Context, model.Account) (model.Account, error)
+	UpdateAccount(context.Context, model.Account) (model.Account, error)
+	DeleteAccount(context.Context, uuid.UUID) error
+	CloseUserAccounts(context.Context, uuid.UUID, bool) ([]model.Account, error)
+	RestoreAccounts(context.Context, []model.Account) error
+	Transfer(context.Context, model.Transfer) (model.TransferResult, error)
+	ListTransactions(context.Context, model.TransactionFilter) (model.TransactionPage, error)
+	ApplyDelta(context.Context, uuid.UUID, int64) (model.Account, error)
+}
+
+// AuditLog is the append-only audit trail, entries are listed in the order they were recorded
+type AuditLog interface {
+	AppendAudit(context.Context, model.AuditEntry) error
+	ListAudit(context.Context, model.AuditFilter) (model.AuditPage, error)
 }
@@ -0,0 +1,537 @@
+package newStorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type StorageSQL struct {
+	db *sql.DB
+	// system is the db.system of the spans
+	system string
+	logger loggingservice.Logger
+}
+
+// OpenSQL connects to the database and applies the embedded schema migrations
+func OpenSQL(driver, dsn string, logger loggingservice.Logger) (*StorageSQL, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err = migrate(context.Background(), db, driver); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &StorageSQL{
+		db:     db,
+		system: dbSystem(driver),
+		logger: logger.With("component", "storage"),
+	}, nil
+}
+
+func (s *StorageSQL) Close() error {
+	return s.db.Close()
+}
+
+func (s *StorageSQL) GetAccount(c context.Context, id uuid.UUID) (model.Account, error) {
+	c, span := s.startSpan(c, "GetAccount")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "GetAccount")
+
+	return getAccount(c, s.db, id)
+}
+
+func (s *StorageSQL) GetUserAccounts(c context.Context, userID uuid.UUID) ([]model.Account, error) {
+	c, span := s.startSpan(c, "GetUserAccounts")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "GetUserAccounts")
+
+	rows, err := s.db.QueryContext(c, "SELECT id, user_id, currency, balance, overdraft_limit, version FROM accounts WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAccounts(rows)
+}
+
+func (s *StorageSQL) CreateAccount(c context.Context, acc model.Account) (model.Account, error) {
+	c, span := s.startSpan(c, "CreateAccount")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "CreateAccount")
+
+	acc.ID = uuid.New()
+	acc.Version = 1
+	if _, err := s.db.ExecContext(c, "INSERT INTO accounts (id, user_id, currency, balance, overdraft_limit, version) VALUES ($1, $2, $3, $4, $5, $6)", acc.ID, acc.UserID, acc.Currency, acc.Balance, acc.OverdraftLimit, acc.Version); err != nil {
+		return model.Account{}, err
+	}
+
+	return acc, nil
+}
+
+func (s *StorageSQL) CreateUniqueAccount(c context.Context, acc model.Account) (model.Account, error) {
+	c, span := s.startSpan(c, "CreateUniqueAccount")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "CreateUniqueAccount")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return model.Account{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var taken int
+	err = tx.QueryRowContext(c, "SELECT COUNT(*) FROM accounts WHERE user_id = $1 AND currency = $2", acc.UserID, acc.Currency).Scan(&taken)
+	if err != nil {
+		return model.Account{}, err
+	}
+	if taken > 0 {
+		return model.Account{}, customErrors.CurrencyTaken
+	}
+
+	// the unique index rejects an account of the same owner and currency opened concurrently
+	acc.ID = uuid.New()
+	acc.Version = 1
+	_, err = tx.ExecContext(c, "INSERT INTO accounts (id, user_id, currency, balance, overdraft_limit, version, exclusive_currency) VALUES ($1, $2, $3, $4, $5, $6, TRUE)",
+		acc.ID, acc.UserID, acc.Currency, acc.Balance, acc.OverdraftLimit, acc.Version)
+	if isUniqueViolation(err) {
+		return model.Account{}, customErrors.CurrencyTaken
+	}
+	if err != nil {
+		return model.Account{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.Account{}, err
+	}
+
+	return acc, nil
+}
+
+// UpdateAccount keeps the stored owner when the update comes without one, the balance and the currency
+// never change. A non-zero version must match the stored one.
+func (s *StorageSQL) UpdateAccount(c context.Context, acc model.Account) (model.Account, error) {
+	c, span := s.startSpan(c, "UpdateAccount")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "UpdateAccount")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return model.Account{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stored, err := getAccount(c, tx, acc.ID)
+	if err != nil {
+		return model.Account{}, err
+	}
+
+	if acc.Version != 0 && acc.Version != stored.Version {
+		return model.Account{}, customErrors.VersionMismatch
+	}
+	if acc.UserID == uuid.Nil {
+		acc.UserID = stored.UserID
+	}
+	// the balance only moves through deposits, withdrawals and transfers, each with its ledger entry
+	acc.Balance = stored.Balance
+	acc.Currency = stored.Currency
+	acc.Version = stored.Version + 1
+
+	// the version check catches a concurrent update between the read and this write
+	result, err := tx.ExecContext(c, "UPDATE accounts SET user_id = $1, overdraft_limit = $2, version = $3 WHERE id = $4 AND version = $5",
+		acc.UserID, acc.OverdraftLimit, acc.Version, acc.ID, stored.Version)
+	// the new owner holds the currency exclusively
+	if isUniqueViolation(err) {
+		return model.Account{}, customErrors.CurrencyTaken
+	}
+	if err != nil {
+		return model.Account{}, err
+	}
+	if err = checkAffected(result); err != nil {
+		return model.Account{}, customErrors.VersionMismatch
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.Account{}, err
+	}
+
+	return acc, nil
+}
+
+func (s *StorageSQL) DeleteAccount(c context.Context, id uuid.UUID) error {
+	c, span := s.startSpan(c, "DeleteAccount")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "DeleteAccount")
+
+	result, err := s.db.ExecContext(c, "DELETE FROM accounts WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// CloseUserAccounts removes all accounts of the user at once, unless force is set
+// it refuses while any of them holds money or debt. A closing entry zeroes the balance
+// of every account that still has one, so balances stay derivable from the ledger.
+func (s *StorageSQL) CloseUserAccounts(c context.Context, userID uuid.UUID, force bool) ([]model.Account, error) {
+	c, span := s.startSpan(c, "CloseUserAccounts")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "CloseUserAccounts")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// deleting first locks the rows, so no deposit slips in between the check and the delete
+	rows, err := tx.QueryContext(c, "DELETE FROM accounts WHERE user_id = $1 RETURNING id, user_id, currency, balance, overdraft_limit, version", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	closed, err := scanAccounts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, acc := range closed {
+		if acc.Balance == 0 {
+			continue
+		}
+		if !force {
+			return nil, customErrors.AccountsNotEmpty
+		}
+		// the balance goes to the closing entry, so it stays derivable from the ledger
+		if err = appendEntry(c, tx, newEntry(acc.ID, model.EntryClosing, -acc.Balance, 0)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if closed == nil {
+		closed = []model.Account{}
+	}
+
+	return closed, nil
+}
+
+// RestoreAccounts puts back accounts removed by CloseUserAccounts, reopening entries undo the closing ones
+func (s *StorageSQL) RestoreAccounts(c context.Context, accounts []model.Account) error {
+	c, span := s.startSpan(c, "RestoreAccounts")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "RestoreAccounts")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, acc := range accounts {
+		if _, err = tx.ExecContext(c, "INSERT INTO accounts (id, user_id, currency, balance, overdraft_limit, version) VALUES ($1, $2, $3, $4, $5, $6)",
+			acc.ID, acc.UserID, acc.Currency, acc.Balance, acc.OverdraftLimit, acc.Version); err != nil {
+			return err
+		}
+		if acc.Balance == 0 {
+			continue
+		}
+		if err = appendEntry(c, tx, newEntry(acc.ID, model.EntryReopening, acc.Balance, acc.Balance)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *StorageSQL) Transfer(c context.Context, t model.Transfer) (model.TransferResult, error) {
+	c, span := s.startSpan(c, "Transfer")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "Transfer")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return model.TransferResult{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if t.IdempotencyKey != "" {
+		res, found, err := replayTransfer(c, tx, t)
+		if err != nil || found {
+			return res, err
+		}
+	}
+
+	// rows are updated in a stable order so opposite transfers do not deadlock
+	first, second := t.From, t.To
+	firstDelta, secondDelta := -t.Amount, t.Credit
+	if second.String() < first.String() {
+		first, second = second, first
+		firstDelta, secondDelta = secondDelta, firstDelta
+	}
+
+	if err = applyDelta(c, tx, first, firstDelta); err != nil {
+		return model.TransferResult{}, err
+	}
+	if err = applyDelta(c, tx, second, secondDelta); err != nil {
+		return model.TransferResult{}, err
+	}
+
+	from, err := getAccount(c, tx, t.From)
+	if err != nil {
+		return model.TransferResult{}, err
+	}
+	to, err := getAccount(c, tx, t.To)
+	if err != nil {
+		return model.TransferResult{}, err
+	}
+
+	if err = appendEntry(c, tx, newEntry(from.ID, model.EntryTransferOut, -t.Amount, from.Balance)); err != nil {
+		return model.TransferResult{}, err
+	}
+	if err = appendEntry(c, tx, newEntry(to.ID, model.EntryTransferIn, t.Credit, to.Balance)); err != nil {
+		return model.TransferResult{}, err
+	}
+
+	key := sql.NullString{String: t.IdempotencyKey, Valid: t.IdempotencyKey != ""}
+	_, err = tx.ExecContext(c, "INSERT INTO transfers (id, idempotency_key, from_id, to_id, amount, credit, rate, from_balance, to_balance, from_version, to_version) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		uuid.New(), key, t.From, t.To, t.Amount, t.Credit, t.Rate, from.Balance, to.Balance, from.Version, to.Version)
+	if isUniqueViolation(err) {
+		// a concurrent request with the same key got in first, this one is its retry
+		tx.Rollback() //nolint:errcheck
+		if res, found, replayErr := replayTransfer(c, s.db, t); found || replayErr != nil {
+			return res, replayErr
+		}
+	}
+	if err != nil {
+		return model.TransferResult{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.TransferResult{}, err
+	}
+
+	return model.TransferResult{From: from, To: to, Credit: t.Credit, Rate: t.Rate}, nil
+}
+
+func (s *StorageSQL) ApplyDelta(c context.Context, id uuid.UUID, delta int64) (model.Account, error) {
+	c, span := s.startSpan(c, "ApplyDelta")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "ApplyDelta")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return model.Account{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err = applyDelta(c, tx, id, delta); err != nil {
+		return model.Account{}, err
+	}
+
+	acc, err := getAccount(c, tx, id)
+	if err != nil {
+		return model.Account{}, err
+	}
+
+	if err = appendEntry(c, tx, newEntry(id, deltaEntryType(delta), delta, acc.Balance)); err != nil {
+		return model.Account{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.Account{}, err
+	}
+
+	return acc, nil
+}
+
+func (s *StorageSQL) ListTransactions(c context.Context, filter model.TransactionFilter) (model.TransactionPage, error) {
+	c, span := s.startSpan(c, "ListTransactions")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "ListTransactions")
+
+	offset, err := pageOffset(filter.PageToken)
+	if err != nil {
+		return model.TransactionPage{}, err
+	}
+
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+	if !filter.From.IsZero() {
+		from = filter.From.UnixNano()
+	}
+	if !filter.To.IsZero() {
+		to = filter.To.UnixNano()
+	}
+
+	// one extra row tells whether there is a next page
+	rows, err := s.db.QueryContext(c, `SELECT id, account_id, type, amount, balance, created_at FROM ledger
+		WHERE account_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at, id LIMIT $4 OFFSET $5`,
+		filter.AccountID, from, to, filter.PageSize+1, offset)
+	if err != nil {
+		return model.TransactionPage{}, err
+	}
+	defer rows.Close()
+
+	res := model.TransactionPage{
+		Transactions: []model.LedgerEntry{},
+	}
+	found := 0
+	for rows.Next() {
+		var (
+			entry     model.LedgerEntry
+			createdAt int64
+		)
+		if err = rows.Scan(&entry.ID, &entry.AccountID, &entry.Type, &entry.Amount, &entry.Balance, &createdAt); err != nil {
+			return model.TransactionPage{}, err
+		}
+		found++
+		if found > filter.PageSize {
+			break
+		}
+		entry.CreatedAt = time.Unix(0, createdAt).UTC()
+		res.Transactions = append(res.Transactions, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return model.TransactionPage{}, err
+	}
+
+	res.NextPageToken = nextPageToken(offset, filter.PageSize, found)
+
+	return res, nil
+}
+
+func appendEntry(c context.Context, tx *sql.Tx, entry model.LedgerEntry) error {
+	_, err := tx.ExecContext(c, "INSERT INTO ledger (id, account_id, type, amount, balance, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		entry.ID, entry.AccountID, entry.Type, entry.Amount, entry.Balance, entry.CreatedAt.UnixNano())
+
+	return err
+}
+
+// replayTransfer returns the recorded result of a transfer from the same account with the same idempotency key
+func replayTransfer(c context.Context, q queryer, t model.Transfer) (model.TransferResult, bool, error) {
+	var (
+		prev model.Transfer
+		res  model.TransferResult
+	)
+
+	err := q.QueryRowContext(c, "SELECT from_id, to_id, amount, credit, rate, from_balance, to_balance, from_version, to_version FROM transfers WHERE from_id = $1 AND idempotency_key = $2", t.From, t.IdempotencyKey).
+		Scan(&prev.From, &prev.To, &prev.Amount, &res.Credit, &res.Rate, &res.From.Balance, &res.To.Balance, &res.From.Version, &res.To.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.TransferResult{}, false, nil
+	}
+	if err != nil {
+		return model.TransferResult{}, false, err
+	}
+
+	prev.IdempotencyKey = t.IdempotencyKey
+	if !prev.SameRequest(t) {
+		return model.TransferResult{}, true, customErrors.IdempotencyKeyReused
+	}
+
+	res.From.ID, res.To.ID = t.From, t.To
+	res.Replayed = true
+	for _, acc := range []*model.Account{&res.From, &res.To} {
+		err = q.QueryRowContext(c, "SELECT user_id, currency, overdraft_limit FROM accounts WHERE id = $1", acc.ID).Scan(&acc.UserID, &acc.Currency, &acc.OverdraftLimit)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return model.TransferResult{}, true, err
+		}
+	}
+
+	return res, true, nil
+}
+
+// applyDelta changes the balance by delta unless a withdrawal would take it below the overdraft limit
+func applyDelta(c context.Context, tx *sql.Tx, id uuid.UUID, delta int64) error {
+	result, err := tx.ExecContext(c, "UPDATE accounts SET balance = balance + $1, version = version + 1 WHERE id = $2 AND ($1 >= 0 OR balance + $1 >= -overdraft_limit)", delta, id)
+	if err != nil {
+		return err
+	}
+
+	if err = checkAffected(result); !errors.Is(err, customErrors.NotFound) {
+		return err
+	}
+
+	if _, err = getAccount(c, tx, id); err != nil {
+		return err
+	}
+
+	return customErrors.InsufficientFunds
+}
+
+func getAccount(c context.Context, q queryer, id uuid.UUID) (model.Account, error) {
+	var acc model.Account
+	err := q.QueryRowContext(c, "SELECT id, user_id, currency, balance, overdraft_limit, version FROM accounts WHERE id = $1", id).Scan(&acc.ID, &acc.UserID, &acc.Currency, &acc.Balance, &acc.OverdraftLimit, &acc.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Account{}, customErrors.NotFound
+	}
+	if err != nil {
+		return model.Account{}, err
+	}
+
+	return acc, nil
+}
+
+func checkAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customErrors.NotFound
+	}
+
+	return nil
+}
+
+// isUniqueViolation tells whether a unique index rejected the statement, on postgres and sqlite
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func scanAccounts(rows *sql.Rows) ([]model.Account, error) {
+	defer rows.Close()
+
+	var res []model.Account
+	for rows.Next() {
+		var acc model.Account
+		if err := rows.Scan(&acc.ID, &acc.UserID, &acc.Currency, &acc.Balance, &acc.OverdraftLimit, &acc.Version); err != nil {
+			return nil, err
+		}
+		res = append(res, acc)
+	}
+
+	return res, rows.Err()
+}
@@ -0,0 +1,319 @@
+package newStorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+const (
+	tokenActive  = "active"
+	tokenUsed    = "used"
+	tokenRevoked = "revoked"
+)
+
+// TokenStore keeps refresh token families and the denylist of revoked access tokens
+type TokenStore interface {
+	CreateRefreshToken(context.Context, model.RefreshToken) error
+	// UseRefreshToken marks the token used, a token used before is returned with customErrors.TokenReused
+	UseRefreshToken(context.Context, string) (model.RefreshToken, error)
+	// RevokeFamily revokes every refresh token of the family and denylists their access tokens
+	RevokeFamily(context.Context, uuid.UUID) error
+	// RevokeName revokes every refresh token issued to the name and denylists their access tokens
+	RevokeName(context.Context, string) error
+	RevokeAccessToken(context.Context, model.RevokedToken) error
+	ListRevokedTokens(context.Context) ([]model.RevokedToken, error)
+}
+
+type refreshRecord struct {
+	token model.RefreshToken
+	state string
+}
+
+func (sdb *StorageDB) CreateRefreshToken(c context.Context, token model.RefreshToken) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "CreateRefreshToken")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "CreateRefreshToken")
+
+	if _, ok := sdb.refreshTokens[token.Hash]; ok {
+		return customErrors.AlreadyExists
+	}
+
+	sdb.refreshTokens[token.Hash] = refreshRecord{token: token, state: tokenActive}
+
+	return nil
+}
+
+func (sdb *StorageDB) UseRefreshToken(c context.Context, hash string) (model.RefreshToken, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "UseRefreshToken")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "UseRefreshToken")
+
+	rec, ok := sdb.refreshTokens[hash]
+	if !ok {
+		return model.RefreshToken{}, customErrors.NotFound
+	}
+
+	if err := checkRefreshState(rec.token, rec.state); err != nil {
+		return rec.token, err
+	}
+
+	rec.state = tokenUsed
+	sdb.refreshTokens[hash] = rec
+
+	return rec.token, nil
+}
+
+func (sdb *StorageDB) RevokeFamily(c context.Context, family uuid.UUID) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "RevokeFamily")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "RevokeFamily")
+
+	for hash, rec := range sdb.refreshTokens {
+		if rec.token.Family != family {
+			continue
+		}
+		rec.state = tokenRevoked
+		sdb.refreshTokens[hash] = rec
+		if rec.token.AccessExpiresAt.After(time.Now()) {
+			sdb.revoked[rec.token.AccessJTI] = rec.token.AccessExpiresAt
+		}
+	}
+
+	return nil
+}
+
+func (sdb *StorageDB) RevokeName(c context.Context, name string) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "RevokeName")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "RevokeName")
+
+	for hash, rec := range sdb.refreshTokens {
+		if rec.token.Name != name {
+			continue
+		}
+		rec.state = tokenRevoked
+		sdb.refreshTokens[hash] = rec
+		if rec.token.AccessExpiresAt.After(time.Now()) {
+			sdb.revoked[rec.token.AccessJTI] = rec.token.AccessExpiresAt
+		}
+	}
+
+	return nil
+}
+
+func (sdb *StorageDB) RevokeAccessToken(c context.Context, token model.RevokedToken) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "RevokeAccessToken")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "RevokeAccessToken")
+
+	sdb.revoked[token.JTI] = token.ExpiresAt
+
+	return nil
+}
+
+func (sdb *StorageDB) ListRevokedTokens(c context.Context) ([]model.RevokedToken, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "ListRevokedTokens")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "ListRevokedTokens")
+
+	res := []model.RevokedToken{}
+	for jti, expiresAt := range sdb.revoked {
+		// expired tokens are rejected anyway
+		if !expiresAt.After(time.Now()) {
+			delete(sdb.revoked, jti)
+			continue
+		}
+		res = append(res, model.RevokedToken{JTI: jti, ExpiresAt: expiresAt})
+	}
+
+	return res, nil
+}
+
+func (s *StorageSQL) CreateRefreshToken(c context.Context, token model.RefreshToken) error {
+	c, span := s.startSpan(c, "CreateRefreshToken")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "CreateRefreshToken")
+
+	_, err := s.db.ExecContext(c, `INSERT INTO refresh_tokens (hash, family, name, state, expires_at, access_jti, access_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		token.Hash, token.Family, token.Name, tokenActive, token.ExpiresAt.UnixNano(), token.AccessJTI, token.AccessExpiresAt.UnixNano())
+
+	return err
+}
+
+func (s *StorageSQL) UseRefreshToken(c context.Context, hash string) (model.RefreshToken, error) {
+	c, span := s.startSpan(c, "UseRefreshToken")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "UseRefreshToken")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var (
+		token                      model.RefreshToken
+		state                      string
+		expiresAt, accessExpiresAt int64
+	)
+	err = tx.QueryRowContext(c, "SELECT hash, family, name, state, expires_at, access_jti, access_expires_at FROM refresh_tokens WHERE hash = $1", hash).
+		Scan(&token.Hash, &token.Family, &token.Name, &state, &expiresAt, &token.AccessJTI, &accessExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.RefreshToken{}, customErrors.NotFound
+	}
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+	token.ExpiresAt = time.Unix(0, expiresAt).UTC()
+	token.AccessExpiresAt = time.Unix(0, accessExpiresAt).UTC()
+
+	if err = checkRefreshState(token, state); err != nil {
+		return token, err
+	}
+
+	// the state condition makes a concurrent use of the same token lose
+	result, err := tx.ExecContext(c, "UPDATE refresh_tokens SET state = $1 WHERE hash = $2 AND state = $3", tokenUsed, hash, tokenActive)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+	if err = checkAffected(result); err != nil {
+		return token, customErrors.TokenReused
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+func (s *StorageSQL) RevokeFamily(c context.Context, family uuid.UUID) error {
+	c, span := s.startSpan(c, "RevokeFamily")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "RevokeFamily")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	_, err = tx.ExecContext(c, `INSERT INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_tokens WHERE family = $1 AND access_expires_at > $2
+		ON CONFLICT DO NOTHING`, family, time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(c, "UPDATE refresh_tokens SET state = $1 WHERE family = $2", tokenRevoked, family); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *StorageSQL) RevokeName(c context.Context, name string) error {
+	c, span := s.startSpan(c, "RevokeName")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "RevokeName")
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	_, err = tx.ExecContext(c, `INSERT INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_tokens WHERE name = $1 AND access_expires_at > $2
+		ON CONFLICT DO NOTHING`, name, time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(c, "UPDATE refresh_tokens SET state = $1 WHERE name = $2", tokenRevoked, name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *StorageSQL) RevokeAccessToken(c context.Context, token model.RevokedToken) error {
+	c, span := s.startSpan(c, "RevokeAccessToken")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "RevokeAccessToken")
+
+	_, err := s.db.ExecContext(c, "INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT DO NOTHING", token.JTI, token.ExpiresAt.UnixNano())
+
+	return err
+}
+
+func (s *StorageSQL) ListRevokedTokens(c context.Context) ([]model.RevokedToken, error) {
+	c, span := s.startSpan(c, "ListRevokedTokens")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "ListRevokedTokens")
+
+	now := time.Now().UnixNano()
+
+	// expired tokens are rejected anyway
+	if _, err := s.db.ExecContext(c, "DELETE FROM revoked_tokens WHERE expires_at <= $1", now); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(c, "SELECT jti, expires_at FROM revoked_tokens")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []model.RevokedToken{}
+	for rows.Next() {
+		var (
+			token     model.RevokedToken
+			expiresAt int64
+		)
+		if err = rows.Scan(&token.JTI, &expiresAt); err != nil {
+			return nil, err
+		}
+		token.ExpiresAt = time.Unix(0, expiresAt).UTC()
+		res = append(res, token)
+	}
+
+	return res, rows.Err()
+}
+
+func checkRefreshState(token model.RefreshToken, state string) error {
+	switch {
+	case state == tokenUsed:
+		return customErrors.TokenReused
+	case state == tokenRevoked || !token.ExpiresAt.After(time.Now()):
+		return customErrors.InvalidToken
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package newStorage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/stasBigunenko/monorepa/pkg/storage/newStorage")
+
+// startSpan starts the span of a storage operation, the caller ends it
+func (sdb *StorageDB) startSpan(c context.Context, method string) (context.Context, trace.Span) {
+	sdb.operations.WithLabelValues(method).Inc()
+	return tracer.Start(c, "StorageDB."+method, trace.WithAttributes(semconv.DBSystemKey.String("memory")))
+}
+
+func (s *StorageSQL) startSpan(c context.Context, method string) (context.Context, trace.Span) {
+	return tracer.Start(c, "StorageSQL."+method, trace.WithAttributes(semconv.DBSystemKey.String(s.system)))
+}
+
+// dbSystem is the db.system attribute of the database behind a driver
+func dbSystem(driver string) string {
+	switch driver {
+	case PostgresStorage:
+		return semconv.DBSystemPostgreSQL.Value.AsString()
+	case "sqlite3":
+		return semconv.DBSystemSqlite.Value.AsString()
+	}
+
+	return driver
+}
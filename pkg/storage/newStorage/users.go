@@ -0,0 +1,221 @@
+package newStorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+func (sdb *StorageDB) GetUser(c context.Context, id uuid.UUID) (model.UserHTTP, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "GetUser")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "GetUser")
+
+	user, ok := sdb.users[id]
+	if !ok {
+		return model.UserHTTP{}, customErrors.NotFound
+	}
+
+	return user, nil
+}
+
+func (sdb *StorageDB) CreateUser(c context.Context, user model.UserHTTP) (model.UserHTTP, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "CreateUser")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "CreateUser")
+
+	user.ID = uuid.New()
+	user.Version = 1
+	sdb.users[user.ID] = user
+
+	return user, nil
+}
+
+func (sdb *StorageDB) UpdateUser(c context.Context, user model.UserHTTP) (model.UserHTTP, error) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "UpdateUser")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "UpdateUser")
+
+	stored, ok := sdb.users[user.ID]
+	if !ok {
+		return model.UserHTTP{}, customErrors.NotFound
+	}
+
+	if user.Version != 0 && user.Version != stored.Version {
+		return model.UserHTTP{}, customErrors.VersionMismatch
+	}
+	user.Version = stored.Version + 1
+	sdb.users[user.ID] = user
+
+	return user, nil
+}
+
+func (sdb *StorageDB) DeleteUser(c context.Context, id uuid.UUID) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "DeleteUser")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "DeleteUser")
+
+	_, active := sdb.users[id]
+	_, deleted := sdb.deletedUsers[id]
+	if !active && !deleted {
+		return customErrors.NotFound
+	}
+
+	delete(sdb.users, id)
+	delete(sdb.deletedUsers, id)
+
+	return nil
+}
+
+func (sdb *StorageDB) SoftDeleteUser(c context.Context, id uuid.UUID) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "SoftDeleteUser")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "SoftDeleteUser")
+
+	user, ok := sdb.users[id]
+	if !ok {
+		return customErrors.NotFound
+	}
+
+	delete(sdb.users, id)
+	sdb.deletedUsers[id] = user
+
+	return nil
+}
+
+func (sdb *StorageDB) RestoreUser(c context.Context, id uuid.UUID) error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	c, span := sdb.startSpan(c, "RestoreUser")
+	defer span.End()
+	sdb.logger.Debug(c, "command received", "method", "RestoreUser")
+
+	user, ok := sdb.deletedUsers[id]
+	if !ok {
+		return customErrors.NotFound
+	}
+
+	delete(sdb.deletedUsers, id)
+	sdb.users[id] = user
+
+	return nil
+}
+
+func (s *StorageSQL) GetUser(c context.Context, id uuid.UUID) (model.UserHTTP, error) {
+	c, span := s.startSpan(c, "GetUser")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "GetUser")
+
+	var user model.UserHTTP
+	err := s.db.QueryRowContext(c, "SELECT id, name, version FROM users WHERE id = $1 AND deleted_at IS NULL", id).Scan(&user.ID, &user.Name, &user.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.UserHTTP{}, customErrors.NotFound
+	}
+	if err != nil {
+		return model.UserHTTP{}, err
+	}
+
+	return user, nil
+}
+
+func (s *StorageSQL) CreateUser(c context.Context, user model.UserHTTP) (model.UserHTTP, error) {
+	c, span := s.startSpan(c, "CreateUser")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "CreateUser")
+
+	user.ID = uuid.New()
+	user.Version = 1
+	if _, err := s.db.ExecContext(c, "INSERT INTO users (id, name, version) VALUES ($1, $2, $3)", user.ID, user.Name, user.Version); err != nil {
+		return model.UserHTTP{}, err
+	}
+
+	return user, nil
+}
+
+func (s *StorageSQL) UpdateUser(c context.Context, user model.UserHTTP) (model.UserHTTP, error) {
+	c, span := s.startSpan(c, "UpdateUser")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "UpdateUser")
+
+	query := "UPDATE users SET name = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL"
+	args := []interface{}{user.Name, user.ID}
+	if user.Version != 0 {
+		query += " AND version = $3"
+		args = append(args, user.Version)
+	}
+
+	err := s.db.QueryRowContext(c, query+" RETURNING version", args...).Scan(&user.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		// tell a stale version from a missing user
+		if _, err = s.GetUser(c, user.ID); err != nil {
+			return model.UserHTTP{}, err
+		}
+		return model.UserHTTP{}, customErrors.VersionMismatch
+	}
+	if err != nil {
+		return model.UserHTTP{}, err
+	}
+
+	return user, nil
+}
+
+func (s *StorageSQL) DeleteUser(c context.Context, id uuid.UUID) error {
+	c, span := s.startSpan(c, "DeleteUser")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "DeleteUser")
+
+	result, err := s.db.ExecContext(c, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+func (s *StorageSQL) SoftDeleteUser(c context.Context, id uuid.UUID) error {
+	c, span := s.startSpan(c, "SoftDeleteUser")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "SoftDeleteUser")
+
+	result, err := s.db.ExecContext(c, "UPDATE users SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL", time.Now().UnixNano(), id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+func (s *StorageSQL) RestoreUser(c context.Context, id uuid.UUID) error {
+	c, span := s.startSpan(c, "RestoreUser")
+	defer span.End()
+	s.logger.Debug(c, "command received", "method", "RestoreUser")
+
+	result, err := s.db.ExecContext(c, "UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
@@ -3,69 +3,36 @@ package usergrpccontroller
 import (
 	"context"
 	"fmt"
-	log "github.com/sirupsen/logrus"
-	"google.golang.org/grpc/metadata"
 
 	"github.com/google/uuid"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/emptypb"
 
 	customerrors "github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/model"
 	pb "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 )
 
-type LoggingService interface {
-	WriteLog(ctx context.Context, message string)
-}
-
 type UserGRPCСontroller struct {
-	client         pb.UserGRPCServiceClient
-	loggingService LoggingService
+	client pb.UserGRPCServiceClient
+	logger loggingservice.Logger
 }
 
-func New(cli pb.UserGRPCServiceClient, loggingService LoggingService) *UserGRPCСontroller {
+func New(cli pb.UserGRPCServiceClient, logger loggingservice.Logger) *UserGRPCСontroller {
 	return &UserGRPCСontroller{
-		client:         cli,
-		loggingService: loggingService,
-	}
-}
-
-func (s UserGRPCСontroller) formatError(err error, message string) error {
-	st, ok := status.FromError(err)
-	if !ok {
-		return fmt.Errorf("%s, failed to parse status or not a grpc error type: %w", message, err)
+		client: cli,
+		logger: logger.With("component", "grpc client"),
 	}
-
-	switch st.Code() {
-	case codes.NotFound:
-		return fmt.Errorf("%s: %w", message, customerrors.NotFound)
-	case codes.AlreadyExists:
-		return fmt.Errorf("%s: %w", message, customerrors.AlreadyExists)
-	case codes.DeadlineExceeded:
-		return fmt.Errorf("%s: %w", message, customerrors.DeadlineExceeded)
-	}
-
-	return fmt.Errorf("%s: %s", message, err.Error())
 }
 
 func (s UserGRPCСontroller) CreateUser(ctx context.Context, name string) (uuid.UUID, error) {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command CreateUser received...")
-
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
-	}
+	s.logger.Debug(ctx, "command received", "method", "CreateUser")
 
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
-
-	resp, err := s.client.Create(c, &pb.Name{
+	resp, err := s.client.Create(ctx, &pb.Name{
 		Name: name,
 	})
 
 	if err != nil {
-		return uuid.Nil, s.formatError(err, "failed to create user")
+		return uuid.Nil, customerrors.FromStatus(err, "failed to create user")
 	}
 
 	userID, err := uuid.Parse(resp.Id)
@@ -77,21 +44,14 @@ func (s UserGRPCСontroller) CreateUser(ctx context.Context, name string) (uuid.
 }
 
 func (s UserGRPCСontroller) GetUser(ctx context.Context, id uuid.UUID) (model.UserHTTP, error) {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command GetUser received...")
-
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
-	}
+	s.logger.Debug(ctx, "command received", "method", "GetUser")
 
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
-
-	resp, err := s.client.Get(c, &pb.Id{
+	resp, err := s.client.Get(ctx, &pb.Id{
 		Id: id.String(),
 	})
 
 	if err != nil {
-		return model.UserHTTP{}, s.formatError(err, "failed to get user")
+		return model.UserHTTP{}, customerrors.FromStatus(err, "failed to get user")
 	}
 
 	userID, err := uuid.Parse(resp.Id)
@@ -100,80 +60,104 @@ func (s UserGRPCСontroller) GetUser(ctx context.Context, id uuid.UUID) (model.U
 	}
 
 	return model.UserHTTP{
-		ID:   userID,
-		Name: resp.Name,
+		ID:      userID,
+		Name:    resp.Name,
+		Version: resp.Version,
 	}, nil
 }
 
-func (s UserGRPCСontroller) GetAllUsers(ctx context.Context) ([]model.UserHTTP, error) {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command GetAllUsers received...")
-
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
-	}
-
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
+func (s UserGRPCСontroller) GetAllUsers(ctx context.Context, filter model.UserFilter) (model.UserPage, error) {
+	s.logger.Debug(ctx, "command received", "method", "GetAllUsers")
 
-	resp, err := s.client.GetAllUsers(c, &emptypb.Empty{})
+	resp, err := s.client.GetAllUsers(ctx, &pb.UserFilter{
+		NamePrefix: filter.NamePrefix,
+		Sort:       filter.Sort,
+		PageSize:   int32(filter.PageSize),
+		PageToken:  filter.PageToken,
+	})
 	if err != nil {
-		return nil, s.formatError(err, "failed to get all users")
+		return model.UserPage{}, customerrors.FromStatus(err, "failed to get all users")
 	}
 
 	users := []model.UserHTTP{}
 	for _, user := range resp.AllUsers {
 		id, err := uuid.Parse(user.Id)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse user ID: %s, %w", err.Error(), customerrors.ParseError)
+			return model.UserPage{}, fmt.Errorf("failed to parse user ID: %s, %w", err.Error(), customerrors.ParseError)
 		}
 
 		users = append(users, model.UserHTTP{
-			ID:   id,
-			Name: user.Name,
+			ID:      id,
+			Name:    user.Name,
+			Version: user.Version,
 		})
 	}
 
-	return users, nil
+	return model.UserPage{Users: users, NextPageToken: resp.NextPageToken}, nil
 }
 
-func (s UserGRPCСontroller) UpdateUser(ctx context.Context, user model.UserHTTP) error {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command UpdateUser received...")
+func (s UserGRPCСontroller) UpdateUser(ctx context.Context, user model.UserHTTP) (model.UserHTTP, error) {
+	s.logger.Debug(ctx, "command received", "method", "UpdateUser")
+
+	resp, err := s.client.Update(ctx, &pb.User{
+		Id:      user.ID.String(),
+		Name:    user.Name,
+		Version: user.Version,
+	})
+
+	if err != nil {
+		return model.UserHTTP{}, customerrors.FromStatus(err, "failed to update user")
+	}
 
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
+	userID, err := uuid.Parse(resp.Id)
+	if err != nil {
+		return model.UserHTTP{}, fmt.Errorf("failed to parse user ID: %s, %w", err.Error(), customerrors.ParseError)
 	}
 
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
+	return model.UserHTTP{
+		ID:      userID,
+		Name:    resp.Name,
+		Version: resp.Version,
+	}, nil
+}
+
+func (s UserGRPCСontroller) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.logger.Debug(ctx, "command received", "method", "DeleteUser")
 
-	_, err := s.client.Update(c, &pb.User{
-		Id:   user.ID.String(),
-		Name: user.Name,
+	_, err := s.client.Delete(ctx, &pb.Id{
+		Id: id.String(),
 	})
 
 	if err != nil {
-		return s.formatError(err, "failed to update user")
+		return customerrors.FromStatus(err, "failed to delete user")
 	}
 
 	return nil
 }
 
-func (s UserGRPCСontroller) DeleteUser(ctx context.Context, id uuid.UUID) error {
-	s.loggingService.WriteLog(ctx, "GRPC Client: Command DeleteUser received...")
+func (s UserGRPCСontroller) SoftDeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.logger.Debug(ctx, "command received", "method", "SoftDeleteUser")
+
+	_, err := s.client.SoftDelete(ctx, &pb.Id{
+		Id: id.String(),
+	})
 
-	contextID, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
+	if err != nil {
+		return customerrors.FromStatus(err, "failed to soft delete user")
 	}
 
-	c := metadata.AppendToOutgoingContext(ctx, "requestid", contextID)
+	return nil
+}
+
+func (s UserGRPCСontroller) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	s.logger.Debug(ctx, "command received", "method", "RestoreUser")
 
-	_, err := s.client.Delete(c, &pb.Id{
+	_, err := s.client.Restore(ctx, &pb.Id{
 		Id: id.String(),
 	})
 
 	if err != nil {
-		return s.formatError(err, "failed to delete user")
+		return customerrors.FromStatus(err, "failed to restore user")
 	}
 
 	return nil
@@ -10,19 +10,15 @@ import (
 	mocks "github.com/stasBigunenko/monorepa/mocks/pkg/userGRPC/proto"
 	"github.com/stasBigunenko/monorepa/model"
 	pb "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
-type MockLoggingService struct {
-}
-
-func (MockLoggingService) WriteLog(ctx context.Context, message string) {}
-
 func TestUserGRPCСontroller_CreateUser(t *testing.T) {
 	type fields struct {
-		client         pb.UserGRPCServiceClient
-		loggingService LoggingService
+		client pb.UserGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	type args struct {
 		name string
@@ -75,8 +71,8 @@ func TestUserGRPCСontroller_CreateUser(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := UserGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
 			got, err := s.CreateUser(context.Background(), tt.args.name)
 			if (err != nil) != tt.wantErr {
@@ -92,8 +88,8 @@ func TestUserGRPCСontroller_CreateUser(t *testing.T) {
 
 func TestUserGRPCСontroller_GetUser(t *testing.T) {
 	type fields struct {
-		client         pb.UserGRPCServiceClient
-		loggingService LoggingService
+		client pb.UserGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	type args struct {
 		id uuid.UUID
@@ -148,8 +144,8 @@ func TestUserGRPCСontroller_GetUser(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := UserGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
 			got, err := s.GetUser(context.Background(), tt.args.id)
 			if (err != nil) != tt.wantErr {
@@ -165,21 +161,25 @@ func TestUserGRPCСontroller_GetUser(t *testing.T) {
 
 func TestUserGRPCСontroller_GetAllUsers(t *testing.T) {
 	type fields struct {
-		client         pb.UserGRPCServiceClient
-		loggingService LoggingService
+		client pb.UserGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	tests := []struct {
 		name    string
 		fields  fields
-		want    []model.UserHTTP
+		want    model.UserPage
 		wantErr bool
 	}{
 		{
 			name: "GetAllUsers OK",
 			fields: fields{
 				client: mocks.MockUserGrpcServiceClient{
-					MockGetAllUsers: func(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*pb.AllUsers, error) {
+					MockGetAllUsers: func(ctx context.Context, in *pb.UserFilter, opts ...grpc.CallOption) (*pb.AllUsers, error) {
+						if in.NamePrefix != "Bo" || in.PageSize != 1 {
+							return nil, errors.New("filter not passed")
+						}
 						it := pb.AllUsers{
+							NextPageToken: "next",
 							AllUsers: []*pb.User{
 								{
 									Id:   "00000000-0000-0000-0000-000000000000",
@@ -191,11 +191,14 @@ func TestUserGRPCСontroller_GetAllUsers(t *testing.T) {
 					},
 				},
 			},
-			want: []model.UserHTTP{
-				{
-					ID:   uuid.MustParse("00000000-0000-0000-0000-000000000000"),
-					Name: "Boris",
+			want: model.UserPage{
+				Users: []model.UserHTTP{
+					{
+						ID:   uuid.MustParse("00000000-0000-0000-0000-000000000000"),
+						Name: "Boris",
+					},
 				},
+				NextPageToken: "next",
 			},
 			wantErr: false,
 		},
@@ -203,22 +206,22 @@ func TestUserGRPCСontroller_GetAllUsers(t *testing.T) {
 			name: "GetAllUSers !OK",
 			fields: fields{
 				client: mocks.MockUserGrpcServiceClient{
-					MockGetAllUsers: func(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*pb.AllUsers, error) {
+					MockGetAllUsers: func(ctx context.Context, in *pb.UserFilter, opts ...grpc.CallOption) (*pb.AllUsers, error) {
 						return &pb.AllUsers{}, errors.New("err")
 					},
 				},
 			},
-			want:    nil,
+			want:    model.UserPage{},
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := UserGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
-			got, err := s.GetAllUsers(context.Background())
+			got, err := s.GetAllUsers(context.Background(), model.UserFilter{NamePrefix: "Bo", PageSize: 1})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("UserGRPCСontroller.GetAllUsers() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -232,8 +235,8 @@ func TestUserGRPCСontroller_GetAllUsers(t *testing.T) {
 
 func TestUserGRPCСontroller_UpdateUser(t *testing.T) {
 	type fields struct {
-		client         pb.UserGRPCServiceClient
-		loggingService LoggingService
+		client pb.UserGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	type args struct {
 		user model.UserHTTP
@@ -283,10 +286,10 @@ func TestUserGRPCСontroller_UpdateUser(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := UserGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
-			if err := s.UpdateUser(context.Background(), tt.args.user); (err != nil) != tt.wantErr {
+			if _, err := s.UpdateUser(context.Background(), tt.args.user); (err != nil) != tt.wantErr {
 				t.Errorf("UserGRPCСontroller.UpdateUser() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -295,8 +298,8 @@ func TestUserGRPCСontroller_UpdateUser(t *testing.T) {
 
 func TestUserGRPCСontroller_DeleteUser(t *testing.T) {
 	type fields struct {
-		client         pb.UserGRPCServiceClient
-		loggingService LoggingService
+		client pb.UserGRPCServiceClient
+		logger loggingservice.Logger
 	}
 	type args struct {
 		id uuid.UUID
@@ -340,8 +343,8 @@ func TestUserGRPCСontroller_DeleteUser(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := UserGRPCСontroller{
-				client:         tt.fields.client,
-				loggingService: MockLoggingService{},
+				client: tt.fields.client,
+				logger: loggingservice.LoggingService{},
 			}
 			if err := s.DeleteUser(context.Background(), tt.args.id); (err != nil) != tt.wantErr {
 				t.Errorf("UserGRPCСontroller.DeleteUser() error = %v, wantErr %v", err, tt.wantErr)
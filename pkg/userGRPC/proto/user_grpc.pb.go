@@ -20,10 +20,12 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type UserGRPCServiceClient interface {
 	Get(ctx context.Context, in *Id, opts ...grpc.CallOption) (*User, error)
-	GetAllUsers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*AllUsers, error)
+	GetAllUsers(ctx context.Context, in *UserFilter, opts ...grpc.CallOption) (*AllUsers, error)
 	Create(ctx context.Context, in *Name, opts ...grpc.CallOption) (*User, error)
 	Update(ctx context.Context, in *User, opts ...grpc.CallOption) (*User, error)
 	Delete(ctx context.Context, in *Id, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	SoftDelete(ctx context.Context, in *Id, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Restore(ctx context.Context, in *Id, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type userGRPCServiceClient struct {
@@ -43,7 +45,7 @@ func (c *userGRPCServiceClient) Get(ctx context.Context, in *Id, opts ...grpc.Ca
 	return out, nil
 }
 
-func (c *userGRPCServiceClient) GetAllUsers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*AllUsers, error) {
+func (c *userGRPCServiceClient) GetAllUsers(ctx context.Context, in *UserFilter, opts ...grpc.CallOption) (*AllUsers, error) {
 	out := new(AllUsers)
 	err := c.cc.Invoke(ctx, "/userGRPC.UserGRPCService/GetAllUsers", in, out, opts...)
 	if err != nil {
@@ -79,15 +81,35 @@ func (c *userGRPCServiceClient) Delete(ctx context.Context, in *Id, opts ...grpc
 	return out, nil
 }
 
+func (c *userGRPCServiceClient) SoftDelete(ctx context.Context, in *Id, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/userGRPC.UserGRPCService/SoftDelete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userGRPCServiceClient) Restore(ctx context.Context, in *Id, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/userGRPC.UserGRPCService/Restore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UserGRPCServiceServer is the server API for UserGRPCService service.
 // All implementations must embed UnimplementedUserGRPCServiceServer
 // for forward compatibility
 type UserGRPCServiceServer interface {
 	Get(context.Context, *Id) (*User, error)
-	GetAllUsers(context.Context, *emptypb.Empty) (*AllUsers, error)
+	GetAllUsers(context.Context, *UserFilter) (*AllUsers, error)
 	Create(context.Context, *Name) (*User, error)
 	Update(context.Context, *User) (*User, error)
 	Delete(context.Context, *Id) (*emptypb.Empty, error)
+	SoftDelete(context.Context, *Id) (*emptypb.Empty, error)
+	Restore(context.Context, *Id) (*emptypb.Empty, error)
 	mustEmbedUnimplementedUserGRPCServiceServer()
 }
 
@@ -98,7 +120,7 @@ type UnimplementedUserGRPCServiceServer struct {
 func (UnimplementedUserGRPCServiceServer) Get(context.Context, *Id) (*User, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
 }
-func (UnimplementedUserGRPCServiceServer) GetAllUsers(context.Context, *emptypb.Empty) (*AllUsers, error) {
+func (UnimplementedUserGRPCServiceServer) GetAllUsers(context.Context, *UserFilter) (*AllUsers, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetAllUsers not implemented")
 }
 func (UnimplementedUserGRPCServiceServer) Create(context.Context, *Name) (*User, error) {
@@ -110,6 +132,12 @@ func (UnimplementedUserGRPCServiceServer) Update(context.Context, *User) (*User,
 func (UnimplementedUserGRPCServiceServer) Delete(context.Context, *Id) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedUserGRPCServiceServer) SoftDelete(context.Context, *Id) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SoftDelete not implemented")
+}
+func (UnimplementedUserGRPCServiceServer) Restore(context.Context, *Id) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
 func (UnimplementedUserGRPCServiceServer) mustEmbedUnimplementedUserGRPCServiceServer() {}
 
 // UnsafeUserGRPCServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -142,7 +170,7 @@ func _UserGRPCService_Get_Handler(srv interface{}, ctx context.Context, dec func
 }
 
 func _UserGRPCService_GetAllUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(emptypb.Empty)
+	in := new(UserFilter)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -154,7 +182,7 @@ func _UserGRPCService_GetAllUsers_Handler(srv interface{}, ctx context.Context,
 		FullMethod: "/userGRPC.UserGRPCService/GetAllUsers",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserGRPCServiceServer).GetAllUsers(ctx, req.(*emptypb.Empty))
+		return srv.(UserGRPCServiceServer).GetAllUsers(ctx, req.(*UserFilter))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -213,6 +241,42 @@ func _UserGRPCService_Delete_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserGRPCService_SoftDelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Id)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserGRPCServiceServer).SoftDelete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userGRPC.UserGRPCService/SoftDelete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserGRPCServiceServer).SoftDelete(ctx, req.(*Id))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserGRPCService_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Id)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserGRPCServiceServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userGRPC.UserGRPCService/Restore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserGRPCServiceServer).Restore(ctx, req.(*Id))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // UserGRPCService_ServiceDesc is the grpc.ServiceDesc for UserGRPCService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -240,6 +304,14 @@ var UserGRPCService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _UserGRPCService_Delete_Handler,
 		},
+		{
+			MethodName: "SoftDelete",
+			Handler:    _UserGRPCService_SoftDelete_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _UserGRPCService_Restore_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "user.proto",
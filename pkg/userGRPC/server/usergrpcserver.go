@@ -2,184 +2,162 @@ package usergrpcserver
 
 import (
 	"context"
-	"errors"
 	"github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
 	"github.com/stasBigunenko/monorepa/customErrors"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/stasBigunenko/monorepa/model"
 	pb "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 	"github.com/stasBigunenko/monorepa/service/user"
 )
 
 // Server userGRPC
 
-type LoggingService interface {
-	WriteLog(ctx context.Context, message string)
-}
-
 type UserServerGRPC struct {
 	pb.UnimplementedUserGRPCServiceServer
 
-	service        user.User
-	loggingService LoggingService
+	service user.User
+	logger  loggingservice.Logger
 }
 
-func NewUsersGRPCServer(s user.User, loggingService LoggingService) UserServerGRPC {
+func NewUsersGRPCServer(s user.User, logger loggingservice.Logger) UserServerGRPC {
 	return UserServerGRPC{
-		service:        s,
-		loggingService: loggingService,
+		service: s,
+		logger:  logger.With("component", "grpc server"),
 	}
 }
 
 func (s UserServerGRPC) Get(c context.Context, in *pb.Id) (*pb.User, error) {
-
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
-	}
-
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
-	}
-
-	s.loggingService.WriteLog(c, "GRPC Server: Command Get received...")
+	s.logger.Debug(c, "command received", "method", "Get")
 
 	id, err := uuid.Parse(in.Id)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to parse uuid in grpc server")
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
 	res, err := s.service.Get(c, id)
 	if err != nil {
-		if errors.Is(err, customErrors.NotFound) {
-			return nil, status.Error(codes.NotFound, "not found")
-		}
-		return nil, status.Error(codes.Internal, "internal storage problem")
+		return nil, customErrors.ToStatus(err, "internal storage problem")
 	}
 
 	return &pb.User{
-		Id:   res.ID.String(),
-		Name: res.Name,
+		Id:      res.ID.String(),
+		Name:    res.Name,
+		Version: res.Version,
 	}, nil
 }
-func (s UserServerGRPC) GetAllUsers(c context.Context, in *emptypb.Empty) (*pb.AllUsers, error) {
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
-	}
-
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
-	}
-
-	s.loggingService.WriteLog(c, "GRPC Server: Command GetAllUsers received...")
-
-	users, err := s.service.GetAll(c)
+func (s UserServerGRPC) GetAllUsers(c context.Context, in *pb.UserFilter) (*pb.AllUsers, error) {
+	s.logger.Debug(c, "command received", "method", "GetAllUsers")
+
+	page, err := s.service.List(c, model.UserFilter{
+		NamePrefix: in.NamePrefix,
+		Sort:       in.Sort,
+		PageSize:   int(in.PageSize),
+		PageToken:  in.PageToken,
+	})
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to get the list of users")
+		return nil, customErrors.ToStatus(err, "failed to get the list of users")
 	}
 
 	pbAllUsers := []*pb.User{}
 
-	for _, val := range users {
+	for _, val := range page.Users {
 		pbAllUsers = append(pbAllUsers, &pb.User{
-			Id:   val.ID.String(),
-			Name: val.Name,
+			Id:      val.ID.String(),
+			Name:    val.Name,
+			Version: val.Version,
 		})
 	}
 	return &pb.AllUsers{
-		AllUsers: pbAllUsers,
+		AllUsers:      pbAllUsers,
+		NextPageToken: page.NextPageToken,
 	}, nil
 }
 
 func (s UserServerGRPC) Create(c context.Context, in *pb.Name) (*pb.User, error) {
-
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
-	}
-
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
-	}
-
-	s.loggingService.WriteLog(c, "GRPC Server: Command Create received...")
+	s.logger.Debug(c, "command received", "method", "Create")
 
 	res, err := s.service.Create(c, in.Name)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create user")
+		return nil, customErrors.ToStatus(err, "failed to create user")
 	}
 
 	return &pb.User{
-		Id:   res.ID.String(),
-		Name: res.Name,
+		Id:      res.ID.String(),
+		Name:    res.Name,
+		Version: res.Version,
 	}, nil
 }
 
 func (s UserServerGRPC) Update(c context.Context, in *pb.User) (*pb.User, error) {
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
-	}
-
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
-	}
-
-	s.loggingService.WriteLog(c, "GRPC Server: Command Update received...")
+	s.logger.Debug(c, "command received", "method", "Update")
 
 	id, err := uuid.Parse(in.Id)
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "failed to parse uuid")
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
 	m := model.UserHTTP{
-		ID:   id,
-		Name: in.Name,
+		ID:      id,
+		Name:    in.Name,
+		Version: in.Version,
 	}
 
 	res, err := s.service.Update(c, m)
 	if err != nil {
-		if errors.Is(err, customErrors.NotFound) {
-			return nil, status.Error(codes.NotFound, "not found")
-		}
-		return nil, status.Error(codes.Internal, "internal storage problem")
+		return nil, customErrors.ToStatus(err, "internal storage problem")
 	}
 
 	return &pb.User{
-		Id:   res.ID.String(),
-		Name: res.Name,
+		Id:      res.ID.String(),
+		Name:    res.Name,
+		Version: res.Version,
 	}, nil
 }
 func (s UserServerGRPC) Delete(c context.Context, in *pb.Id) (*emptypb.Empty, error) {
+	s.logger.Debug(c, "command received", "method", "Delete")
 
-	md, ok := metadata.FromIncomingContext(c)
-	if !ok {
-		log.Info("Cann't receive metada")
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
-	if ccc, ok := md["requestid"]; ok {
-		c = context.WithValue(context.Background(), model.ContextKeyRequestID, ccc[0])
+	err = s.service.Delete(c, id)
+	if err != nil {
+		return nil, customErrors.ToStatus(err, "internal storage problem")
 	}
 
-	s.loggingService.WriteLog(c, "GRPC Server: Command Delete received...")
+	return &emptypb.Empty{}, nil
+}
+
+func (s UserServerGRPC) SoftDelete(c context.Context, in *pb.Id) (*emptypb.Empty, error) {
+	s.logger.Debug(c, "command received", "method", "SoftDelete")
 
 	id, err := uuid.Parse(in.Id)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to parse uuid")
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
 	}
 
-	err = s.service.Delete(c, id)
+	err = s.service.SoftDelete(c, id)
+	if err != nil {
+		return nil, customErrors.ToStatus(err, "internal storage problem")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s UserServerGRPC) Restore(c context.Context, in *pb.Id) (*emptypb.Empty, error) {
+	s.logger.Debug(c, "command received", "method", "Restore")
+
+	id, err := uuid.Parse(in.Id)
+	if err != nil {
+		return nil, customErrors.ToStatus(customErrors.UUIDError, "failed to parse uuid in grpc server")
+	}
+
+	err = s.service.Restore(c, id)
 	if err != nil {
-		if errors.Is(err, customErrors.NotFound) {
-			return nil, status.Error(codes.NotFound, "not found")
-		}
-		return nil, status.Error(codes.Internal, "internal storage problem")
+		return nil, customErrors.ToStatus(err, "internal storage problem")
 	}
 
 	return &emptypb.Empty{}, nil
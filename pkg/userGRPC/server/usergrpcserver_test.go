@@ -13,19 +13,15 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/stasBigunenko/monorepa/customErrors"
 	userInt "github.com/stasBigunenko/monorepa/mocks/service/user"
 	"github.com/stasBigunenko/monorepa/model"
 	pb "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
 	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 )
 
-type MockLoggingService struct {
-}
-
-func (MockLoggingService) WriteLog(ctx context.Context, message string) {}
-
 func Test_Create(t *testing.T) {
-	loggingService := MockLoggingService{}
+	loggingService := loggingservice.LoggingService{}
 	ui := new(userInt.User)
 	s := "Andrew"
 	uuidS := "00000000-0000-0000-0000-000000000000"
@@ -61,12 +57,12 @@ func Test_Create(t *testing.T) {
 }
 
 func Test_Get(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(userInt.User)
 	uuidS := "00000000-0000-0000-0000-000000000000"
 	id, _ := uuid.Parse(uuidS)
 	m := model.UserHTTP{ID: id, Name: "Andrew"}
-	ui.On("Get", context.Background(), id).Return(m, nil)
+	ui.On("Get", mock.Anything, id).Return(m, nil)
 
 	ui2 := new(userInt.User)
 	m1 := model.UserHTTP{}
@@ -109,11 +105,11 @@ func Test_Get(t *testing.T) {
 }
 
 func TestUserService_Delete(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(userInt.User)
 	uuidS := "00000000-0000-0000-0000-000000000000"
 	id, _ := uuid.Parse(uuidS)
-	ui.On("Delete", context.Background(), id).Return(nil)
+	ui.On("Delete", mock.Anything, id).Return(nil)
 
 	tests := []struct {
 		name  string
@@ -149,7 +145,7 @@ func TestUserService_Delete(t *testing.T) {
 
 //
 func TestUserService_GetAllUsers(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(userInt.User)
 	id1 := uuid.New()
 	id2 := uuid.New()
@@ -164,12 +160,18 @@ func TestUserService_GetAllUsers(t *testing.T) {
 	u2 := &pb.User{Id: id2.String(), Name: m2.Name}
 	all := []*pb.User{u1, u2}
 	au := pb.AllUsers{
-		AllUsers: all,
+		AllUsers:      all,
+		NextPageToken: "next",
 	}
-	ui.On("GetAll", context.Background()).Return(m, nil)
+	filter := model.UserFilter{NamePrefix: "A", Sort: "name", PageSize: 2}
+	in := &pb.UserFilter{NamePrefix: "A", Sort: "name", PageSize: 2}
+	ui.On("List", mock.Anything, filter).Return(model.UserPage{Users: m, NextPageToken: "next"}, nil)
 
 	ui2 := new(userInt.User)
-	ui2.On("GetAll", context.Background()).Return(nil, errors.New("err"))
+	ui2.On("List", mock.Anything, filter).Return(model.UserPage{}, errors.New("err"))
+
+	ui3 := new(userInt.User)
+	ui3.On("List", mock.Anything, filter).Return(model.UserPage{}, customErrors.InvalidArgument)
 
 	tests := []struct {
 		name    string
@@ -187,13 +189,18 @@ func TestUserService_GetAllUsers(t *testing.T) {
 			stor:    ui2,
 			wantErr: codes.Internal,
 		},
+		{
+			name:    "Bad page token",
+			stor:    ui3,
+			wantErr: codes.InvalidArgument,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			u := NewUsersGRPCServer(tc.stor, loggingService)
-			got, err := u.GetAllUsers(context.Background(), &emptypb.Empty{})
-			if err != nil && status.Code(err) != tc.wantErr {
-				assert.Error(t, err)
+			got, err := u.GetAllUsers(context.Background(), in)
+			if tc.wantErr != codes.OK {
+				assert.Equal(t, tc.wantErr, status.Code(err))
 				return
 			}
 			assert.Equal(t, tc.want, got)
@@ -203,14 +210,14 @@ func TestUserService_GetAllUsers(t *testing.T) {
 
 //
 func TestUserService_Update(t *testing.T) {
-	loggingService := loggingservice.New()
+	loggingService := loggingservice.LoggingService{}
 	ui := new(userInt.User)
 	idd := "00000000-0000-0000-0000-000000000000"
 	id, _ := uuid.Parse(idd)
 	m := model.UserHTTP{ID: id, Name: "Abdula"}
-	ui.On("Update", context.Background(), m).Return(m, nil)
+	ui.On("Update", mock.Anything, m).Return(m, nil)
 	ui2 := new(userInt.User)
-	ui2.On("Update", context.Background(), m).Return(model.UserHTTP{}, errors.New("err"))
+	ui2.On("Update", mock.Anything, m).Return(model.UserHTTP{}, errors.New("err"))
 
 	tests := []struct {
 		name    string
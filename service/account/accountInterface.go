@@ -9,8 +9,14 @@ import (
 type AccInterface interface {
 	Get(context.Context, uuid.UUID) (model.Account, error)
 	GetUser(context.Context, uuid.UUID) ([]model.Account, error)
-	GetAll(context.Context) ([]model.Account, error)
-	Create(context.Context, uuid.UUID) (model.Account, error)
+	List(context.Context, model.AccountFilter) (model.AccountPage, error)
+	Create(context.Context, uuid.UUID, string) (model.Account, error)
 	Update(context.Context, model.Account) (model.Account, error)
 	Delete(context.Context, uuid.UUID) error
+	CloseUserAccounts(context.Context, uuid.UUID, bool) ([]model.Account, error)
+	RestoreAccounts(context.Context, []model.Account) error
+	Transfer(context.Context, model.Transfer) (model.TransferResult, error)
+	ListTransactions(context.Context, model.TransactionFilter) (model.TransactionPage, error)
+	Deposit(context.Context, uuid.UUID, int64) (model.Account, error)
+	Withdraw(context.Context, uuid.UUID, int64) (model.Account, error)
 }
@@ -0,0 +1,37 @@
+package currency
+
+import "strings"
+
+// active ISO 4217 codes by the number of minor unit digits, everything not listed has two
+var (
+	noMinorUnits    = "BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX UYI VND VUV XAF XOF XPF"
+	threeMinorUnits = "BHD IQD JOD KWD LYD OMR TND"
+	fourMinorUnits  = "CLF UYW"
+	twoMinorUnits   = "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BOV BRL BSD BTN BWP BYN BZD " +
+		"CAD CDF CHE CHF CHW CNY COP COU CRC CUP CVE CZK DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GTQ " +
+		"GYD HKD HNL HTG HUF IDR ILS INR IRR JMD KES KGS KHR KPW KYD KZT LAK LBP LKR LRD LSL MAD MDL MGA MKD MMK MNT " +
+		"MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD PAB PEN PGK PHP PKR PLN QAR RON RSD RUB SAR SBD " +
+		"SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TOP TRY TTD TWD TZS UAH USD USN UYU UZS VED " +
+		"VES WST XCD YER ZAR ZMW ZWL"
+)
+
+var minorUnits = make(map[string]int)
+
+func init() {
+	for digits, codes := range []string{noMinorUnits, "", twoMinorUnits, threeMinorUnits, fourMinorUnits} {
+		for _, code := range strings.Fields(codes) {
+			minorUnits[code] = digits
+		}
+	}
+}
+
+// Valid reports whether code is an active ISO 4217 currency, codes are upper case
+func Valid(code string) bool {
+	_, ok := minorUnits[code]
+	return ok
+}
+
+// MinorUnits is the number of decimal digits of the minor unit, balances are kept in minor units
+func MinorUnits(code string) int {
+	return minorUnits[code]
+}
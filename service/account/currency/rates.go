@@ -0,0 +1,124 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+)
+
+// rateDigits is the precision the applied rate is recorded with
+const rateDigits = 8
+
+// ratesFile is the exchange rate table on disk, a rate is the price of one base unit in that currency
+//
+//	{"base": "USD", "rates": {"EUR": 0.92, "JPY": 149.5}}
+type ratesFile struct {
+	Base  string                 `json:"base"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// Rates converts between any two currencies of the table through the base currency
+type Rates struct {
+	rates map[string]*big.Rat
+}
+
+// LoadRates reads the exchange rate table from a json file
+func LoadRates(path string) (*Rates, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file ratesFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates: %w", err)
+	}
+
+	if !Valid(file.Base) {
+		return nil, fmt.Errorf("base %q: %w", file.Base, customErrors.UnknownCurrency)
+	}
+
+	r := &Rates{rates: map[string]*big.Rat{file.Base: big.NewRat(1, 1)}}
+	for code, value := range file.Rates {
+		if !Valid(code) {
+			return nil, fmt.Errorf("rate of %q: %w", code, customErrors.UnknownCurrency)
+		}
+		rate, ok := new(big.Rat).SetString(value.String())
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("rate of %s must be a positive number, got %s", code, value)
+		}
+		r.rates[code] = rate
+	}
+
+	return r, nil
+}
+
+// Convert turns amount minor units of from into minor units of to, rounded half away from zero.
+// It returns the applied rate of one from unit in to units as a decimal string.
+func (r *Rates) Convert(amount int64, from, to string) (int64, string, error) {
+	if from == to {
+		return amount, "1", nil
+	}
+
+	var fromRate, toRate *big.Rat
+	if r != nil {
+		fromRate, toRate = r.rates[from], r.rates[to]
+	}
+	if fromRate == nil || toRate == nil {
+		return 0, "", fmt.Errorf("%s to %s: %w", from, to, customErrors.NoExchangeRate)
+	}
+
+	rate := new(big.Rat).Quo(toRate, fromRate)
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate)
+	converted.Mul(converted, scale(MinorUnits(to)-MinorUnits(from)))
+
+	res, ok := round(converted)
+	if !ok {
+		return 0, "", fmt.Errorf("converted amount does not fit: %w", customErrors.InvalidAmount)
+	}
+	if res == 0 && amount != 0 {
+		return 0, "", fmt.Errorf("amount is below one minor unit of %s: %w", to, customErrors.InvalidAmount)
+	}
+
+	return res, formatRate(rate), nil
+}
+
+// scale is 10^digits, digits may be negative
+func scale(digits int) *big.Rat {
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(digits))), nil)
+	if digits < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), pow)
+	}
+	return new(big.Rat).SetInt(pow)
+}
+
+func round(x *big.Rat) (int64, bool) {
+	num := new(big.Int).Abs(x.Num())
+	q, m := new(big.Int).QuoRem(num, x.Denom(), new(big.Int))
+	if m.Lsh(m, 1).Cmp(x.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if x.Sign() < 0 {
+		q.Neg(q)
+	}
+
+	return q.Int64(), q.IsInt64()
+}
+
+func formatRate(rate *big.Rat) string {
+	s := rate.FloatString(rateDigits)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+package currency
+
+import (
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+)
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("EUR"))
+	assert.False(t, Valid("eur"), "codes are upper case")
+	assert.False(t, Valid("XYZ"))
+	assert.Equal(t, 2, MinorUnits("USD"))
+	assert.Equal(t, 0, MinorUnits("JPY"))
+	assert.Equal(t, 3, MinorUnits("KWD"))
+}
+
+func TestLoadRates(t *testing.T) {
+	r, err := LoadRates("../../../pkg/storage/rates/exchange_rates.json")
+	require.NoError(t, err)
+	assert.Len(t, r.rates, 8)
+
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "rates.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	_, err = LoadRates(write(`{"base": "XYZ", "rates": {}}`))
+	assert.ErrorIs(t, err, customErrors.UnknownCurrency)
+
+	_, err = LoadRates(write(`{"base": "USD", "rates": {"EUR": 0}}`))
+	assert.Error(t, err)
+
+	_, err = LoadRates(write(`{"base": "USD", "rates": {"EUR": "0.9"}}`))
+	assert.NoError(t, err, "rates may be quoted")
+}
+
+func TestRates_Convert(t *testing.T) {
+	r, err := LoadRates("../../../pkg/storage/rates/exchange_rates.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		amount   int64
+		from, to string
+		want     int64
+		rate     string
+		wantErr  error
+	}{
+		{name: "Same currency", amount: 1050, from: "EUR", to: "EUR", want: 1050, rate: "1"},
+		{name: "From the base", amount: 10000, from: "USD", to: "EUR", want: 9200, rate: "0.92"},
+		{name: "To the base", amount: 9200, from: "EUR", to: "USD", want: 10000, rate: "1.08695652"},
+		{name: "Cross rate", amount: 100, from: "EUR", to: "GBP", want: 86, rate: "0.85869565"},
+		{name: "No minor units", amount: 100, from: "USD", to: "JPY", want: 150, rate: "149.5"},
+		{name: "Three minor units", amount: 14950, from: "JPY", to: "KWD", want: 30800, rate: "0.0020602"},
+		{name: "No rate", amount: 100, from: "USD", to: "SEK", wantErr: customErrors.NoExchangeRate},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, rate, err := r.Convert(tc.amount, tc.from, tc.to)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.rate, rate)
+		})
+	}
+
+	weak := &Rates{rates: map[string]*big.Rat{"USD": big.NewRat(1, 1), "JPY": big.NewRat(1000, 1)}}
+	_, _, err = weak.Convert(1, "JPY", "USD")
+	assert.ErrorIs(t, err, customErrors.InvalidAmount, "nothing would be credited")
+
+	_, _, err = (*Rates)(nil).Convert(100, "USD", "EUR")
+	assert.ErrorIs(t, err, customErrors.NoExchangeRate)
+}
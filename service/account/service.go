@@ -2,120 +2,354 @@ package account
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/google/uuid"
 
+	"github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/model"
 	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	"github.com/stasBigunenko/monorepa/service/account/currency"
+	"github.com/stasBigunenko/monorepa/service/audit"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
 )
 
-type LoggingService interface {
-	WriteLog(ctx context.Context, message string)
+// UserService looks up the owner of a new account, implemented by the user gRPC client
+type UserService interface {
+	GetUser(ctx context.Context, id uuid.UUID) (model.UserHTTP, error)
+}
+
+// ExchangeRates converts minor units between currencies, implemented by currency.Rates
+type ExchangeRates interface {
+	Convert(amount int64, from, to string) (int64, string, error)
 }
 
 type AccService struct {
-	storage        newStorage.NewStore
-	loggingService LoggingService
+	storage newStorage.AccountRepository
+	users   UserService
+	logger  loggingservice.Logger
+
+	onePerCurrency bool
+	rates          ExchangeRates
+	audit          *audit.Recorder
 }
 
-func NewAccService(s newStorage.NewStore, loggingService LoggingService) *AccService {
-	return &AccService{
-		storage:        s,
-		loggingService: loggingService,
+type Option func(*AccService)
+
+// OneAccountPerCurrency refuses to open a second account of a user in the same currency
+func OneAccountPerCurrency() Option {
+	return func(a *AccService) {
+		a.onePerCurrency = true
 	}
 }
 
-func (a *AccService) Get(c context.Context, id uuid.UUID) (model.Account, error) {
-	a.loggingService.WriteLog(c, "AccService: Command Get received...")
+// WithExchangeRates allows transfers between accounts in different currencies
+func WithExchangeRates(rates ExchangeRates) Option {
+	return func(a *AccService) {
+		a.rates = rates
+	}
+}
 
-	val, err := a.storage.Get(c, id)
-	if err != nil {
-		return model.Account{}, err
+// WithAudit records every change of an account in the audit trail
+func WithAudit(recorder *audit.Recorder) Option {
+	return func(a *AccService) {
+		a.audit = recorder
 	}
+}
 
-	res, ok := val.(model.Account)
-	if !ok {
-		return model.Account{}, err
+func NewAccService(s newStorage.AccountRepository, users UserService, logger loggingservice.Logger, opts ...Option) *AccService {
+	a := &AccService{
+		storage: s,
+		users:   users,
+		logger:  logger.With("component", "account service"),
+	}
+	for _, opt := range opts {
+		opt(a)
 	}
 
-	return res, nil
+	return a
+}
+
+func (a *AccService) Get(c context.Context, id uuid.UUID) (model.Account, error) {
+	a.logger.Debug(c, "command received", "method", "Get")
 
+	return a.storage.GetAccount(c, id)
 }
 
 func (a *AccService) GetUser(c context.Context, userID uuid.UUID) ([]model.Account, error) {
-	a.loggingService.WriteLog(c, "AccService: Command GetUser received...")
+	a.logger.Debug(c, "command received", "method", "GetUser")
 
-	val, err := a.storage.GetUserAccounts(c, userID)
+	res, err := a.storage.GetUserAccounts(c, userID)
 	if err != nil {
 		return []model.Account{}, err
 	}
 
-	res, ok := val.([]model.Account)
-	if !ok {
-		return []model.Account{}, err
-	}
-
 	return res, nil
 }
 
-func (a *AccService) GetAll(c context.Context) ([]model.Account, error) {
-	a.loggingService.WriteLog(c, "AccService: Command GetAll received...")
+// List returns a page of accounts, by default ordered by id
+func (a *AccService) List(c context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+	a.logger.Debug(c, "command received", "method", "List")
 
-	val, err := a.storage.GetAll(c)
-	if err != nil {
-		return nil, err
+	switch {
+	case filter.PageSize < 0:
+		return model.AccountPage{}, customErrors.InvalidArgument
+	case filter.PageSize == 0:
+		filter.PageSize = defaultPageSize
+	case filter.PageSize > maxPageSize:
+		filter.PageSize = maxPageSize
 	}
 
-	res, ok := val.([]model.Account)
-	if !ok {
-		return []model.Account{}, err
+	if filter.Sort == "" {
+		filter.Sort = model.SortByID
+	}
+	if field, _ := model.ParseSort(filter.Sort); field != model.SortByID && field != model.SortByBalance {
+		return model.AccountPage{}, customErrors.InvalidArgument
 	}
 
-	return res, nil
+	if filter.MinBalance != nil && filter.MaxBalance != nil && *filter.MinBalance > *filter.MaxBalance {
+		return model.AccountPage{}, customErrors.InvalidArgument
+	}
+
+	return a.storage.ListAccounts(c, filter)
 }
 
-func (a *AccService) Create(c context.Context, userID uuid.UUID) (model.Account, error) {
-	a.loggingService.WriteLog(c, "AccService: Command Create received...")
+// Create opens an account for an existing user, in the default currency when none is given
+func (a *AccService) Create(c context.Context, userID uuid.UUID, code string) (model.Account, error) {
+	a.logger.Debug(c, "command received", "method", "Create")
 
-	acc := model.Account{
-		UserID: userID,
+	if userID == uuid.Nil {
+		return model.Account{}, customErrors.UnknownOwner
 	}
 
-	val, err := a.storage.Create(c, acc)
-	if err != nil {
+	if _, err := a.users.GetUser(c, userID); err != nil {
+		if errors.Is(err, customErrors.NotFound) {
+			return model.Account{}, customErrors.UnknownOwner
+		}
 		return model.Account{}, err
 	}
 
-	res, ok := val.(model.Account)
-	if !ok {
+	code = strings.ToUpper(code)
+	if code == "" {
+		code = model.DefaultCurrency
+	}
+	if !currency.Valid(code) {
+		return model.Account{}, customErrors.UnknownCurrency
+	}
+
+	acc := model.Account{
+		UserID:   userID,
+		Currency: code,
+	}
+
+	// storage checks and inserts at once, so concurrent creates can not both pass
+	create := a.storage.CreateAccount
+	if a.onePerCurrency {
+		create = a.storage.CreateUniqueAccount
+	}
+
+	res, err := create(c, acc)
+	if err != nil {
 		return model.Account{}, err
 	}
+	a.audit.Record(c, model.AuditCreate, res.ID, nil, res)
 
 	return res, nil
 }
 
 func (a *AccService) Update(c context.Context, account model.Account) (model.Account, error) {
-	a.loggingService.WriteLog(c, "AccService: Command Update received...")
+	a.logger.Debug(c, "command received", "method", "Update")
+
+	// a negative limit would refuse withdrawals even from a positive balance
+	if account.OverdraftLimit < 0 {
+		return model.Account{}, fmt.Errorf("negative overdraft limit: %w", customErrors.InvalidArgument)
+	}
 
-	val, err := a.storage.Update(c, account)
+	before, err := a.auditedAccount(c, account.ID)
 	if err != nil {
 		return model.Account{}, err
 	}
 
-	res, ok := val.(model.Account)
-	if !ok {
+	res, err := a.storage.UpdateAccount(c, account)
+	if err != nil {
 		return model.Account{}, err
 	}
+	a.audit.Record(c, model.AuditUpdate, res.ID, before, res)
 
 	return res, nil
 }
 
 func (a *AccService) Delete(c context.Context, id uuid.UUID) error {
-	a.loggingService.WriteLog(c, "AccService: Command Delete received...")
+	a.logger.Debug(c, "command received", "method", "Delete")
+
+	before, err := a.auditedAccount(c, id)
+	if err != nil {
+		return err
+	}
+
+	err = a.storage.DeleteAccount(c, id)
+	if err != nil {
+		return err
+	}
+	a.audit.Record(c, model.AuditDelete, id, before, nil)
+
+	return nil
+}
+
+// auditedAccount is the account before a change, it is only read when changes are audited
+func (a *AccService) auditedAccount(c context.Context, id uuid.UUID) (interface{}, error) {
+	if a.audit == nil {
+		return nil, nil
+	}
+
+	acc, err := a.storage.GetAccount(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return acc, nil
+}
+
+// CloseUserAccounts removes the accounts of a user being deleted, force closes them even with money on them
+func (a *AccService) CloseUserAccounts(c context.Context, userID uuid.UUID, force bool) ([]model.Account, error) {
+	a.logger.Debug(c, "command received", "method", "CloseUserAccounts")
 
-	err := a.storage.Delete(c, id)
+	closed, err := a.storage.CloseUserAccounts(c, userID, force)
 	if err != nil {
+		return nil, err
+	}
+	for _, acc := range closed {
+		a.audit.Record(c, model.AuditDelete, acc.ID, acc, nil)
+	}
+
+	return closed, nil
+}
+
+// RestoreAccounts undoes CloseUserAccounts when the user could not be deleted
+func (a *AccService) RestoreAccounts(c context.Context, accounts []model.Account) error {
+	a.logger.Debug(c, "command received", "method", "RestoreAccounts")
+
+	if err := a.storage.RestoreAccounts(c, accounts); err != nil {
 		return err
 	}
+	for _, acc := range accounts {
+		a.audit.Record(c, model.AuditRestore, acc.ID, nil, acc)
+	}
 
 	return nil
 }
+
+// Transfer credits the receiving account in its own currency at the exchange rate of the moment
+func (a *AccService) Transfer(c context.Context, t model.Transfer) (model.TransferResult, error) {
+	a.logger.Debug(c, "command received", "method", "Transfer")
+
+	if t.From == t.To {
+		return model.TransferResult{}, customErrors.SameAccount
+	}
+
+	if t.Amount <= 0 {
+		return model.TransferResult{}, customErrors.InvalidAmount
+	}
+
+	from, err := a.storage.GetAccount(c, t.From)
+	if err != nil {
+		return model.TransferResult{}, err
+	}
+	to, err := a.storage.GetAccount(c, t.To)
+	if err != nil {
+		return model.TransferResult{}, err
+	}
+
+	t.Credit, t.Rate = t.Amount, "1"
+	if from.Currency != to.Currency {
+		if a.rates == nil {
+			return model.TransferResult{}, customErrors.NoExchangeRate
+		}
+		if t.Credit, t.Rate, err = a.rates.Convert(t.Amount, from.Currency, to.Currency); err != nil {
+			return model.TransferResult{}, err
+		}
+	}
+
+	res, err := a.storage.Transfer(c, t)
+	if err != nil {
+		return model.TransferResult{}, err
+	}
+	if res.Replayed {
+		// a retry changes nothing, the audit trail has the transfer from the first attempt
+		return res, nil
+	}
+
+	// the accounts read above may be older than the transfer, the result tells their state right before it
+	fromBefore, toBefore := res.From, res.To
+	fromBefore.Balance, fromBefore.Version = fromBefore.Balance+t.Amount, fromBefore.Version-1
+	toBefore.Balance, toBefore.Version = toBefore.Balance-res.Credit, toBefore.Version-1
+	a.audit.Record(c, model.AuditTransfer, from.ID, fromBefore, res.From)
+	a.audit.Record(c, model.AuditTransfer, to.ID, toBefore, res.To)
+
+	return res, nil
+}
+
+func (a *AccService) ListTransactions(c context.Context, filter model.TransactionFilter) (model.TransactionPage, error) {
+	a.logger.Debug(c, "command received", "method", "ListTransactions")
+
+	switch {
+	case filter.PageSize < 0:
+		return model.TransactionPage{}, customErrors.InvalidArgument
+	case filter.PageSize == 0:
+		filter.PageSize = defaultPageSize
+	case filter.PageSize > maxPageSize:
+		filter.PageSize = maxPageSize
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return model.TransactionPage{}, customErrors.InvalidArgument
+	}
+
+	if _, err := a.storage.GetAccount(c, filter.AccountID); err != nil {
+		return model.TransactionPage{}, err
+	}
+
+	return a.storage.ListTransactions(c, filter)
+}
+
+func (a *AccService) Deposit(c context.Context, id uuid.UUID, amount int64) (model.Account, error) {
+	a.logger.Debug(c, "command received", "method", "Deposit")
+
+	if amount <= 0 {
+		return model.Account{}, customErrors.InvalidAmount
+	}
+
+	return a.applyDelta(c, model.AuditDeposit, id, amount)
+}
+
+func (a *AccService) Withdraw(c context.Context, id uuid.UUID, amount int64) (model.Account, error) {
+	a.logger.Debug(c, "command received", "method", "Withdraw")
+
+	if amount <= 0 {
+		return model.Account{}, customErrors.InvalidAmount
+	}
+
+	return a.applyDelta(c, model.AuditWithdraw, id, -amount)
+}
+
+func (a *AccService) applyDelta(c context.Context, operation string, id uuid.UUID, delta int64) (model.Account, error) {
+	res, err := a.storage.ApplyDelta(c, id, delta)
+	if err != nil {
+		return model.Account{}, err
+	}
+
+	// the change is applied atomically, so the account before it is known without reading it
+	before := res
+	before.Balance -= delta
+	before.Version--
+	a.audit.Record(c, operation, id, before, res)
+
+	return res, nil
+}
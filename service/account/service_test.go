@@ -2,75 +2,129 @@ package account
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	log "github.com/sirupsen/logrus"
+	"fmt"
 	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/mocks/pkg/storage/mockNewStore"
 	"github.com/stasBigunenko/monorepa/model"
+	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	"github.com/stasBigunenko/monorepa/service/audit"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-type MockLoggingService struct {
-}
+// MockUserService knows the users in the map, any other id is not found
+type MockUserService map[uuid.UUID]bool
 
-func (s MockLoggingService) WriteLog(ctx context.Context, message string) {}
+func (m MockUserService) GetUser(ctx context.Context, id uuid.UUID) (model.UserHTTP, error) {
+	if !m[id] {
+		return model.UserHTTP{}, fmt.Errorf("failed to get user: %w", customErrors.NotFound)
+	}
+	return model.UserHTTP{ID: id}, nil
+}
 
 func Test_Create(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.AccountRepository)
 	id := uuid.New()
 	userID := uuid.New()
-	m := model.Account{ID: id, UserID: userID, Balance: 0}
-	mm := model.Account{UserID: userID}
-	ui.On("Create", context.Background(), mm).Return(m, nil)
+	m := model.Account{ID: id, UserID: userID, Currency: "EUR"}
+	ui.On("CreateAccount", context.Background(), model.Account{UserID: userID, Currency: "EUR"}).Return(m, nil)
+	usd := model.Account{ID: id, UserID: userID, Currency: model.DefaultCurrency}
+	ui.On("CreateAccount", context.Background(), model.Account{UserID: userID, Currency: model.DefaultCurrency}).Return(usd, nil)
+	users := MockUserService{userID: true}
 
 	tests := []struct {
-		name    string
-		param   uuid.UUID
-		stor    *mockNewStore.NewStore
-		want    model.Account
-		wantErr string
+		name     string
+		param    uuid.UUID
+		currency string
+		stor     *mockNewStore.AccountRepository
+		want     model.Account
+		wantErr  error
 	}{
 		{
-			name:    "Everything good",
-			param:   userID,
+			name:     "Everything good",
+			param:    userID,
+			currency: "eur",
+			stor:     ui,
+			want:     m,
+		},
+		{
+			name:  "Default currency",
+			param: userID,
+			stor:  ui,
+			want:  usd,
+		},
+		{
+			name:     "Unknown currency",
+			param:    userID,
+			currency: "XYZ",
+			stor:     ui,
+			want:     model.Account{},
+			wantErr:  customErrors.UnknownCurrency,
+		},
+		{
+			name:    "Nil owner",
+			param:   uuid.Nil,
 			stor:    ui,
-			want:    m,
-			wantErr: "",
+			want:    model.Account{},
+			wantErr: customErrors.UnknownOwner,
+		},
+		{
+			name:    "Owner does not exist",
+			param:   uuid.New(),
+			stor:    ui,
+			want:    model.Account{},
+			wantErr: customErrors.UnknownOwner,
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			u := NewAccService(tc.stor, loggingService)
-			got, err := u.Create(context.Background(), tc.param)
-			log.Info(got)
-			if err != nil && err.Error() != tc.wantErr {
-				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
-				return
-			}
+			u := NewAccService(tc.stor, users, loggingService)
+			got, err := u.Create(context.Background(), tc.param, tc.currency)
+			assert.ErrorIs(t, err, tc.wantErr)
 			assert.Equal(t, tc.want, got)
 		})
 	}
 }
 
-//
+func TestAccService_CreateOnePerCurrency(t *testing.T) {
+	userID := uuid.New()
+	ui := new(mockNewStore.AccountRepository)
+	ui.On("CreateUniqueAccount", context.Background(), model.Account{UserID: userID, Currency: "EUR"}).Return(model.Account{}, customErrors.CurrencyTaken)
+	created := model.Account{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	ui.On("CreateUniqueAccount", context.Background(), model.Account{UserID: userID, Currency: "USD"}).Return(created, nil)
+	u := NewAccService(ui, MockUserService{userID: true}, loggingservice.LoggingService{}, OneAccountPerCurrency())
+
+	_, err := u.Create(context.Background(), userID, "EUR")
+	assert.ErrorIs(t, err, customErrors.CurrencyTaken)
+
+	got, err := u.Create(context.Background(), userID, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, created, got)
+}
+
 func Test_Get(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.AccountRepository)
 	id := uuid.New()
 	userID := uuid.New()
 	m := model.Account{ID: id, UserID: userID, Balance: 0}
-	ui.On("Get", context.Background(), id).Return(m, nil)
+	ui.On("GetAccount", context.Background(), id).Return(m, nil)
 
-	ui2 := new(mockNewStore.NewStore)
-	ui2.On("Get", mock.Anything, mock.Anything).Return(nil, nil)
+	ui2 := new(mockNewStore.AccountRepository)
+	ui2.On("GetAccount", mock.Anything, mock.Anything).Return(model.Account{}, errors.New("couldn't get account"))
 
 	tests := []struct {
 		name    string
-		stor    *mockNewStore.NewStore
+		stor    *mockNewStore.AccountRepository
 		want    model.Account
 		wantErr string
 	}{
@@ -88,7 +142,7 @@ func Test_Get(t *testing.T) {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			u := NewAccService(tc.stor, loggingService)
+			u := NewAccService(tc.stor, MockUserService{}, loggingService)
 			got, err := u.Get(context.Background(), id)
 			if err != nil && err.Error() != tc.wantErr {
 				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
@@ -100,14 +154,14 @@ func Test_Get(t *testing.T) {
 }
 
 func TestUserService_Delete(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.AccountRepository)
 	id := uuid.New()
-	ui.On("Delete", context.Background(), id).Return(nil)
+	ui.On("DeleteAccount", context.Background(), id).Return(nil)
 
 	tests := []struct {
 		name    string
-		stor    *mockNewStore.NewStore
+		stor    *mockNewStore.AccountRepository
 		param   uuid.UUID
 		result  []byte
 		wantErr string
@@ -121,7 +175,7 @@ func TestUserService_Delete(t *testing.T) {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			u := NewAccService(tc.stor, loggingService)
+			u := NewAccService(tc.stor, MockUserService{}, loggingService)
 			err := u.Delete(context.Background(), tc.param)
 			if err != nil && err.Error() != tc.wantErr {
 				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
@@ -132,56 +186,69 @@ func TestUserService_Delete(t *testing.T) {
 	}
 }
 
-func TestUserService_GetAll(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+func TestUserService_List(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.AccountRepository)
 	m1 := model.Account{ID: uuid.New(), UserID: uuid.New(), Balance: 0}
 	m2 := model.Account{ID: uuid.New(), UserID: uuid.New(), Balance: 12}
-	m := []model.Account{
-		m1,
-		m2,
-	}
-	ui.On("GetAll", context.Background()).Return(m, nil)
+	m := model.AccountPage{Accounts: []model.Account{m1, m2}}
+	ui.On("ListAccounts", context.Background(), model.AccountFilter{Sort: model.SortByID, PageSize: 50}).Return(m, nil)
+
+	min, max := int64(10), int64(5)
 
 	tests := []struct {
 		name    string
-		stor    *mockNewStore.NewStore
-		want    []model.Account
-		wantErr string
+		stor    *mockNewStore.AccountRepository
+		filter  model.AccountFilter
+		want    model.AccountPage
+		wantErr error
 	}{
 		{
 			name: "Everything ok",
 			stor: ui,
 			want: m,
 		},
+		{
+			name:    "Unknown sort",
+			stor:    ui,
+			filter:  model.AccountFilter{Sort: "name"},
+			wantErr: customErrors.InvalidArgument,
+		},
+		{
+			name:    "Empty balance range",
+			stor:    ui,
+			filter:  model.AccountFilter{MinBalance: &min, MaxBalance: &max},
+			wantErr: customErrors.InvalidArgument,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			u := NewAccService(tc.stor, loggingService)
-			got, err := u.GetAll(context.Background())
-			if err != nil {
-				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
+			u := NewAccService(tc.stor, MockUserService{}, loggingService)
+			got, err := u.List(context.Background(), tc.filter)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
 				return
 			}
+			assert.NoError(t, err)
 			assert.Equal(t, tc.want, got)
 		})
 	}
 }
 
 func TestUserService_Update(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.AccountRepository)
 	id := uuid.New()
 	userID := uuid.New()
 	m := model.Account{ID: id, UserID: userID, Balance: 0}
-	ui.On("Update", context.Background(), m).Return(m, nil)
+	ui.On("UpdateAccount", context.Background(), m).Return(m, nil)
 
-	ui2 := new(mockNewStore.NewStore)
-	ui2.On("Update", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("not found"))
+	ui2 := new(mockNewStore.AccountRepository)
+	ui2.On("UpdateAccount", mock.Anything, mock.Anything).Return(model.Account{}, errors.New("not found"))
 
 	tests := []struct {
 		name    string
-		stor    *mockNewStore.NewStore
+		stor    *mockNewStore.AccountRepository
 		param   model.Account
 		want    model.Account
 		wantErr string
@@ -199,11 +266,18 @@ func TestUserService_Update(t *testing.T) {
 			want:    model.Account{},
 			wantErr: "not found",
 		},
+		{
+			name:    "Negative overdraft limit",
+			stor:    new(mockNewStore.AccountRepository),
+			param:   model.Account{ID: id, UserID: userID, OverdraftLimit: -100},
+			want:    model.Account{},
+			wantErr: "negative overdraft limit: invalid argument",
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			u := NewAccService(tc.stor, loggingService)
-			got, err := u.Update(context.Background(), m)
+			u := NewAccService(tc.stor, MockUserService{}, loggingService)
+			got, err := u.Update(context.Background(), tc.param)
 			if (err != nil) && err.Error() != tc.wantErr {
 				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
 				return
@@ -214,8 +288,8 @@ func TestUserService_Update(t *testing.T) {
 }
 
 func TestUserService_GetUser(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.AccountRepository)
 	userID := uuid.New()
 	m1 := model.Account{ID: uuid.New(), UserID: userID, Balance: 0}
 	m2 := model.Account{ID: uuid.New(), UserID: userID, Balance: 12}
@@ -227,7 +301,7 @@ func TestUserService_GetUser(t *testing.T) {
 
 	tests := []struct {
 		name    string
-		stor    *mockNewStore.NewStore
+		stor    *mockNewStore.AccountRepository
 		param   uuid.UUID
 		want    []model.Account
 		wantErr string
@@ -241,7 +315,7 @@ func TestUserService_GetUser(t *testing.T) {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			u := NewAccService(tc.stor, loggingService)
+			u := NewAccService(tc.stor, MockUserService{}, loggingService)
 			got, err := u.GetUser(context.Background(), tc.param)
 			if (err != nil) && err.Error() != tc.wantErr {
 				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
@@ -251,3 +325,224 @@ func TestUserService_GetUser(t *testing.T) {
 		})
 	}
 }
+
+// MockRates converts at a fixed rate of one unit to rate units, whatever the currencies
+type MockRates int64
+
+func (m MockRates) Convert(amount int64, from, to string) (int64, string, error) {
+	return amount * int64(m), fmt.Sprint(int64(m)), nil
+}
+
+func TestUserService_Transfer(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.AccountRepository)
+	from := uuid.New()
+	to := uuid.New()
+	yen := uuid.New()
+	ui.On("GetAccount", context.Background(), from).Return(model.Account{ID: from, Currency: "USD", Balance: 10}, nil)
+	ui.On("GetAccount", context.Background(), to).Return(model.Account{ID: to, Currency: "USD"}, nil)
+	ui.On("GetAccount", context.Background(), yen).Return(model.Account{ID: yen, Currency: "JPY"}, nil)
+
+	tr := model.Transfer{From: from, To: to, Amount: 10, Credit: 10, Rate: "1"}
+	res := model.TransferResult{
+		From:   model.Account{ID: from, Balance: 0},
+		To:     model.Account{ID: to, Balance: 10},
+		Credit: 10,
+		Rate:   "1",
+	}
+	ui.On("Transfer", context.Background(), tr).Return(res, nil)
+
+	converted := model.Transfer{From: from, To: yen, Amount: 10, Credit: 1500, Rate: "150"}
+	convertedRes := model.TransferResult{
+		From:   model.Account{ID: from, Balance: 0},
+		To:     model.Account{ID: yen, Balance: 1500},
+		Credit: 1500,
+		Rate:   "150",
+	}
+	ui.On("Transfer", context.Background(), converted).Return(convertedRes, nil)
+
+	tests := []struct {
+		name    string
+		stor    *mockNewStore.AccountRepository
+		rates   ExchangeRates
+		param   model.Transfer
+		want    model.TransferResult
+		wantErr error
+	}{
+		{
+			name:  "Everything ok",
+			stor:  ui,
+			param: model.Transfer{From: from, To: to, Amount: 10},
+			want:  res,
+		},
+		{
+			name:  "Converted",
+			stor:  ui,
+			rates: MockRates(150),
+			param: model.Transfer{From: from, To: yen, Amount: 10},
+			want:  convertedRes,
+		},
+		{
+			name:    "No exchange rates",
+			stor:    ui,
+			param:   model.Transfer{From: from, To: yen, Amount: 10},
+			want:    model.TransferResult{},
+			wantErr: customErrors.NoExchangeRate,
+		},
+		{
+			name:    "Same account",
+			stor:    ui,
+			param:   model.Transfer{From: from, To: from, Amount: 10},
+			want:    model.TransferResult{},
+			wantErr: customErrors.SameAccount,
+		},
+		{
+			name:    "Negative amount",
+			stor:    ui,
+			param:   model.Transfer{From: from, To: to, Amount: -10},
+			want:    model.TransferResult{},
+			wantErr: customErrors.InvalidAmount,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []Option
+			if tc.rates != nil {
+				opts = append(opts, WithExchangeRates(tc.rates))
+			}
+			u := NewAccService(tc.stor, MockUserService{}, loggingService, opts...)
+			got, err := u.Transfer(context.Background(), tc.param)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUserService_TransferAudit(t *testing.T) {
+	ctx := context.Background()
+	logger := loggingservice.LoggingService{}
+	store := newStorage.NewDB(logger)
+	u := NewAccService(store, nil, logger, WithAudit(audit.NewRecorder(store, model.AuditResourceAccount, logger)))
+
+	from, err := store.CreateAccount(ctx, model.Account{Balance: 100})
+	require.NoError(t, err)
+	to, err := store.CreateAccount(ctx, model.Account{})
+	require.NoError(t, err)
+
+	transfer := model.Transfer{From: from.ID, To: to.ID, Amount: 30, IdempotencyKey: "key"}
+	_, err = u.Transfer(ctx, transfer)
+	require.NoError(t, err)
+	// the retry gets the first result and leaves no entries of its own
+	res, err := u.Transfer(ctx, transfer)
+	require.NoError(t, err)
+	assert.True(t, res.Replayed)
+
+	page, err := store.ListAudit(ctx, model.AuditFilter{Resource: model.AuditResourceAccount, ResourceID: from.ID, PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+
+	var before model.Account
+	require.NoError(t, json.Unmarshal(page.Entries[0].Before, &before))
+	assert.Equal(t, int64(100), before.Balance)
+}
+
+func TestUserService_ListTransactions(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	id := uuid.New()
+	page := model.TransactionPage{
+		Transactions: []model.LedgerEntry{{ID: uuid.New(), AccountID: id, Type: model.EntryDeposit, Amount: 10, Balance: 10}},
+	}
+	ui := new(mockNewStore.AccountRepository)
+	ui.On("GetAccount", context.Background(), id).Return(model.Account{ID: id}, nil)
+	ui.On("ListTransactions", context.Background(), model.TransactionFilter{AccountID: id, PageSize: 50}).Return(page, nil)
+
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		stor    *mockNewStore.AccountRepository
+		param   model.TransactionFilter
+		want    model.TransactionPage
+		wantErr string
+	}{
+		{
+			name:  "Default page size",
+			stor:  ui,
+			param: model.TransactionFilter{AccountID: id},
+			want:  page,
+		},
+		{
+			name:    "Empty time range",
+			stor:    ui,
+			param:   model.TransactionFilter{AccountID: id, From: now, To: now},
+			want:    model.TransactionPage{},
+			wantErr: "invalid argument",
+		},
+		{
+			name:    "Negative page size",
+			stor:    ui,
+			param:   model.TransactionFilter{AccountID: id, PageSize: -1},
+			want:    model.TransactionPage{},
+			wantErr: "invalid argument",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u := NewAccService(tc.stor, MockUserService{}, loggingService)
+			got, err := u.ListTransactions(context.Background(), tc.param)
+			if (err != nil) && err.Error() != tc.wantErr {
+				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
+				return
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUserService_DepositWithdraw(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.AccountRepository)
+	id := uuid.New()
+	ui.On("ApplyDelta", context.Background(), id, int64(10)).Return(model.Account{ID: id, Balance: 10}, nil)
+	ui.On("ApplyDelta", context.Background(), id, int64(-10)).Return(model.Account{}, customErrors.InsufficientFunds)
+
+	u := NewAccService(ui, MockUserService{}, loggingService)
+
+	got, err := u.Deposit(context.Background(), id, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, model.Account{ID: id, Balance: 10}, got)
+
+	_, err = u.Withdraw(context.Background(), id, 10)
+	assert.ErrorIs(t, err, customErrors.InsufficientFunds)
+
+	_, err = u.Deposit(context.Background(), id, 0)
+	assert.ErrorIs(t, err, customErrors.InvalidAmount)
+
+	_, err = u.Withdraw(context.Background(), id, -5)
+	assert.ErrorIs(t, err, customErrors.InvalidAmount)
+
+	ui.AssertNumberOfCalls(t, "ApplyDelta", 2)
+}
+
+func TestAccService_CloseUserAccounts(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	userID := uuid.New()
+	closed := []model.Account{{ID: uuid.New(), UserID: userID}}
+
+	ui := new(mockNewStore.AccountRepository)
+	ui.On("CloseUserAccounts", context.Background(), userID, false).Return(nil, customErrors.AccountsNotEmpty)
+	ui.On("CloseUserAccounts", context.Background(), userID, true).Return(closed, nil)
+	ui.On("RestoreAccounts", context.Background(), closed).Return(nil)
+
+	u := NewAccService(ui, MockUserService{}, loggingService)
+
+	_, err := u.CloseUserAccounts(context.Background(), userID, false)
+	assert.ErrorIs(t, err, customErrors.AccountsNotEmpty)
+
+	got, err := u.CloseUserAccounts(context.Background(), userID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, closed, got)
+
+	assert.NoError(t, u.RestoreAccounts(context.Background(), closed))
+	ui.AssertExpectations(t)
+}
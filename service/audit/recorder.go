@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// Recorder appends the changes of one resource to the audit trail. The actor and the
+// request id come from the context the gRPC server fills in from the gateway metadata.
+type Recorder struct {
+	log      newStorage.AuditLog
+	resource string
+	logger   loggingservice.Logger
+	failures *prometheus.CounterVec
+}
+
+// Option configures a Recorder
+type Option func(*Recorder)
+
+// WithMetrics registers the counter of changes the recorder failed to append
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(r *Recorder) {
+		reg.MustRegister(r.failures)
+	}
+}
+
+func NewRecorder(auditLog newStorage.AuditLog, resource string, logger loggingservice.Logger, opts ...Option) *Recorder {
+	r := &Recorder{
+		log:      auditLog,
+		resource: resource,
+		logger:   logger.With("component", "audit"),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "audit_record_failures_total",
+			Help: "Changes missing from the audit trail since appending them failed, by resource and operation.",
+		}, []string{"resource", "operation"}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Record appends a change of the resource with the given id, before or after is nil when there is none.
+// The change is done already, so a failure to record it is logged and counted in audit_record_failures_total,
+// not returned. A nil Recorder records nothing.
+func (r *Recorder) Record(c context.Context, operation string, id uuid.UUID, before, after interface{}) {
+	if r == nil {
+		return
+	}
+
+	r.logger.Debug(c, "command received", "method", "Record")
+
+	entry := model.AuditEntry{
+		ID:         uuid.New(),
+		Time:       time.Unix(0, time.Now().UnixNano()).UTC(),
+		Actor:      actor(c),
+		Operation:  operation,
+		Resource:   r.resource,
+		ResourceID: id,
+	}
+	entry.RequestID, _ = c.Value(model.ContextKeyRequestID).(string)
+
+	var err error
+	if entry.Before, err = marshal(before); err == nil {
+		entry.After, err = marshal(after)
+	}
+	if err == nil {
+		err = r.log.AppendAudit(c, entry)
+	}
+	if err != nil {
+		r.failures.WithLabelValues(r.resource, operation).Inc()
+		r.logger.Error(c, "failed to record a change", "operation", operation, "resource", r.resource, "resource_id", id, "error", err)
+	}
+}
+
+// List returns a page of the audit trail of the resource, 50 entries by default
+func (r *Recorder) List(c context.Context, filter model.AuditFilter) (model.AuditPage, error) {
+	r.logger.Debug(c, "command received", "method", "List")
+
+	switch {
+	case filter.PageSize < 0:
+		return model.AuditPage{}, fmt.Errorf("negative page size: %w", customErrors.InvalidArgument)
+	case filter.PageSize == 0:
+		filter.PageSize = defaultPageSize
+	case filter.PageSize > maxPageSize:
+		filter.PageSize = maxPageSize
+	}
+
+	// services sharing a database see each other's entries otherwise
+	filter.Resource = r.resource
+
+	return r.log.ListAudit(c, filter)
+}
+
+// actor is the login name of the caller, the user id for tokens without one
+func actor(c context.Context) string {
+	caller, _ := c.Value(model.IdentityKey).(model.Identity)
+	if caller.Name == "" && caller.UserID != uuid.Nil {
+		return caller.UserID.String()
+	}
+
+	return caller.Name
+}
+
+func marshal(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
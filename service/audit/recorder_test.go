@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
+)
+
+func TestRecorder(t *testing.T) {
+	db := newStorage.NewDB(loggingservice.LoggingService{})
+	accounts := NewRecorder(db, model.AuditResourceAccount, loggingservice.LoggingService{})
+	users := NewRecorder(db, model.AuditResourceUser, loggingservice.LoggingService{})
+
+	id := uuid.New()
+	c := context.WithValue(context.Background(), model.IdentityKey, model.Identity{Name: "root"})
+	c = context.WithValue(c, model.ContextKeyRequestID, "req-1")
+
+	accounts.Record(c, model.AuditUpdate, id, model.Account{ID: id, Balance: 1}, model.Account{ID: id, Balance: 2})
+	users.Record(c, model.AuditCreate, id, nil, model.UserHTTP{ID: id, Name: "bob"})
+
+	// the token of a caller without a name still tells who it was
+	caller := uuid.New()
+	accounts.Record(context.WithValue(context.Background(), model.IdentityKey, model.Identity{UserID: caller}), model.AuditDelete, id, model.Account{ID: id}, nil)
+
+	var none *Recorder
+	none.Record(c, model.AuditDelete, id, nil, nil)
+
+	page, err := accounts.List(context.Background(), model.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2, "only the entries of the resource")
+
+	update := page.Entries[0]
+	assert.Equal(t, "root", update.Actor)
+	assert.Equal(t, "req-1", update.RequestID)
+	assert.Equal(t, model.AuditUpdate, update.Operation)
+	assert.Equal(t, id, update.ResourceID)
+	var before model.Account
+	require.NoError(t, json.Unmarshal(update.Before, &before))
+	assert.Equal(t, model.Account{ID: id, Balance: 1}, before)
+	assert.Equal(t, caller.String(), page.Entries[1].Actor)
+	assert.Nil(t, page.Entries[1].After)
+
+	page, err = users.List(context.Background(), model.AuditFilter{Resource: model.AuditResourceAccount})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1, "the resource of the recorder wins")
+	assert.Nil(t, page.Entries[0].Before)
+
+	_, err = users.List(context.Background(), model.AuditFilter{PageSize: -1})
+	assert.ErrorIs(t, err, customErrors.InvalidArgument)
+}
+
+// brokenLog refuses every entry
+type brokenLog struct {
+	newStorage.AuditLog
+}
+
+func (brokenLog) AppendAudit(context.Context, model.AuditEntry) error {
+	return errors.New("connection refused")
+}
+
+func TestRecorder_Failures(t *testing.T) {
+	reg := metrics.NewRegistry()
+	accounts := NewRecorder(brokenLog{}, model.AuditResourceAccount, loggingservice.LoggingService{}, WithMetrics(reg))
+
+	accounts.Record(context.Background(), model.AuditUpdate, uuid.New(), nil, model.Account{})
+	accounts.Record(context.Background(), model.AuditUpdate, uuid.New(), nil, model.Account{})
+
+	rec := httptest.NewRecorder()
+	metrics.Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `audit_record_failures_total{operation="update",resource="account"} 2`)
+}
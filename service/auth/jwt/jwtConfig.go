@@ -4,12 +4,17 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"time"
 )
 
+// refresh tokens live for 30 days unless REFRESH_TOKEN_EXPIRE is set
+const defaultRefreshExpire = 30 * 24 * 60
+
 type Config struct {
-	pathCert            string
-	certVersion         string
-	tokenExpireDuration int
+	pathCert              string
+	certVersion           string
+	tokenExpireDuration   int
+	refreshExpireDuration int
 }
 
 func NewJTWConfig() (*Config, error) {
@@ -24,16 +29,28 @@ func NewJTWConfig() (*Config, error) {
 		return nil, err
 	}
 
+	refreshDuration := defaultRefreshExpire
+	if refreshTime := os.Getenv("REFRESH_TOKEN_EXPIRE"); refreshTime != "" {
+		if refreshDuration, err = strconv.Atoi(refreshTime); err != nil {
+			return nil, err
+		}
+	}
+
 	path := os.Getenv("CERT_PATH")
 	if path == "" {
 		return nil, errors.New("wrong cert path")
 	}
 
 	token := &Config{
-		pathCert:            path,
-		certVersion:         certVersion,
-		tokenExpireDuration: duration,
+		pathCert:              path,
+		certVersion:           certVersion,
+		tokenExpireDuration:   duration,
+		refreshExpireDuration: refreshDuration,
 	}
 
 	return token, nil
 }
+
+func (c *Config) RefreshTokenLife() time.Duration {
+	return time.Duration(c.refreshExpireDuration) * time.Minute
+}
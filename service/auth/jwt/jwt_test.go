@@ -11,6 +11,7 @@ import (
 
 	"github.com/golang-jwt/jwt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateUserJWTToken(t *testing.T) {
@@ -69,19 +70,20 @@ func TestCreateUserJWTToken(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		token, err := CreateUserJWTToken(tc.request.userName, &tc.envConfig)
-
+		km, err := NewKeyManager(&tc.envConfig)
 		if tc.want.isError {
-			assert.Equal(t, token, "", tc.name)
 			assert.Error(t, err, tc.name)
-		} else {
-			assert.NotEqual(t, token, "", tc.name)
-			assert.Nil(t, err, tc.name)
+			continue
 		}
+		require.NoError(t, err, tc.name)
+
+		token, _, err := km.CreateUserJWTToken(model.Identity{Name: tc.request.userName}, "1")
+		assert.NotEqual(t, token, "", tc.name)
+		assert.Nil(t, err, tc.name)
 	}
 }
 
-func TestGetCertificateKey(t *testing.T) {
+func TestKeyManager_PublicKeyPEM(t *testing.T) {
 	type request struct {
 		certVersion string
 	}
@@ -91,35 +93,18 @@ func TestGetCertificateKey(t *testing.T) {
 	}
 
 	testCases := []struct {
-		name      string
-		request   request
-		want      want
-		envConfig Config
+		name    string
+		request request
+		want    want
 	}{
 		{
 			name: "normal request",
 			request: request{
 				certVersion: "1",
 			},
-
 			want: want{
 				isError: false,
 			},
-			envConfig: Config{
-				pathCert: "../../../pkg/storage/certificates",
-			},
-		},
-		{
-			name: "wrong path",
-			request: request{
-				certVersion: "1",
-			},
-			want: want{
-				isError: true,
-			},
-			envConfig: Config{
-				pathCert: "./",
-			},
 		},
 		{
 			name: "wrong cert version",
@@ -129,19 +114,18 @@ func TestGetCertificateKey(t *testing.T) {
 			want: want{
 				isError: true,
 			},
-			envConfig: Config{
-				pathCert: "./",
-			},
 		},
 	}
 
-	for _, tc := range testCases {
+	km, err := NewKeyManager(&Config{pathCert: "../../../pkg/storage/certificates", certVersion: "1", tokenExpireDuration: 10})
+	require.NoError(t, err)
 
-		cert, err := GetCertificateKey(tc.request.certVersion, &tc.envConfig)
+	for _, tc := range testCases {
+		cert, err := km.PublicKeyPEM(tc.request.certVersion)
 
 		if tc.want.isError {
 			assert.Nil(t, cert, tc.name)
-			assert.Error(t, err, tc.name)
+			assert.ErrorIs(t, err, ErrUnknownKey, tc.name)
 		} else {
 			assert.NotNil(t, cert, tc.name)
 			assert.Nil(t, err, tc.name)
@@ -150,29 +134,23 @@ func TestGetCertificateKey(t *testing.T) {
 }
 
 // general test of function work
-func TesCertParsing(t *testing.T) {
-
-	// config env
-	// JWT config
-	conf := Config{
-		certVersion:         "1",
-		tokenExpireDuration: 10, // minutes
-		pathCert:            "../../../pkg/storage/certificates",
-	}
+func TestCertParsing(t *testing.T) {
+	km, err := NewKeyManager(&Config{pathCert: "../../../pkg/storage/certificates", certVersion: "1", tokenExpireDuration: 10})
+	require.NoError(t, err)
 
 	// create user
-	user := model.User{
+	user := model.Identity{
 		Name: "bob",
 	}
 
-	tokenString, err := CreateUserJWTToken(user.Name, &conf)
+	tokenString, _, err := km.CreateUserJWTToken(user, "1")
 	assert.Nil(t, err)
 
-	pbKeyBytes, err := GetCertificateKey(conf.certVersion, &conf)
+	pbKeyBytes, err := km.PublicKeyPEM(km.ActiveVersion())
 	assert.Nil(t, err)
 
 	block, _ := pem.Decode(pbKeyBytes)
-	assert.NotNil(t, block, "block")
+	require.NotNil(t, block, "block")
 
 	pubInterface, _ := x509.ParsePKIXPublicKey(block.Bytes)
 
@@ -182,10 +160,31 @@ func TesCertParsing(t *testing.T) {
 	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return pub, nil
 	})
+	require.Nil(t, err)
+
+	claims, ok := token.Claims.(*UserClaims)
+	require.True(t, ok)
+	assert.Equal(t, user.Name, claims.Name)
+}
+
+func TestKeyManager_JWKS(t *testing.T) {
+	km, err := NewKeyManager(&Config{pathCert: "../../../pkg/storage/certificates", certVersion: "2", tokenExpireDuration: 10})
+	require.NoError(t, err)
+
+	jwks := km.JWKS()
+	assert.Len(t, jwks.Keys, 3)
+
+	tokenString, _, err := km.CreateUserJWTToken(model.Identity{Name: "bob"}, "1")
 	assert.Nil(t, err)
 
-	if claims, ok := token.Claims.(*UserClaims); ok {
-		assert.Equal(t, user.Name, claims.Name)
-		fmt.Println(claims.ExpiresAt)
-	}
+	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
+		for _, jwk := range jwks.Keys {
+			if jwk.Kid == token.Header["kid"] {
+				return ParseJWK(jwk)
+			}
+		}
+		return nil, fmt.Errorf("unknown kid %v", token.Header["kid"])
+	})
+	assert.Nil(t, err)
+	assert.True(t, token.Valid)
 }
@@ -0,0 +1,343 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+const keyBits = 2048
+
+// stateFile keeps the active version and the retirement times next to the keys
+const stateFile = "keys.json"
+
+var ErrUnknownKey = errors.New("unknown key version")
+
+type signingKey struct {
+	key       *rsa.PrivateKey
+	retiredAt time.Time
+}
+
+// keyState is what survives a restart, so a rotation is not undone by CERT_VERSION
+type keyState struct {
+	Active  string               `json:"active"`
+	Retired map[string]time.Time `json:"retired,omitempty"`
+}
+
+// KeyManager keeps every signing key version in memory. New keys are published
+// before they are promoted and retired keys stay published until tokens signed with them expire.
+type KeyManager struct {
+	mu        sync.RWMutex
+	pathCert  string
+	active    string
+	keys      map[string]*signingKey
+	tokenLife time.Duration
+}
+
+// NewKeyManager loads all key versions from the certificate path. The version promoted last is active,
+// the configured version only until the first promotion. Keys expired in the meantime are pruned.
+func NewKeyManager(conf *Config) (*KeyManager, error) {
+	km := &KeyManager{
+		pathCert:  conf.pathCert,
+		keys:      make(map[string]*signingKey),
+		tokenLife: time.Duration(conf.tokenExpireDuration) * time.Minute,
+	}
+
+	for _, version := range keyVersions(conf.pathCert) {
+		key, err := readRSAPrivateKey(version, conf.pathCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key version %s: %w", version, err)
+		}
+		km.keys[version] = &signingKey{key: key}
+	}
+
+	state, err := readKeyState(conf.pathCert)
+	if err != nil {
+		return nil, err
+	}
+
+	km.active = conf.certVersion
+	if state.Active != "" {
+		km.active = state.Active
+	}
+	if _, ok := km.keys[km.active]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, km.active)
+	}
+
+	for version, retiredAt := range state.Retired {
+		if key, ok := km.keys[version]; ok && version != km.active {
+			key.retiredAt = retiredAt
+		}
+	}
+
+	if err = km.prune(); err != nil {
+		return nil, err
+	}
+
+	return km, nil
+}
+
+func readKeyState(path string) (keyState, error) {
+	var state keyState
+
+	b, err := ioutil.ReadFile(filepath.Join(path, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	if err = json.Unmarshal(b, &state); err != nil {
+		return state, fmt.Errorf("failed to read key state: %w", err)
+	}
+
+	return state, nil
+}
+
+// saveState writes the active version and retirement times, the caller holds km.mu
+func (km *KeyManager) saveState() error {
+	state := keyState{
+		Active:  km.active,
+		Retired: make(map[string]time.Time),
+	}
+	for version, key := range km.keys {
+		if !key.retiredAt.IsZero() {
+			state.Retired[version] = key.retiredAt
+		}
+	}
+
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	// write and rename, so a crash never leaves a half written state behind
+	tmp := filepath.Join(km.pathCert, stateFile+".tmp")
+	if err = ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(km.pathCert, stateFile))
+}
+
+// prune deletes retired keys every token of which has expired from memory and disk, the caller holds km.mu
+func (km *KeyManager) prune() error {
+	pruned := false
+	for version, key := range km.keys {
+		if version == km.active || !km.expired(key) {
+			continue
+		}
+
+		if err := os.Remove(getCertName(km.pathCert, version)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove key version %s: %w", version, err)
+		}
+		delete(km.keys, version)
+		pruned = true
+	}
+
+	if !pruned {
+		return nil
+	}
+
+	return km.saveState()
+}
+
+// keyVersions lists versions of the key files in the path
+func keyVersions(path string) []string {
+	files, _ := filepath.Glob(getCertName(path, "*"))
+
+	versions := make([]string, 0, len(files))
+	for _, file := range files {
+		versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "private_key"), ".pem"))
+	}
+	sort.Strings(versions)
+
+	return versions
+}
+
+func (km *KeyManager) ActiveVersion() string {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	return km.active
+}
+
+// Check fails when the active key can not sign tokens
+func (km *KeyManager) Check() error {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	key, ok := km.keys[km.active]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, km.active)
+	}
+
+	return key.key.Validate()
+}
+
+// Generate creates and stores a new key version without making it active
+func (km *KeyManager) Generate() (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return "", err
+	}
+
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	version := km.nextVersion()
+
+	block := &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}
+	if err = ioutil.WriteFile(getCertName(km.pathCert, version), pem.EncodeToMemory(block), 0600); err != nil {
+		return "", err
+	}
+
+	km.keys[version] = &signingKey{key: key}
+
+	return version, nil
+}
+
+// nextVersion is one more than the highest numeric version, the caller holds km.mu
+func (km *KeyManager) nextVersion() string {
+	max := 0
+	for version := range km.keys {
+		if n, err := strconv.Atoi(version); err == nil && n > max {
+			max = n
+		}
+	}
+
+	return strconv.Itoa(max + 1)
+}
+
+// Promote makes the version active and retires the previously active key. The change is persisted
+// before it takes effect and keys that expired meanwhile are pruned.
+func (km *KeyManager) Promote(version string) error {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	key, ok := km.keys[version]
+	if !ok || km.expired(key) {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, version)
+	}
+
+	if version == km.active {
+		return nil
+	}
+
+	previous, retiredAt := km.active, key.retiredAt
+	km.keys[previous].retiredAt = time.Now()
+	key.retiredAt = time.Time{}
+	km.active = version
+
+	if err := km.saveState(); err != nil {
+		km.keys[previous].retiredAt = time.Time{}
+		key.retiredAt = retiredAt
+		km.active = previous
+		return fmt.Errorf("failed to save key state: %w", err)
+	}
+
+	return km.prune()
+}
+
+// Rotate generates a new key and promotes it
+func (km *KeyManager) Rotate() (string, error) {
+	version, err := km.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	return version, km.Promote(version)
+}
+
+// expired tells whether every token signed with a retired key has expired, the caller holds km.mu
+func (km *KeyManager) expired(key *signingKey) bool {
+	return !key.retiredAt.IsZero() && time.Since(key.retiredAt) > km.tokenLife
+}
+
+// CreateUserJWTToken signs a token with the given id for the user with the active key, the user id becomes
+// the subject. It returns when the token expires
+func (km *KeyManager) CreateUserJWTToken(user model.Identity, jti string) (string, time.Time, error) {
+	km.mu.RLock()
+	version, key := km.active, km.keys[km.active].key
+	km.mu.RUnlock()
+
+	claims := newClaim(user.Name, version)
+	claims.Id = jti
+	claims.Subject = user.UserID.String()
+	claims.Roles = user.Roles
+	claims.addExpTime(int(km.tokenLife / time.Minute))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS512, claims)
+	token.Header["kid"] = version
+
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, time.Unix(claims.ExpiresAt, 0), nil
+}
+
+// JWKS publishes the keys tokens may still be signed with
+func (km *KeyManager) JWKS() model.JWKS {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	versions := make([]string, 0, len(km.keys))
+	for version, key := range km.keys {
+		if !km.expired(key) {
+			versions = append(versions, version)
+		}
+	}
+	sort.Strings(versions)
+
+	jwks := model.JWKS{
+		Keys: []model.JWK{},
+	}
+	for _, version := range versions {
+		jwks.Keys = append(jwks.Keys, publicJWK(version, &km.keys[version].key.PublicKey))
+	}
+
+	return jwks
+}
+
+// PublicKeyPEM returns the public key of the version in PEM
+func (km *KeyManager) PublicKeyPEM(version string) ([]byte, error) {
+	km.mu.RLock()
+	key, ok := km.keys[version]
+	km.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, version)
+	}
+
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&key.key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyBytes,
+	}), nil
+}
@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// copyKeys copies the test certificates, so generated keys do not end up in the repository
+func copyKeys(t *testing.T) string {
+	dir := t.TempDir()
+	for _, version := range keyVersions("../../../pkg/storage/certificates") {
+		b, err := ioutil.ReadFile(getCertName("../../../pkg/storage/certificates", version))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(getCertName(dir, version), b, 0600))
+	}
+
+	return dir
+}
+
+func kids(km *KeyManager) []string {
+	var res []string
+	for _, key := range km.JWKS().Keys {
+		res = append(res, key.Kid)
+	}
+
+	return res
+}
+
+func TestNewKeyManager(t *testing.T) {
+	km, err := NewKeyManager(&Config{pathCert: "../../../pkg/storage/certificates", certVersion: "2", tokenExpireDuration: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "2", km.ActiveVersion())
+	assert.Equal(t, []string{"1", "2", "3"}, kids(km))
+
+	_, err = NewKeyManager(&Config{pathCert: "../../../pkg/storage/certificates", certVersion: "0", tokenExpireDuration: 10})
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyManager_Check(t *testing.T) {
+	km, err := NewKeyManager(&Config{pathCert: "../../../pkg/storage/certificates", certVersion: "2", tokenExpireDuration: 10})
+	require.NoError(t, err)
+	assert.NoError(t, km.Check())
+
+	km.active = "0"
+	assert.ErrorIs(t, km.Check(), ErrUnknownKey)
+}
+
+func TestKeyManager_Rotate(t *testing.T) {
+	dir := copyKeys(t)
+	km, err := NewKeyManager(&Config{pathCert: dir, certVersion: "3", tokenExpireDuration: 10})
+	require.NoError(t, err)
+
+	oldToken, _, err := km.CreateUserJWTToken(model.Identity{Name: "bob"}, "1")
+	require.NoError(t, err)
+
+	version, err := km.Generate()
+	require.NoError(t, err)
+	assert.Equal(t, "4", version)
+	assert.Equal(t, "3", km.ActiveVersion(), "generated key is not used before promotion")
+	assert.Contains(t, kids(km), "4", "generated key is published before promotion")
+	assert.FileExists(t, filepath.Join(dir, "private_key4.pem"))
+
+	require.NoError(t, km.Promote(version))
+	assert.Equal(t, "4", km.ActiveVersion())
+	assert.ErrorIs(t, km.Promote("42"), ErrUnknownKey)
+
+	newToken, expiresAt, err := km.CreateUserJWTToken(model.Identity{Name: "bob"}, "2")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), expiresAt, time.Minute)
+
+	for _, tokenString := range []string{oldToken, newToken} {
+		token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
+			for _, jwk := range km.JWKS().Keys {
+				if jwk.Kid == token.Header["kid"] {
+					return ParseJWK(jwk)
+				}
+			}
+			return nil, ErrUnknownKey
+		})
+		require.NoError(t, err)
+		assert.True(t, token.Valid)
+	}
+
+	// once tokens of the retired key expired it is not published anymore
+	km.tokenLife = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	assert.NotContains(t, kids(km), "3")
+	assert.Contains(t, kids(km), "4")
+	assert.ErrorIs(t, km.Promote("3"), ErrUnknownKey)
+
+	version, err = km.Rotate()
+	require.NoError(t, err)
+	assert.Equal(t, "5", version)
+	assert.Equal(t, "5", km.ActiveVersion())
+
+	// the rotation prunes the expired key from memory and disk
+	assert.NotContains(t, km.keys, "3")
+	assert.NoFileExists(t, filepath.Join(dir, "private_key3.pem"))
+}
+
+func TestKeyManager_Restart(t *testing.T) {
+	dir := copyKeys(t)
+	km, err := NewKeyManager(&Config{pathCert: dir, certVersion: "2", tokenExpireDuration: 10})
+	require.NoError(t, err)
+
+	version, err := km.Rotate()
+	require.NoError(t, err)
+	require.NoError(t, km.Promote("3"))
+
+	// the configured version does not undo the rotation
+	km, err = NewKeyManager(&Config{pathCert: dir, certVersion: "2", tokenExpireDuration: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "3", km.ActiveVersion())
+	assert.False(t, km.keys["2"].retiredAt.IsZero(), "retirement survives the restart")
+	assert.False(t, km.keys[version].retiredAt.IsZero(), "retirement survives the restart")
+
+	// keys retired longer than a token lives are pruned on start
+	time.Sleep(10 * time.Millisecond)
+	km, err = NewKeyManager(&Config{pathCert: dir, certVersion: "2", tokenExpireDuration: 0})
+	require.NoError(t, err)
+	assert.Equal(t, "3", km.ActiveVersion())
+	assert.Equal(t, []string{"1", "3"}, kids(km))
+	assert.NoFileExists(t, filepath.Join(dir, "private_key2.pem"))
+	assert.NoFileExists(t, getCertName(dir, version))
+}
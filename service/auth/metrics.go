@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// results of the login and refresh token counters
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+	resultInvalid = "invalid"
+	resultReused  = "reused"
+)
+
+// Option configures a Session
+type Option func(*Session)
+
+// WithMetrics registers the login and token counters of the session
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(s *Session) {
+		reg.MustRegister(s.metrics.logins, s.metrics.issued, s.metrics.refreshes)
+	}
+}
+
+type sessionMetrics struct {
+	logins    *prometheus.CounterVec
+	issued    *prometheus.CounterVec
+	refreshes *prometheus.CounterVec
+}
+
+func newSessionMetrics() sessionMetrics {
+	return sessionMetrics{
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_logins_total",
+			Help: "Logins by result, success or failure.",
+		}, []string{"result"}),
+		issued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_tokens_issued_total",
+			Help: "Token pairs issued by reason, login or refresh.",
+		}, []string{"reason"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_refresh_token_verifications_total",
+			Help: "Refresh tokens presented by result, success, invalid, reused or failure.",
+		}, []string{"result"}),
+	}
+}
@@ -1,11 +1,44 @@
 package auth
 
-import "github.com/stasBigunenko/monorepa/model"
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/model"
+	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	"github.com/stasBigunenko/monorepa/service/auth/jwt"
+)
 
 type Session struct {
+	credentials newStorage.CredentialStore
+	tokens      newStorage.TokenStore
+	users       UserService
+	keys        *jwt.KeyManager
+	refreshLife time.Duration
+	metrics     sessionMetrics
 }
 
 type Service interface {
-	Login(model.User) (string, error)
+	Login(model.User) (model.TokenPair, error)
+	Refresh(context.Context, string) (model.TokenPair, error)
+	Logout(context.Context, string) error
+	RevokedTokens(context.Context) ([]model.RevokedToken, error)
+	Register(context.Context, model.User) (uuid.UUID, error)
+	ChangePassword(context.Context, model.PasswordChange) error
+	SetRole(context.Context, string, string) error
+	DisableUser(context.Context, uuid.UUID) error
+	EnableUser(context.Context, uuid.UUID) error
 	GetCert(string) ([]byte, error)
+	GetJWKS() (model.JWKS, error)
+	GenerateKey() (string, error)
+	PromoteKey(string) error
+	RotateKey() (string, error)
+}
+
+// UserService creates the user a login name is registered for, implemented by the user gRPC client
+type UserService interface {
+	CreateUser(ctx context.Context, name string) (uuid.UUID, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
 }
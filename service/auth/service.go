@@ -1,51 +1,209 @@
 package auth
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
 	er "github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/model"
+	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
 	"github.com/stasBigunenko/monorepa/service/auth/jwt"
 )
 
 // init item services
-func New() *Session {
-	return &Session{}
+func New(store newStorage.AuthStore, users UserService, keys *jwt.KeyManager, refreshLife time.Duration, opts ...Option) *Session {
+	s := &Session{
+		credentials: store,
+		tokens:      store,
+		users:       users,
+		keys:        keys,
+		refreshLife: refreshLife,
+		metrics:     newSessionMetrics(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-// verify user for login
-func userVerify(password string) bool {
-	return password != ""
+// verify user data before it is checked or stored
+func userVerify(user model.User) error {
+	if strings.TrimSpace(user.Name) == "" {
+		return er.WrongName
+	}
+
+	if user.Password == "" {
+		return er.WrongPassword
+	}
+
+	return nil
 }
 
-// Create JWT tocken for user
-func (s *Session) Login(user model.User) (string, error) {
-	if ok := userVerify(user.Password); !ok {
-		return "", er.WrongPassword
+// check the password of a registered name
+func (s *Session) authenticate(ctx context.Context, user model.User) (model.Credential, error) {
+	if err := userVerify(user); err != nil {
+		return model.Credential{}, err
 	}
 
-	conf, err := jwt.NewJTWConfig()
+	cred, err := s.credentials.GetCredential(ctx, user.Name)
+	if errors.Is(err, er.NotFound) {
+		checkPassword(string(dummyHash), user.Password) //nolint:errcheck
+		return model.Credential{}, er.InvalidCredentials
+	}
 	if err != nil {
-		return "", err
+		return model.Credential{}, err
+	}
+
+	if err = checkPassword(cred.PasswordHash, user.Password); err != nil {
+		return model.Credential{}, err
+	}
+
+	// the user was deleted
+	if cred.Disabled {
+		return model.Credential{}, er.InvalidCredentials
 	}
 
-	token, err := jwt.CreateUserJWTToken(user.Name, conf)
+	return cred, nil
+}
+
+// Create JWT tocken and a refresh token of a new family for user
+func (s *Session) Login(user model.User) (model.TokenPair, error) {
+	ctx := context.Background()
+
+	cred, err := s.authenticate(ctx, user)
 	if err != nil {
-		return "", err
+		s.metrics.logins.WithLabelValues(resultFailure).Inc()
+		return model.TokenPair{}, err
 	}
+	s.metrics.logins.WithLabelValues(resultSuccess).Inc()
 
-	return token, nil
+	pair, err := s.issue(ctx, cred, uuid.New())
+	if err == nil {
+		s.metrics.issued.WithLabelValues("login").Inc()
+	}
+
+	return pair, err
 }
 
-// Get certificate for user
-func (s *Session) GetCert(keyCertVersion string) ([]byte, error) {
-	conf, err := jwt.NewJTWConfig()
+// Register creates the user in the user service and stores the password hash for its name
+func (s *Session) Register(ctx context.Context, user model.User) (uuid.UUID, error) {
+	if err := userVerify(user); err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err := s.credentials.GetCredential(ctx, user.Name)
+	if err == nil {
+		return uuid.Nil, er.AlreadyExists
+	}
+	if !errors.Is(err, er.NotFound) {
+		return uuid.Nil, err
+	}
+
+	hash, err := hashPassword(user.Password)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// the new user registers itself as far as the audit trail is concerned
+	ctx = context.WithValue(ctx, model.IdentityKey, model.Identity{Name: user.Name})
+
+	id, err := s.users.CreateUser(ctx, user.Name)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = s.credentials.CreateCredential(ctx, model.Credential{
+		Name:         user.Name,
+		UserID:       id,
+		PasswordHash: hash,
+		Role:         model.RoleUser,
+	})
 	if err != nil {
-		return nil, err
+		// do not leave a user nobody can log in as
+		s.users.DeleteUser(ctx, id) //nolint:errcheck
+		return uuid.Nil, err
 	}
 
-	res, err := jwt.GetCertificateKey(keyCertVersion, conf)
+	return id, nil
+}
+
+func (s *Session) ChangePassword(ctx context.Context, change model.PasswordChange) error {
+	cred, err := s.authenticate(ctx, model.User{Name: change.Name, Password: change.Password})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return res, nil
+	if change.NewPassword == "" {
+		return er.WrongPassword
+	}
+
+	hash, err := hashPassword(change.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.credentials.UpdatePassword(ctx, cred.Name, hash)
+}
+
+// SetRole grants the role to a registered name, it is carried by tokens issued afterwards
+func (s *Session) SetRole(ctx context.Context, name, role string) error {
+	if role != model.RoleUser && role != model.RoleAdmin {
+		return er.InvalidArgument
+	}
+
+	return s.credentials.UpdateRole(ctx, name, role)
+}
+
+// DisableUser disables the logins of a deleted user and revokes every token issued to them,
+// a user without credentials has nothing to disable
+func (s *Session) DisableUser(ctx context.Context, id uuid.UUID) error {
+	names, err := s.credentials.SetDisabled(ctx, id, true)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err = s.tokens.RevokeName(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnableUser lets a user log in again once its delete was undone, revoked tokens stay revoked
+func (s *Session) EnableUser(ctx context.Context, id uuid.UUID) error {
+	_, err := s.credentials.SetDisabled(ctx, id, false)
+
+	return err
+}
+
+// Get certificate for user
+func (s *Session) GetCert(keyCertVersion string) ([]byte, error) {
+	return s.keys.PublicKeyPEM(keyCertVersion)
+}
+
+// Get public keys tokens may be signed with
+func (s *Session) GetJWKS() (model.JWKS, error) {
+	return s.keys.JWKS(), nil
+}
+
+// Generate a key that is published but not used for signing yet
+func (s *Session) GenerateKey() (string, error) {
+	return s.keys.Generate()
+}
+
+// Start signing with the key version
+func (s *Session) PromoteKey(version string) error {
+	return s.keys.Promote(version)
+}
+
+// Generate a key and start signing with it
+func (s *Session) RotateKey() (string, error) {
+	return s.keys.Rotate()
 }
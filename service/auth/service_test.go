@@ -0,0 +1,336 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	er "github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	"github.com/stasBigunenko/monorepa/service/auth/jwt"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
+)
+
+type MockUserService struct {
+	MockCreateUser func(ctx context.Context, name string) (uuid.UUID, error)
+	MockDeleteUser func(ctx context.Context, id uuid.UUID) error
+}
+
+func (m MockUserService) CreateUser(ctx context.Context, name string) (uuid.UUID, error) {
+	return m.MockCreateUser(ctx, name)
+}
+
+func (m MockUserService) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return m.MockDeleteUser(ctx, id)
+}
+
+func newKeys(t *testing.T) *jwt.KeyManager {
+	t.Setenv("CERT_VERSION", "1")
+	t.Setenv("TOKEN_EXPIRE", "10")
+	t.Setenv("CERT_PATH", "../../pkg/storage/certificates")
+
+	conf, err := jwt.NewJTWConfig()
+	require.NoError(t, err)
+	keys, err := jwt.NewKeyManager(conf)
+	require.NoError(t, err)
+
+	return keys
+}
+
+func TestSession_RegisterLogin(t *testing.T) {
+	id := uuid.New()
+	users := MockUserService{
+		MockCreateUser: func(_ context.Context, name string) (uuid.UUID, error) {
+			return id, nil
+		},
+	}
+	credentials := newStorage.NewDB(loggingservice.LoggingService{})
+	s := New(credentials, users, newKeys(t), time.Hour)
+
+	got, err := s.Register(context.Background(), model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	cred, err := credentials.GetCredential(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, id, cred.UserID)
+	assert.NotEqual(t, "123123", cred.PasswordHash, "password must be stored hashed")
+
+	_, err = s.Register(context.Background(), model.User{Name: "bob", Password: "123"})
+	assert.ErrorIs(t, err, er.AlreadyExists)
+
+	tests := []struct {
+		name    string
+		user    model.User
+		wantErr error
+	}{
+		{
+			name: "Everything ok",
+			user: model.User{Name: "bob", Password: "123123"},
+		},
+		{
+			name:    "Wrong password",
+			user:    model.User{Name: "bob", Password: "321321"},
+			wantErr: er.InvalidCredentials,
+		},
+		{
+			name:    "Unknown name",
+			user:    model.User{Name: "alice", Password: "123123"},
+			wantErr: er.InvalidCredentials,
+		},
+		{
+			name:    "Empty password",
+			user:    model.User{Name: "bob"},
+			wantErr: er.WrongPassword,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := s.Login(tc.user)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				assert.Empty(t, token)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, token)
+		})
+	}
+}
+
+func TestSession_RegisterCompensation(t *testing.T) {
+	id := uuid.New()
+	deleted := uuid.Nil
+	users := MockUserService{
+		MockCreateUser: func(_ context.Context, name string) (uuid.UUID, error) {
+			return id, nil
+		},
+		MockDeleteUser: func(_ context.Context, userID uuid.UUID) error {
+			deleted = userID
+			return nil
+		},
+	}
+	s := New(failingCredentials{newStorage.NewDB(loggingservice.LoggingService{})}, users, nil, time.Hour)
+
+	_, err := s.Register(context.Background(), model.User{Name: "bob", Password: "123123"})
+	assert.Error(t, err)
+	assert.Equal(t, id, deleted, "created user must be removed when the credential is not stored")
+}
+
+func TestSession_ChangePassword(t *testing.T) {
+	users := MockUserService{
+		MockCreateUser: func(_ context.Context, name string) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	s := New(newStorage.NewDB(loggingservice.LoggingService{}), users, newKeys(t), time.Hour)
+
+	_, err := s.Register(context.Background(), model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+
+	err = s.ChangePassword(context.Background(), model.PasswordChange{Name: "bob", Password: "wrong", NewPassword: "321321"})
+	assert.ErrorIs(t, err, er.InvalidCredentials)
+
+	err = s.ChangePassword(context.Background(), model.PasswordChange{Name: "bob", Password: "123123"})
+	assert.ErrorIs(t, err, er.WrongPassword)
+
+	require.NoError(t, s.ChangePassword(context.Background(), model.PasswordChange{Name: "bob", Password: "123123", NewPassword: "321321"}))
+
+	_, err = s.Login(model.User{Name: "bob", Password: "123123"})
+	assert.ErrorIs(t, err, er.InvalidCredentials)
+
+	_, err = s.Login(model.User{Name: "bob", Password: "321321"})
+	assert.NoError(t, err)
+}
+
+// failingCredentials fails to store new credentials
+type failingCredentials struct {
+	newStorage.AuthStore
+}
+
+func (failingCredentials) CreateCredential(context.Context, model.Credential) error {
+	return errors.New("storage is down")
+}
+
+func TestSession_Refresh(t *testing.T) {
+	users := MockUserService{
+		MockCreateUser: func(_ context.Context, name string) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	store := newStorage.NewDB(loggingservice.LoggingService{})
+	s := New(store, users, newKeys(t), time.Hour)
+	ctx := context.Background()
+
+	_, err := s.Register(ctx, model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+
+	first, err := s.Login(model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.RefreshToken)
+
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.NotEqual(t, first.AccessToken, second.AccessToken)
+
+	_, err = s.Refresh(ctx, "unknown")
+	assert.ErrorIs(t, err, er.InvalidToken)
+
+	// presenting the rotated token again revokes the whole family
+	_, err = s.Refresh(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, er.TokenReused)
+
+	_, err = s.Refresh(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, er.InvalidToken)
+
+	revoked, err := s.RevokedTokens(ctx)
+	require.NoError(t, err)
+	assert.Len(t, revoked, 2, "access tokens of the family must be denylisted")
+}
+
+func TestSession_Logout(t *testing.T) {
+	users := MockUserService{
+		MockCreateUser: func(_ context.Context, name string) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	s := New(newStorage.NewDB(loggingservice.LoggingService{}), users, newKeys(t), time.Hour)
+	ctx := context.Background()
+
+	_, err := s.Register(ctx, model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+
+	tokens, err := s.Login(model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.Logout(ctx, "unknown"), er.InvalidToken)
+	require.NoError(t, s.Logout(ctx, tokens.RefreshToken))
+
+	_, err = s.Refresh(ctx, tokens.RefreshToken)
+	assert.Error(t, err)
+
+	revoked, err := s.RevokedTokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, revoked, 1)
+}
+
+func TestSession_SetRole(t *testing.T) {
+	users := MockUserService{
+		MockCreateUser: func(_ context.Context, name string) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	s := New(newStorage.NewDB(loggingservice.LoggingService{}), users, newKeys(t), time.Hour)
+	ctx := context.Background()
+
+	id, err := s.Register(ctx, model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+
+	tokens, err := s.Login(model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.RoleUser}, tokenClaims(t, tokens.AccessToken).Roles)
+	assert.Equal(t, id.String(), tokenClaims(t, tokens.AccessToken).Subject, "the user id is the subject")
+
+	assert.ErrorIs(t, s.SetRole(ctx, "bob", "root"), er.InvalidArgument)
+	assert.ErrorIs(t, s.SetRole(ctx, "alice", model.RoleAdmin), er.NotFound)
+	require.NoError(t, s.SetRole(ctx, "bob", model.RoleAdmin))
+
+	// the new role is carried from the next refresh on
+	tokens, err = s.Refresh(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.RoleAdmin}, tokenClaims(t, tokens.AccessToken).Roles)
+}
+
+func TestSession_DisableUser(t *testing.T) {
+	users := MockUserService{
+		MockCreateUser: func(_ context.Context, name string) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	s := New(newStorage.NewDB(loggingservice.LoggingService{}), users, newKeys(t), time.Hour)
+	ctx := context.Background()
+
+	id, err := s.Register(ctx, model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+
+	tokens, err := s.Login(model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.DisableUser(ctx, id))
+	require.NoError(t, s.DisableUser(ctx, uuid.New()), "a user without credentials has nothing to disable")
+
+	_, err = s.Login(model.User{Name: "bob", Password: "123123"})
+	assert.ErrorIs(t, err, er.InvalidCredentials, "a deleted user can not log in")
+
+	_, err = s.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, er.InvalidToken, "tokens of a deleted user are revoked")
+
+	revoked, err := s.RevokedTokens(ctx)
+	require.NoError(t, err)
+	assert.Len(t, revoked, 1, "the access token of a deleted user must be denylisted")
+
+	_, err = s.Register(ctx, model.User{Name: "bob", Password: "123123"})
+	assert.ErrorIs(t, err, er.AlreadyExists, "the name of a deleted user is not given away")
+
+	// an undone delete lets the user log in again
+	require.NoError(t, s.EnableUser(ctx, id))
+	_, err = s.Login(model.User{Name: "bob", Password: "123123"})
+	assert.NoError(t, err)
+}
+
+// tokenClaims reads the claims without verifying the signature
+func tokenClaims(t *testing.T, token string) jwt.UserClaims {
+	var claims jwt.UserClaims
+	_, _, err := new(gojwt.Parser).ParseUnverified(token, &claims)
+	require.NoError(t, err)
+
+	return claims
+}
+
+func TestSession_Metrics(t *testing.T) {
+	users := MockUserService{
+		MockCreateUser: func(_ context.Context, name string) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	reg := metrics.NewRegistry()
+	s := New(newStorage.NewDB(loggingservice.LoggingService{}), users, newKeys(t), time.Hour, WithMetrics(reg))
+	ctx := context.Background()
+
+	_, err := s.Register(ctx, model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+
+	_, err = s.Login(model.User{Name: "bob", Password: "321321"})
+	require.Error(t, err)
+	first, err := s.Login(model.User{Name: "bob", Password: "123123"})
+	require.NoError(t, err)
+	_, err = s.Refresh(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	_, err = s.Refresh(ctx, "unknown")
+	require.Error(t, err)
+	_, err = s.Refresh(ctx, first.RefreshToken)
+	require.Error(t, err)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	got := rec.Body.String()
+
+	assert.Contains(t, got, `auth_logins_total{result="failure"} 1`)
+	assert.Contains(t, got, `auth_logins_total{result="success"} 1`)
+	assert.Contains(t, got, `auth_tokens_issued_total{reason="login"} 1`)
+	assert.Contains(t, got, `auth_tokens_issued_total{reason="refresh"} 1`)
+	assert.Contains(t, got, `auth_refresh_token_verifications_total{result="success"} 1`)
+	assert.Contains(t, got, `auth_refresh_token_verifications_total{result="invalid"} 1`)
+	assert.Contains(t, got, `auth_refresh_token_verifications_total{result="reused"} 1`)
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	er "github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// issue signs an access token carrying the user id and role of the credential and stores
+// a refresh token of the family bound to it
+func (s *Session) issue(ctx context.Context, cred model.Credential, family uuid.UUID) (model.TokenPair, error) {
+	jti := uuid.New().String()
+
+	access, expiresAt, err := s.keys.CreateUserJWTToken(model.Identity{
+		Name:   cred.Name,
+		UserID: cred.UserID,
+		Roles:  []string{cred.Role},
+	}, jti)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	err = s.tokens.CreateRefreshToken(ctx, model.RefreshToken{
+		Hash:            hashRefreshToken(refresh),
+		Family:          family,
+		Name:            cred.Name,
+		ExpiresAt:       time.Now().Add(s.refreshLife),
+		AccessJTI:       jti,
+		AccessExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	return model.TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresAt:    expiresAt.Unix(),
+	}, nil
+}
+
+// Refresh replaces the refresh token with a new one of the same family. A token
+// presented twice means it leaked, so the whole family is revoked.
+func (s *Session) Refresh(ctx context.Context, refresh string) (model.TokenPair, error) {
+	token, err := s.tokens.UseRefreshToken(ctx, hashRefreshToken(refresh))
+	switch {
+	case errors.Is(err, er.TokenReused):
+		s.metrics.refreshes.WithLabelValues(resultReused).Inc()
+		if err = s.tokens.RevokeFamily(ctx, token.Family); err != nil {
+			return model.TokenPair{}, err
+		}
+		return model.TokenPair{}, er.TokenReused
+	case errors.Is(err, er.NotFound) || errors.Is(err, er.InvalidToken):
+		s.metrics.refreshes.WithLabelValues(resultInvalid).Inc()
+		return model.TokenPair{}, er.InvalidToken
+	case err != nil:
+		s.metrics.refreshes.WithLabelValues(resultFailure).Inc()
+		return model.TokenPair{}, err
+	}
+
+	// the role is read again, so a changed role applies from the next refresh
+	cred, err := s.credentials.GetCredential(ctx, token.Name)
+	// the credential of a deleted user is gone or disabled
+	if errors.Is(err, er.NotFound) || (err == nil && cred.Disabled) {
+		s.metrics.refreshes.WithLabelValues(resultInvalid).Inc()
+		return model.TokenPair{}, er.InvalidToken
+	}
+	if err != nil {
+		s.metrics.refreshes.WithLabelValues(resultFailure).Inc()
+		return model.TokenPair{}, err
+	}
+	s.metrics.refreshes.WithLabelValues(resultSuccess).Inc()
+
+	pair, err := s.issue(ctx, cred, token.Family)
+	if err == nil {
+		s.metrics.issued.WithLabelValues("refresh").Inc()
+	}
+
+	return pair, err
+}
+
+// Logout revokes the family of the refresh token together with its access tokens
+func (s *Session) Logout(ctx context.Context, refresh string) error {
+	token, err := s.tokens.UseRefreshToken(ctx, hashRefreshToken(refresh))
+	if errors.Is(err, er.NotFound) {
+		return er.InvalidToken
+	}
+	if err != nil && !errors.Is(err, er.TokenReused) && !errors.Is(err, er.InvalidToken) {
+		return err
+	}
+
+	return s.tokens.RevokeFamily(ctx, token.Family)
+}
+
+// RevokedTokens lists access token ids the gateway must reject
+func (s *Session) RevokedTokens(ctx context.Context) ([]model.RevokedToken, error) {
+	return s.tokens.ListRevokedTokens(ctx)
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// only hashes are stored, so a leaked table can not be replayed
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
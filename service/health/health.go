@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// statuses of the report and of every check in it
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// a dependency answering slower than this is reported down
+const DefaultTimeout = 2 * time.Second
+
+// Check returns an error when the dependency can not be used
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker runs the checks of the dependencies a service needs to serve requests
+type Checker struct {
+	timeout time.Duration
+	names   []string
+	checks  map[string]Check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Add registers the check of a dependency, a name added twice keeps the last check
+func (c *Checker) Add(name string, check Check) {
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Run runs all checks at once, the report is degraded when any of them fails
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(c.names))
+	var wg sync.WaitGroup
+	for i, name := range c.names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = CheckResult{Status: StatusOK}
+			if err := check(ctx); err != nil {
+				results[i] = CheckResult{Status: StatusDown, Error: err.Error()}
+			}
+		}(i, c.checks[name])
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(c.names)),
+	}
+	for i, name := range c.names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// ServeHTTP answers readiness probes, 503 with the failed dependencies while any is down
+func (c *Checker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	report := c.Run(req.Context())
+
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+// Live answers liveness probes, the process serves HTTP so it is alive
+func Live(w http.ResponseWriter, _ *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report) //nolint:errcheck
+}
+
+// GRPC checks a gRPC service through the grpc.health.v1 protocol, the empty service asks for the whole server
+func GRPC(conn grpc.ClientConnInterface, service string) Check {
+	client := healthpb.NewHealthClient(conn)
+
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("service %q is %s", service, resp.Status)
+		}
+
+		return nil
+	}
+}
+
+// NewGRPCServer registers the grpc.health.v1 service on s and reports the server and
+// the given services serving. Shutdown on the returned server reports them not serving.
+func NewGRPCServer(s *grpc.Server, services ...string) *grpchealth.Server {
+	hs := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+
+	for _, service := range append([]string{""}, services...) {
+		hs.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	return hs
+}
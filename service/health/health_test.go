@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker(DefaultTimeout)
+	checker.Add("accounts", func(context.Context) error { return nil })
+	checker.Add("users", func(context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	checker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var report Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, Report{Status: StatusOK, Checks: map[string]CheckResult{
+		"accounts": {Status: StatusOK},
+		"users":    {Status: StatusOK},
+	}}, report)
+
+	checker.Add("users", func(context.Context) error { return errors.New("connection refused") })
+
+	rec = httptest.NewRecorder()
+	checker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	report = Report{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, Report{Status: StatusDegraded, Checks: map[string]CheckResult{
+		"accounts": {Status: StatusOK},
+		"users":    {Status: StatusDown, Error: "connection refused"},
+	}}, report)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	checker := NewChecker(0)
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := checker.Run(context.Background())
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusDown, report.Checks["slow"].Status)
+}
+
+func TestLive(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Live(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
+
+func TestGRPC(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	hs := NewGRPCServer(s, "userGRPC.UserGRPCService")
+	go s.Serve(lis) //nolint:errcheck
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ctx := context.Background()
+	assert.NoError(t, GRPC(conn, "")(ctx))
+	assert.NoError(t, GRPC(conn, "userGRPC.UserGRPCService")(ctx))
+	assert.Error(t, GRPC(conn, "accountGRPC.AccountGRPCService")(ctx), "services not registered are unknown")
+
+	hs.Shutdown()
+	assert.EqualError(t, GRPC(conn, "userGRPC.UserGRPCService")(ctx), `service "userGRPC.UserGRPCService" is NOT_SERVING`)
+}
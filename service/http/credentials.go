@@ -0,0 +1,42 @@
+package httpservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+)
+
+// DisableUser disables the logins of a deleted user in the auth service and revokes their tokens
+func (s HTTPService) DisableUser(ctx context.Context, id uuid.UUID) error {
+	return s.adminCall(ctx, "/admin/users/"+id.String()+"/disable")
+}
+
+// EnableUser lets a user log in again once its delete was undone
+func (s HTTPService) EnableUser(ctx context.Context, id uuid.UUID) error {
+	return s.adminCall(ctx, "/admin/users/"+id.String()+"/enable")
+}
+
+// adminCall posts to an admin route of the auth service, it needs the admin token of the auth service
+func (s HTTPService) adminCall(ctx context.Context, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.authURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Admin-Token", s.adminToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to jwt server: %s: %w", err, customErrors.Unavailable)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("jwt server responded with %s", resp.Status)
+	}
+
+	return nil
+}
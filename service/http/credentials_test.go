@@ -0,0 +1,41 @@
+package httpservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+func TestHTTPService_DisableUser(t *testing.T) {
+	id := uuid.New()
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("X-Admin-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := New(strings.TrimPrefix(srv.URL, "http://"), time.Hour, "secret", loggingservice.LoggingService{})
+	assert.NoError(t, s.DisableUser(context.Background(), id))
+	assert.NoError(t, s.EnableUser(context.Background(), id))
+	assert.Equal(t, []string{"/admin/users/" + id.String() + "/disable", "/admin/users/" + id.String() + "/enable"}, paths)
+
+	s = New(strings.TrimPrefix(srv.URL, "http://"), time.Hour, "", loggingservice.LoggingService{})
+	assert.Error(t, s.DisableUser(context.Background(), id), "the auth service refuses calls without the admin token")
+
+	srv.Close()
+	assert.ErrorIs(t, s.DisableUser(context.Background(), id), customErrors.Unavailable)
+}
@@ -0,0 +1,103 @@
+package httpservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stasBigunenko/monorepa/model"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+// revocations reach the gateway at most this late
+const DefaultDenylistInterval = 10 * time.Second
+
+// Denylist caches the ids of access tokens revoked by the auth service.
+// The list is refreshed in the background once older than the interval, and
+// while the auth service is unreachable the last known list keeps being used.
+type Denylist struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	logger   loggingservice.Logger
+
+	mu        sync.RWMutex
+	revoked   map[string]time.Time
+	fetchedAt time.Time
+
+	loadOnce   sync.Once
+	refreshing int32
+}
+
+func NewDenylist(url string, interval time.Duration, logger loggingservice.Logger) *Denylist {
+	return &Denylist{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		logger:   logger,
+		revoked:  make(map[string]time.Time),
+	}
+}
+
+// Revoked reports whether the access token with the given jti was revoked
+func (d *Denylist) Revoked(jti string) bool {
+	// the first request waits for the list, later ones never block on the auth service
+	d.loadOnce.Do(func() {
+		if err := d.refresh(); err != nil {
+			d.logger.Warn(context.Background(), "failed to load revoked tokens", "error", err)
+		}
+	})
+
+	d.mu.RLock()
+	expiresAt, ok := d.revoked[jti]
+	stale := time.Since(d.fetchedAt) > d.interval
+	d.mu.RUnlock()
+
+	if stale && atomic.CompareAndSwapInt32(&d.refreshing, 0, 1) {
+		go func() {
+			defer atomic.StoreInt32(&d.refreshing, 0)
+			if err := d.refresh(); err != nil {
+				d.logger.Warn(context.Background(), "failed to refresh revoked tokens", "error", err)
+			}
+		}()
+	}
+
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (d *Denylist) refresh() error {
+	d.mu.Lock()
+	// failed attempts also wait for the interval
+	d.fetchedAt = time.Now()
+	d.mu.Unlock()
+
+	resp, err := d.client.Get(d.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to jwt server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwt server responded with %s", resp.Status)
+	}
+
+	var list model.RevokedTokens
+	if err = json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return fmt.Errorf("failed to unmarshal revoked tokens: %w", err)
+	}
+
+	revoked := make(map[string]time.Time, len(list.Revoked))
+	for _, token := range list.Revoked {
+		revoked[token.JTI] = token.ExpiresAt
+	}
+
+	d.mu.Lock()
+	d.revoked = revoked
+	d.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package httpservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+	jwtservice "github.com/stasBigunenko/monorepa/service/auth/jwt"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+// newRevokedServer serves the given revoked tokens, it fails while down is set
+func newRevokedServer(t *testing.T, revoked []model.RevokedToken, hits *int32, down *int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if atomic.LoadInt32(down) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(model.RevokedTokens{Revoked: revoked}) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestDenylist_Revoked(t *testing.T) {
+	var hits, down int32
+	revoked := []model.RevokedToken{
+		{JTI: "revoked", ExpiresAt: time.Now().Add(time.Hour)},
+		{JTI: "expired", ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+	srv := newRevokedServer(t, revoked, &hits, &down)
+
+	d := NewDenylist(srv.URL, time.Hour, loggingservice.LoggingService{})
+
+	assert.True(t, d.Revoked("revoked"))
+	assert.False(t, d.Revoked("expired"))
+	assert.False(t, d.Revoked("other"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits), "the list must be cached")
+}
+
+func TestDenylist_FailsOpen(t *testing.T) {
+	var hits int32
+	down := int32(1)
+	srv := newRevokedServer(t, nil, &hits, &down)
+
+	d := NewDenylist(srv.URL, time.Hour, loggingservice.LoggingService{})
+
+	assert.False(t, d.Revoked("any"), "tokens are accepted while the auth service is down")
+}
+
+func TestHTTPService_ParseRevokedToken(t *testing.T) {
+	var keyHits, keyDown, hits, down int32
+	keySrv := newJWKSServer(t, &keyHits, &keyDown)
+
+	conf, err := jwtservice.NewJTWConfig()
+	require.NoError(t, err)
+	keys, err := jwtservice.NewKeyManager(conf)
+	require.NoError(t, err)
+
+	token, expiresAt, err := keys.CreateUserJWTToken(model.Identity{Name: "bob"}, "jti-1")
+	require.NoError(t, err)
+
+	srv := newRevokedServer(t, []model.RevokedToken{{JTI: "jti-1", ExpiresAt: expiresAt}}, &hits, &down)
+	userID := uuid.New()
+
+	s := HTTPService{
+		keys:    NewKeySet(keySrv.URL, time.Hour, loggingservice.LoggingService{}),
+		revoked: NewDenylist(srv.URL, time.Hour, loggingservice.LoggingService{}),
+	}
+
+	_, err = s.ParseToken("bearer " + token)
+	assert.ErrorIs(t, err, customErrors.InvalidToken)
+
+	other, _, err := keys.CreateUserJWTToken(model.Identity{Name: "bob", UserID: userID, Roles: []string{model.RoleAdmin}}, "jti-2")
+	require.NoError(t, err)
+
+	caller, err := s.ParseToken("bearer " + other)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", caller.Name)
+	assert.Equal(t, userID, caller.UserID)
+	assert.True(t, caller.HasRole(model.RoleAdmin))
+}
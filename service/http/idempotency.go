@@ -0,0 +1,95 @@
+package httpservice
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// retries within this time get the first response again
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotencyCache keeps the first response to every request with an idempotency key.
+// A key is reserved while its request runs, so a retry arriving meanwhile is turned away
+// instead of running the request twice.
+type IdempotencyCache struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	entries    map[string]*idempotencyEntry
+	lastPurged time.Time
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	response    *model.StoredResponse
+	expiresAt   time.Time
+}
+
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{
+		ttl:        ttl,
+		entries:    make(map[string]*idempotencyEntry),
+		lastPurged: time.Now(),
+	}
+}
+
+// Begin reserves the key for a request with the given fingerprint.
+// It returns the stored response when the request was already answered.
+func (c *IdempotencyCache) Begin(key, fingerprint string) (*model.StoredResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.purge(now)
+
+	if e, ok := c.entries[key]; ok && now.Before(e.expiresAt) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, customErrors.IdempotencyKeyReused
+		case e.response == nil:
+			return nil, customErrors.IdempotencyKeyInUse
+		}
+		return e.response, nil
+	}
+
+	// a request that never finishes must not hold the key for good either
+	c.entries[key] = &idempotencyEntry{fingerprint: fingerprint, expiresAt: now.Add(c.ttl)}
+
+	return nil, nil
+}
+
+// Finish stores the response to the request that reserved the key
+func (c *IdempotencyCache) Finish(key string, resp model.StoredResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.response = &resp
+		e.expiresAt = time.Now().Add(c.ttl)
+	}
+}
+
+// Release frees the key without a response, the next request with it runs again
+func (c *IdempotencyCache) Release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// purge drops expired entries, at most once per TTL
+func (c *IdempotencyCache) purge(now time.Time) {
+	if now.Sub(c.lastPurged) < c.ttl {
+		return
+	}
+	c.lastPurged = now
+
+	for key, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package httpservice
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	c := NewIdempotencyCache(time.Hour)
+	created := model.StoredResponse{Status: http.StatusCreated, Header: http.Header{"Location": {"/users/1"}}}
+
+	stored, err := c.Begin("bob/key", "POST /users body")
+	require.NoError(t, err)
+	assert.Nil(t, stored, "the first request runs")
+
+	_, err = c.Begin("bob/key", "POST /users body")
+	assert.ErrorIs(t, err, customErrors.IdempotencyKeyInUse, "a retry waits for the first request")
+
+	c.Finish("bob/key", created)
+	stored, err = c.Begin("bob/key", "POST /users body")
+	require.NoError(t, err)
+	assert.Equal(t, &created, stored)
+
+	_, err = c.Begin("bob/key", "POST /users other body")
+	assert.ErrorIs(t, err, customErrors.IdempotencyKeyReused)
+
+	stored, err = c.Begin("alice/key", "POST /users other body")
+	require.NoError(t, err)
+	assert.Nil(t, stored, "keys of other callers are independent")
+
+	c.Release("alice/key")
+	stored, err = c.Begin("alice/key", "POST /users body")
+	require.NoError(t, err)
+	assert.Nil(t, stored, "a released key runs again")
+}
+
+func TestIdempotencyCache_Expired(t *testing.T) {
+	c := NewIdempotencyCache(time.Hour)
+
+	_, err := c.Begin("key", "POST /users body")
+	require.NoError(t, err)
+	c.Finish("key", model.StoredResponse{Status: http.StatusCreated})
+
+	c.entries["key"].expiresAt = time.Now().Add(-time.Second)
+	c.lastPurged = time.Now().Add(-2 * time.Hour)
+
+	stored, err := c.Begin("key", "POST /users other body")
+	require.NoError(t, err)
+	assert.Nil(t, stored, "an expired key is free again")
+
+	c.entries["other"] = &idempotencyEntry{expiresAt: time.Now().Add(-time.Second)}
+	c.lastPurged = time.Now().Add(-2 * time.Hour)
+	_, err = c.Begin("key", "POST /users other body")
+	assert.ErrorIs(t, err, customErrors.IdempotencyKeyInUse)
+	assert.NotContains(t, c.entries, "other", "expired keys are purged")
+}
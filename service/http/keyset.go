@@ -0,0 +1,168 @@
+package httpservice
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stasBigunenko/monorepa/model"
+	jwtservice "github.com/stasBigunenko/monorepa/service/auth/jwt"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+const (
+	DefaultKeySetTTL = 5 * time.Minute
+
+	// the key set is fetched at most this often, so forged kids or an auth outage do not flood the auth service
+	minRefreshInterval = 10 * time.Second
+)
+
+// KeySet caches the public keys published by the auth service JWKS endpoint.
+// Keys older than the TTL are refreshed in the background and served meanwhile,
+// so verification keeps working while the auth service is briefly down.
+type KeySet struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+	logger loggingservice.Logger
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+
+	refreshMu   sync.Mutex
+	refreshing  int32
+	minInterval time.Duration
+}
+
+func NewKeySet(url string, ttl time.Duration, logger loggingservice.Logger) *KeySet {
+	return &KeySet{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+		logger: logger,
+		keys:   make(map[string]*rsa.PublicKey),
+
+		minInterval: minRefreshInterval,
+	}
+}
+
+// Key returns the public key with the given kid
+func (ks *KeySet) Key(kid string) (*rsa.PublicKey, error) {
+	ks.mu.RLock()
+	key, ok := ks.keys[kid]
+	stale := time.Since(ks.fetchedAt) > ks.ttl
+	ks.mu.RUnlock()
+
+	if ok {
+		if stale && atomic.CompareAndSwapInt32(&ks.refreshing, 0, 1) {
+			go func() {
+				defer atomic.StoreInt32(&ks.refreshing, 0)
+				if err := ks.refresh(); err != nil {
+					ks.logger.Warn(context.Background(), "failed to refresh jwks", "error", err)
+				}
+			}()
+		}
+		return key, nil
+	}
+
+	if err := ks.refresh(); err != nil {
+		return nil, err
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok = ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return key, nil
+}
+
+// Check fetches the key set when none was fetched yet and fails while there are no keys to verify tokens with
+func (ks *KeySet) Check() error {
+	ks.mu.RLock()
+	empty := len(ks.keys) == 0
+	ks.mu.RUnlock()
+	if !empty {
+		return nil
+	}
+
+	if err := ks.refresh(); err != nil {
+		return err
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if len(ks.keys) == 0 {
+		return fmt.Errorf("no keys from the jwt server at %s", ks.url)
+	}
+
+	return nil
+}
+
+// refresh fetches the key set unless the last attempt was recent
+func (ks *KeySet) refresh() error {
+	ks.refreshMu.Lock()
+	defer ks.refreshMu.Unlock()
+
+	ks.mu.RLock()
+	recent := time.Since(ks.attemptedAt) < ks.minInterval
+	ks.mu.RUnlock()
+	if recent {
+		return nil
+	}
+
+	ks.mu.Lock()
+	ks.attemptedAt = time.Now()
+	ks.mu.Unlock()
+
+	keys, err := ks.fetch()
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+	ks.mu.Unlock()
+
+	return nil
+}
+
+func (ks *KeySet) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := ks.client.Get(ks.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to jwt server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt server responded with %s", resp.Status)
+	}
+
+	var jwks model.JWKS
+	if err = json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		key, err := jwtservice.ParseJWK(jwk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
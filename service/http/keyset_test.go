@@ -0,0 +1,137 @@
+package httpservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stasBigunenko/monorepa/model"
+	jwtservice "github.com/stasBigunenko/monorepa/service/auth/jwt"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+const certPath = "../../pkg/storage/certificates"
+
+// newJWKSServer serves the test certificates, it fails while down is set
+func newJWKSServer(t *testing.T, hits *int32, down *int32) *httptest.Server {
+	t.Setenv("CERT_VERSION", "1")
+	t.Setenv("TOKEN_EXPIRE", "10")
+	t.Setenv("CERT_PATH", certPath)
+
+	conf, err := jwtservice.NewJTWConfig()
+	require.NoError(t, err)
+	km, err := jwtservice.NewKeyManager(conf)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if atomic.LoadInt32(down) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(km.JWKS()) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestKeySet_Key(t *testing.T) {
+	var hits, down int32
+	srv := newJWKSServer(t, &hits, &down)
+
+	ks := NewKeySet(srv.URL, time.Hour, loggingservice.LoggingService{})
+	ks.minInterval = 0
+
+	key, err := ks.Key("1")
+	require.NoError(t, err)
+	assert.NotNil(t, key)
+
+	_, err = ks.Key("2")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits), "cached keys must not be fetched again")
+
+	_, err = ks.Key("42")
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits), "unknown kid must refresh the key set")
+}
+
+func TestKeySet_SurvivesOutage(t *testing.T) {
+	var hits, down int32
+	srv := newJWKSServer(t, &hits, &down)
+
+	ks := NewKeySet(srv.URL, time.Millisecond, loggingservice.LoggingService{})
+	ks.minInterval = 0
+
+	_, err := ks.Key("1")
+	require.NoError(t, err)
+
+	atomic.StoreInt32(&down, 1)
+	time.Sleep(5 * time.Millisecond)
+
+	key, err := ks.Key("1")
+	require.NoError(t, err, "stale keys are served while the auth service is down")
+	assert.NotNil(t, key)
+}
+
+func TestKeySet_RefreshRateLimited(t *testing.T) {
+	var hits, down int32
+	srv := newJWKSServer(t, &hits, &down)
+
+	ks := NewKeySet(srv.URL, time.Hour, loggingservice.LoggingService{})
+
+	for i := 0; i < 5; i++ {
+		_, err := ks.Key("unknown")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+}
+
+func TestKeySet_Check(t *testing.T) {
+	var hits, down int32
+	srv := newJWKSServer(t, &hits, &down)
+
+	ks := NewKeySet(srv.URL, time.Hour, loggingservice.LoggingService{})
+	ks.minInterval = 0
+
+	atomic.StoreInt32(&down, 1)
+	assert.Error(t, ks.Check(), "no keys while the auth service is down")
+
+	atomic.StoreInt32(&down, 0)
+	require.NoError(t, ks.Check())
+
+	atomic.StoreInt32(&down, 1)
+	assert.NoError(t, ks.Check(), "known keys keep the gateway ready")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+}
+
+func TestHTTPService_ParseToken(t *testing.T) {
+	var hits, down int32
+	srv := newJWKSServer(t, &hits, &down)
+
+	conf, err := jwtservice.NewJTWConfig()
+	require.NoError(t, err)
+	km, err := jwtservice.NewKeyManager(conf)
+	require.NoError(t, err)
+	token, _, err := km.CreateUserJWTToken(model.Identity{Name: "bob"}, "1")
+	require.NoError(t, err)
+
+	s := HTTPService{keys: NewKeySet(srv.URL, time.Hour, loggingservice.LoggingService{})}
+
+	caller, err := s.ParseToken("bearer " + token)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", caller.Name)
+
+	_, err = s.ParseToken("bearer " + token + "x")
+	assert.Error(t, err)
+
+	_, err = s.ParseToken(token)
+	assert.Error(t, err)
+}
@@ -1,41 +1,78 @@
 package httpservice
 
 import (
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/json"
-	"encoding/pem"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
 	jwtservice "github.com/stasBigunenko/monorepa/service/auth/jwt"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 )
 
 type HTTPService struct {
-	JwtServiceAddr string
+	keys    *KeySet
+	revoked *Denylist
+
+	authURL    string
+	adminToken string
+	client     *http.Client
+}
+
+// New verifies tokens with the keys of the auth service at jwtServiceAddr, the admin token
+// lets it disable the logins of deleted users there
+func New(jwtServiceAddr string, keySetTTL time.Duration, adminToken string, logger loggingservice.Logger) HTTPService {
+	logger = logger.With("component", "auth client")
+
+	return HTTPService{
+		keys:       NewKeySet("http://"+jwtServiceAddr+"/.well-known/jwks.json", keySetTTL, logger),
+		revoked:    NewDenylist("http://"+jwtServiceAddr+"/revoked", DefaultDenylistInterval, logger),
+		authURL:    "http://" + jwtServiceAddr,
+		adminToken: adminToken,
+		client:     &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// ParseToken verifies the bearer token and returns the caller it was issued for
+func (s HTTPService) ParseToken(tokenHeader string) (model.Identity, error) {
+	claims, err := s.parseToken(tokenHeader)
+	if err != nil {
+		return model.Identity{}, fmt.Errorf("%s: %w", err, customErrors.InvalidToken)
+	}
+
+	// tokens issued before the subject was set have no user id, they pass admin checks only
+	userID, _ := uuid.Parse(claims.Subject)
+
+	return model.Identity{Name: claims.Name, UserID: userID, Roles: claims.Roles}, nil
 }
 
-type tokenResp struct {
-	PbKey []byte `json:"publicKey"`
+// CheckKeys fails while no public keys of the auth service are known, no token can be verified then
+func (s HTTPService) CheckKeys() error {
+	return s.keys.Check()
 }
 
-func (s HTTPService) ParseToken(tokenHeader string) (string, error) {
+func (s HTTPService) parseToken(tokenHeader string) (*jwtservice.UserClaims, error) {
 	splitted := strings.Split(tokenHeader, " ")
 	if len(splitted) != 2 {
-		return "", fmt.Errorf("malformed auth token, could not split two parts")
+		return nil, fmt.Errorf("malformed auth token, could not split two parts")
 	}
 
 	if splitted[0] != "bearer" {
-		return "", fmt.Errorf("malformed auth token, the first part is not bearer")
+		return nil, fmt.Errorf("malformed auth token, the first part is not bearer")
 	}
 
 	tokenPart := splitted[1]
 
 	token, err := jwt.ParseWithClaims(tokenPart, &jwtservice.UserClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+
 		claims, ok := token.Claims.(*jwtservice.UserClaims)
 		if !ok {
 			return nil, fmt.Errorf("wrong format of claims")
@@ -46,43 +83,31 @@ func (s HTTPService) ParseToken(tokenHeader string) (string, error) {
 			return nil, fmt.Errorf("token expired")
 		}
 
-		resp, err := http.Get("http://" + s.JwtServiceAddr + "/get-cert/" + claims.KeyVersion)
-		if err != nil {
-			return nil, fmt.Errorf("failed to connect to jwt server: %w", err)
-		}
-		defer resp.Body.Close()
-
-		var publickeyJSON tokenResp
-		if err = json.NewDecoder(resp.Body).Decode(&publickeyJSON); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal public key: %w", err)
-		}
-
-		block, _ := pem.Decode(publickeyJSON.PbKey)
-		pubInterface, err := x509.ParsePKIXPublicKey(block.Bytes)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode public key: %w", err)
-		}
-
-		publicKeyForToken, ok := pubInterface.(*rsa.PublicKey)
+		// tokens issued before kid was set carry the key version in the claims
+		kid, ok := token.Header["kid"].(string)
 		if !ok {
-			return nil, fmt.Errorf("failed to convert public key: %w", err)
+			kid = claims.KeyVersion
 		}
 
-		return publicKeyForToken, nil
+		return s.keys.Key(kid)
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("failed to parse token: %w", err)
+		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 
 	if !token.Valid {
-		return "", fmt.Errorf("token is not valid: %w", err)
+		return nil, fmt.Errorf("token is not valid: %w", err)
 	}
 
 	claims, ok := token.Claims.(*jwtservice.UserClaims)
 	if !ok {
-		return "", fmt.Errorf("wrong format of claims")
+		return nil, fmt.Errorf("wrong format of claims")
+	}
+
+	if claims.Id != "" && s.revoked != nil && s.revoked.Revoked(claims.Id) {
+		return nil, fmt.Errorf("token revoked")
 	}
 
-	return claims.Name, nil
+	return claims, nil
 }
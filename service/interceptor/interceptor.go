@@ -0,0 +1,203 @@
+package interceptor
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/stasBigunenko/monorepa/model"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/metrics"
+)
+
+// calls without a deadline of their own get these, streams may run for long and get none
+const (
+	DefaultServerTimeout = 30 * time.Second
+	DefaultClientTimeout = 10 * time.Second
+)
+
+// ServerOptions chains the interceptors of the gRPC servers: tracing, metrics when m is set, the request
+// context, logging, recovery and the default deadline. Recovery runs inside logging and metrics so a panic counts as Internal.
+func ServerOptions(logger loggingservice.Logger, m *metrics.GRPCMetrics) []grpc.ServerOption {
+	unary := []grpc.UnaryServerInterceptor{otelgrpc.UnaryServerInterceptor()}
+	if m != nil {
+		unary = append(unary, m.UnaryServerInterceptor())
+	}
+	unary = append(unary,
+		UnaryServerContext(),
+		loggingservice.UnaryServerInterceptor(logger),
+		UnaryServerRecovery(logger),
+		UnaryServerDeadline(DefaultServerTimeout),
+	)
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(
+			otelgrpc.StreamServerInterceptor(),
+			StreamServerContext(),
+			loggingservice.StreamServerInterceptor(logger),
+			StreamServerRecovery(logger),
+		),
+	}
+}
+
+// ClientOptions chains the interceptors of the gRPC clients: tracing, the request context and the default deadline
+func ClientOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(otelgrpc.UnaryClientInterceptor(), UnaryClientContext(), UnaryClientDeadline(DefaultClientTimeout)),
+		grpc.WithChainStreamInterceptor(otelgrpc.StreamClientInterceptor(), StreamClientContext()),
+	}
+}
+
+// UnaryServerContext puts the request id and the caller the metadata carries into the context of the handler
+func UnaryServerContext() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(incomingContext(ctx), req)
+	}
+}
+
+func StreamServerContext() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, serverStream{ServerStream: ss, ctx: incomingContext(ss.Context())})
+	}
+}
+
+// UnaryServerRecovery turns a panic of the handler into codes.Internal, the server keeps running
+func UnaryServerRecovery(logger loggingservice.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ctx, logger, info.FullMethod, r)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerRecovery(logger loggingservice.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ss.Context(), logger, info.FullMethod, r)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+// UnaryServerDeadline bounds calls that came without a deadline
+func UnaryServerDeadline(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := withDefaultDeadline(ctx, timeout)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
+
+// UnaryClientContext passes the request id and the caller in the context on to the server
+func UnaryClientContext() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+func StreamClientContext() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+// UnaryClientDeadline bounds calls made without a deadline, a server that hangs does not hang the caller
+func UnaryClientDeadline(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := withDefaultDeadline(ctx, timeout)
+		defer cancel()
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func incomingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get(model.MetadataRequestID); len(ids) > 0 && ids[0] != "" {
+		ctx = context.WithValue(ctx, model.ContextKeyRequestID, ids[0])
+	}
+
+	return context.WithValue(ctx, model.IdentityKey, callerFromMetadata(md))
+}
+
+// callerFromMetadata reads who the request is made for, a request without a user id
+// is treated as an unprivileged caller owning nothing
+func callerFromMetadata(md metadata.MD) model.Identity {
+	// the name only tells who did it for the audit trail, it grants nothing
+	var name string
+	if names := md.Get(model.MetadataName); len(names) > 0 {
+		name = names[0]
+	}
+
+	ids := md.Get(model.MetadataUserID)
+	if len(ids) == 0 {
+		return model.Identity{Name: name}
+	}
+
+	id, err := uuid.Parse(ids[0])
+	if err != nil {
+		return model.Identity{Name: name}
+	}
+
+	return model.Identity{Name: name, UserID: id, Roles: md.Get(model.MetadataRoles)}
+}
+
+func outgoingContext(ctx context.Context) context.Context {
+	var kv []string
+	if id, ok := ctx.Value(model.ContextKeyRequestID).(string); ok && id != "" {
+		kv = append(kv, model.MetadataRequestID, id)
+	}
+
+	if caller, ok := ctx.Value(model.IdentityKey).(model.Identity); ok {
+		kv = append(kv, model.MetadataUserID, caller.UserID.String(), model.MetadataName, caller.Name)
+		for _, role := range caller.Roles {
+			kv = append(kv, model.MetadataRoles, role)
+		}
+	}
+
+	if len(kv) == 0 {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+func withDefaultDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+func recovered(ctx context.Context, logger loggingservice.Logger, method string, r interface{}) error {
+	logger.Error(ctx, "grpc handler panicked", "method", method, "panic", r, "stack", string(debug.Stack()))
+
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// serverStream replaces the context of the stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,143 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/stasBigunenko/monorepa/model"
+	pb "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+)
+
+// userServer keeps the context of the last call, Get of the nil id panics
+type userServer struct {
+	pb.UnimplementedUserGRPCServiceServer
+	got chan context.Context
+}
+
+func (s userServer) Get(c context.Context, in *pb.Id) (*pb.User, error) {
+	if in.Id == uuid.Nil.String() {
+		panic("nil id")
+	}
+	s.got <- c
+
+	return &pb.User{Id: in.Id}, nil
+}
+
+func newClient(t *testing.T) (pb.UserGRPCServiceClient, chan context.Context) {
+	got := make(chan context.Context, 1)
+	logger := loggingservice.LoggingService{}
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(ServerOptions(logger, nil)...)
+	pb.RegisterUserGRPCServiceServer(s, userServer{got: got})
+	go s.Serve(lis) //nolint:errcheck
+	t.Cleanup(s.Stop)
+
+	opts := append(ClientOptions(), grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }))
+	conn, err := grpc.DialContext(context.Background(), "bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewUserGRPCServiceClient(conn), got
+}
+
+func TestPassesRequestContext(t *testing.T) {
+	client, got := newClient(t)
+	caller := model.Identity{Name: "bob", UserID: uuid.New(), Roles: []string{model.RoleUser}}
+
+	ctx := context.WithValue(context.Background(), model.ContextKeyRequestID, "req-1")
+	ctx = context.WithValue(ctx, model.IdentityKey, caller)
+	_, err := client.Get(ctx, &pb.Id{Id: uuid.New().String()})
+	require.NoError(t, err)
+
+	c := <-got
+	assert.Equal(t, "req-1", c.Value(model.ContextKeyRequestID))
+	assert.Equal(t, caller, c.Value(model.IdentityKey))
+	_, ok := c.Deadline()
+	assert.True(t, ok, "calls without a deadline get the default one")
+
+	// a call without a caller is unprivileged
+	_, err = client.Get(context.Background(), &pb.Id{Id: uuid.New().String()})
+	require.NoError(t, err)
+
+	c = <-got
+	assert.Nil(t, c.Value(model.ContextKeyRequestID))
+	assert.Equal(t, model.Identity{}, c.Value(model.IdentityKey))
+}
+
+func TestRecovery(t *testing.T) {
+	client, got := newClient(t)
+
+	_, err := client.Get(context.Background(), &pb.Id{Id: uuid.Nil.String()})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = client.Get(context.Background(), &pb.Id{Id: uuid.New().String()})
+	assert.NoError(t, err, "the server keeps serving after a panic")
+	<-got
+}
+
+func TestUnaryServerDeadline(t *testing.T) {
+	interceptor := UnaryServerDeadline(time.Minute)
+	deadline := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		d, _ := ctx.Deadline()
+		return d, nil
+	}
+
+	got, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, deadline)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), got.(time.Time), time.Second)
+
+	want := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), want)
+	defer cancel()
+	got, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, deadline)
+	require.NoError(t, err)
+	assert.Equal(t, want, got, "the deadline of the caller is kept")
+}
+
+// stream is a server stream with a context only
+type stream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s stream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServer(t *testing.T) {
+	userID := uuid.New()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		model.MetadataRequestID, "req-1",
+		model.MetadataUserID, userID.String(),
+		model.MetadataRoles, model.RoleAdmin,
+	))
+	info := &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}
+
+	var got context.Context
+	err := StreamServerContext()(nil, stream{ctx: ctx}, info, func(_ interface{}, ss grpc.ServerStream) error {
+		got = ss.Context()
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", got.Value(model.ContextKeyRequestID))
+	assert.Equal(t, model.Identity{UserID: userID, Roles: []string{model.RoleAdmin}}, got.Value(model.IdentityKey))
+
+	err = StreamServerRecovery(loggingservice.LoggingService{})(nil, stream{ctx: ctx}, info, func(interface{}, grpc.ServerStream) error {
+		panic("broken stream")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
@@ -0,0 +1,47 @@
+package loggingservice
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor logs every call of a gRPC server with its method, status code and latency.
+// Failures of the server are logged as errors, refused calls as warnings. It runs after the
+// interceptor that reads the request id and the caller from the metadata, so the lines carry them.
+func UnaryServerInterceptor(logger Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs every stream once it ends
+func StreamServerInterceptor(logger Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), logger, info.FullMethod, start, err)
+
+		return err
+	}
+}
+
+func logCall(ctx context.Context, logger Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	keyvals := []interface{}{"method", method, "code", code.String(), "latency", time.Since(start).String()}
+	switch code {
+	case codes.OK:
+		logger.Info(ctx, "grpc call", keyvals...)
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unavailable, codes.DeadlineExceeded:
+		logger.Error(ctx, "grpc call failed", append(keyvals, "error", err)...)
+	default:
+		logger.Warn(ctx, "grpc call refused", append(keyvals, "error", err)...)
+	}
+}
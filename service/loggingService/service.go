@@ -1,26 +1,134 @@
 package loggingservice
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/net/context"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/stasBigunenko/monorepa/model"
 )
 
+// output formats of LOG_FORMAT
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Logger writes leveled log lines with key/value fields, Info(ctx, "account created", "account_id", id).
+// The request id, the caller and the trace found in the context are added to every line as request_id, user and trace_id.
+type Logger interface {
+	Debug(ctx context.Context, msg string, keyvals ...interface{})
+	Info(ctx context.Context, msg string, keyvals ...interface{})
+	Warn(ctx context.Context, msg string, keyvals ...interface{})
+	Error(ctx context.Context, msg string, keyvals ...interface{})
+	// With returns a logger adding the fields to every line
+	With(keyvals ...interface{}) Logger
+}
+
+type Config struct {
+	// Service names the binary in every line
+	Service string
+	// Level is debug, info, warn or error
+	Level string
+	// Format is json or text
+	Format string
+	Output io.Writer
+}
+
+// LoggingService is a Logger on top of logrus, the zero value writes nothing
 type LoggingService struct {
+	entry *log.Entry
+}
+
+// New builds a logrus logger of its own, the standard one is left alone.
+// An empty or malformed level keeps info and an unknown format keeps json.
+func New(cfg Config) LoggingService {
+	logger := log.New()
+
+	level, err := log.ParseLevel(cfg.Level)
+	if err != nil {
+		level = log.InfoLevel
+	}
+	logger.SetLevel(level)
+
+	if cfg.Format == FormatText {
+		logger.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	} else {
+		logger.SetFormatter(&log.JSONFormatter{})
+	}
+
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	logger.SetOutput(cfg.Output)
+
+	entry := log.NewEntry(logger)
+	if cfg.Service != "" {
+		entry = entry.WithField("service", cfg.Service)
+	}
+
+	return LoggingService{entry: entry}
+}
+
+func (l LoggingService) Debug(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.log(ctx, log.DebugLevel, msg, keyvals)
+}
+
+func (l LoggingService) Info(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.log(ctx, log.InfoLevel, msg, keyvals)
+}
+
+func (l LoggingService) Warn(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.log(ctx, log.WarnLevel, msg, keyvals)
+}
+
+func (l LoggingService) Error(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.log(ctx, log.ErrorLevel, msg, keyvals)
+}
+
+func (l LoggingService) With(keyvals ...interface{}) Logger {
+	if l.entry == nil {
+		return l
+	}
+
+	return LoggingService{entry: l.entry.WithFields(fields(keyvals))}
 }
 
-func New() LoggingService {
-	return LoggingService{}
+func (l LoggingService) log(ctx context.Context, level log.Level, msg string, keyvals []interface{}) {
+	if l.entry == nil || !l.entry.Logger.IsLevelEnabled(level) {
+		return
+	}
+
+	f := fields(keyvals)
+	if id, ok := ctx.Value(model.ContextKeyRequestID).(string); ok && id != "" {
+		f["request_id"] = id
+	}
+	if caller, ok := ctx.Value(model.IdentityKey).(model.Identity); ok && caller.Name != "" {
+		f["user"] = caller.Name
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		f["trace_id"] = span.TraceID().String()
+	}
+
+	l.entry.WithFields(f).Log(level, msg)
 }
 
-func (h LoggingService) WriteLog(ctx context.Context, message string) {
-	id, ok := ctx.Value(model.ContextKeyRequestID).(string)
-	if !ok {
-		log.Info("failed to convert context value and get context id")
+// fields pairs up keys and values, a key without a value is kept with an empty one
+func fields(keyvals []interface{}) log.Fields {
+	f := make(log.Fields, len(keyvals)/2+2)
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		if i+1 == len(keyvals) {
+			f[key] = ""
+			break
+		}
+
+		f[key] = keyvals[i+1]
 	}
 
-	log.Info(fmt.Sprintf("Request ID: %s; Message: %s", id, message))
+	return f
 }
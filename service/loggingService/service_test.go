@@ -0,0 +1,45 @@
+package loggingservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+func TestLoggingService(t *testing.T) {
+	var buf bytes.Buffer
+	standardLevel := log.GetLevel()
+	logger := New(Config{Service: "account", Level: "warn", Format: FormatJSON, Output: &buf})
+	assert.Equal(t, standardLevel, log.GetLevel(), "the standard logger is left alone")
+
+	c := context.WithValue(context.Background(), model.ContextKeyRequestID, "bob_1")
+	c = context.WithValue(c, model.IdentityKey, model.Identity{Name: "bob"})
+
+	logger.Info(c, "below the level")
+	assert.Zero(t, buf.Len())
+
+	logger.With("component", "storage").Error(c, "failed", "method", "GetAccount", "error", errors.New("boom"), "odd")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "error", line["level"])
+	assert.Equal(t, "failed", line["msg"])
+	assert.Equal(t, "account", line["service"])
+	assert.Equal(t, "storage", line["component"])
+	assert.Equal(t, "bob_1", line["request_id"])
+	assert.Equal(t, "bob", line["user"])
+	assert.Equal(t, "GetAccount", line["method"])
+	assert.Equal(t, "boom", line["error"])
+	assert.Equal(t, "", line["odd"])
+
+	// the zero value is a logger that writes nothing
+	LoggingService{}.With("a", 1).Error(c, "nowhere")
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// scrape gets the metrics the way Prometheus does
+func scrape(t *testing.T, g prometheus.Gatherer) string {
+	rec := httptest.NewRecorder()
+	Handler(g).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestHTTPMetrics_Middleware(t *testing.T) {
+	reg := NewRegistry()
+	router := mux.NewRouter()
+	router.Use(NewHTTPMetrics(reg).Middleware)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		if mux.Vars(req)["id"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}).Methods("GET")
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	got := scrape(t, reg)
+	assert.Contains(t, got, `http_server_requests_total{code="200",method="GET",route="/users/{id}"} 2`)
+	assert.Contains(t, got, `http_server_requests_total{code="404",method="GET",route="/users/{id}"} 1`)
+	assert.Contains(t, got, `http_server_request_duration_seconds_count{method="GET",route="/users/{id}"} 3`)
+	assert.Contains(t, got, "go_goroutines")
+}
+
+func TestGRPCMetrics_UnaryServerInterceptor(t *testing.T) {
+	reg := NewRegistry()
+	interceptor := NewGRPCMetrics(reg).UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserGRPCService/GetUser"}
+
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	notFound := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+
+	resp, err := interceptor(context.Background(), nil, info, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	_, err = interceptor(context.Background(), nil, info, notFound)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	got := scrape(t, reg)
+	assert.Contains(t, got, `grpc_server_handled_total{code="OK",method="/user.UserGRPCService/GetUser"} 1`)
+	assert.Contains(t, got, `grpc_server_handled_total{code="NotFound",method="/user.UserGRPCService/GetUser"} 1`)
+	assert.Contains(t, got, `grpc_server_handling_seconds_count{method="/user.UserGRPCService/GetUser"} 2`)
+}
+
+func TestRegisterStore(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "store_calls_total", Help: "Calls."})
+	counter.Inc()
+
+	RegisterStore(reg, "users", counter)
+	// stores without metrics are skipped
+	RegisterStore(reg, "audit", struct{}{})
+
+	assert.Contains(t, scrape(t, reg), `store_calls_total{store="users"} 1`)
+}
@@ -0,0 +1,91 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+// ContentType of the error responses, RFC 7807
+const ContentType = "application/problem+json"
+
+// TypePrefix starts the type of every problem, the rest is the reason of the error in kebab case,
+// urn:monorepa:problem:insufficient-funds. Problems of errors with no reason are about:blank.
+const TypePrefix = "urn:monorepa:problem:"
+
+// the detail of server errors, the error itself only goes to the log
+var serverDetail = map[int]string{
+	http.StatusServiceUnavailable: "a service the request needs is unavailable, try again later",
+	http.StatusGatewayTimeout:     "a service the request needs did not answer in time, try again later",
+}
+
+// Problem is the body of every error response
+type Problem struct {
+	Type      string                    `json:"type"`
+	Title     string                    `json:"title"`
+	Status    int                       `json:"status"`
+	Detail    string                    `json:"detail,omitempty"`
+	Instance  string                    `json:"instance,omitempty"`
+	RequestID string                    `json:"request_id,omitempty"`
+	Errors    []customErrors.FieldError `json:"errors,omitempty"`
+}
+
+// New describes err answered with status to req. The detail of client errors is the error,
+// server errors get a generic one so nothing of the internals leaks.
+func New(req *http.Request, status int, err error) Problem {
+	p := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: req.URL.Path,
+	}
+	p.RequestID, _ = req.Context().Value(model.ContextKeyRequestID).(string)
+
+	if reason := customErrors.ReasonOf(err); reason != "" {
+		p.Type = TypePrefix + strings.ToLower(strings.ReplaceAll(reason, "_", "-"))
+	}
+
+	if status >= http.StatusInternalServerError {
+		p.Detail = serverDetail[status]
+		if p.Detail == "" {
+			p.Detail = "the request could not be completed"
+		}
+		return p
+	}
+
+	if err != nil {
+		p.Detail = err.Error()
+	}
+
+	var fields customErrors.FieldErrors
+	if errors.As(err, &fields) {
+		p.Errors = fields
+	}
+
+	return p
+}
+
+// Write sends the problem as the response
+func Write(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p) //nolint:errcheck
+}
+
+// Error answers req with the problem of err
+func Error(w http.ResponseWriter, req *http.Request, status int, err error) {
+	Write(w, New(req, status, err))
+}
+
+// NotFound and MethodNotAllowed answer requests no route matches
+func NotFound(w http.ResponseWriter, req *http.Request) {
+	Error(w, req, http.StatusNotFound, nil)
+}
+
+func MethodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	Error(w, req, http.StatusMethodNotAllowed, nil)
+}
@@ -0,0 +1,62 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stasBigunenko/monorepa/customErrors"
+	"github.com/stasBigunenko/monorepa/model"
+)
+
+func TestNew(t *testing.T) {
+	req := httptest.NewRequest("POST", "/transfers?dry=1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), model.ContextKeyRequestID, "req-1"))
+
+	p := New(req, http.StatusUnprocessableEntity, fmt.Errorf("failed to transfer: %w", customErrors.InsufficientFunds))
+	assert.Equal(t, Problem{
+		Type:      TypePrefix + "insufficient-funds",
+		Title:     "Unprocessable Entity",
+		Status:    http.StatusUnprocessableEntity,
+		Detail:    "failed to transfer: insufficient funds",
+		Instance:  "/transfers",
+		RequestID: "req-1",
+	}, p)
+
+	p = New(req, http.StatusBadRequest, customErrors.Field("amount", "must be positive"))
+	assert.Equal(t, TypePrefix+"invalid-argument", p.Type)
+	assert.Equal(t, []customErrors.FieldError{{Field: "amount", Message: "must be positive"}}, p.Errors)
+
+	p = New(req, http.StatusInternalServerError, errors.New("dial tcp 10.0.0.7:5432: connection refused"))
+	assert.Equal(t, "about:blank", p.Type)
+	assert.Equal(t, "the request could not be completed", p.Detail)
+	assert.Equal(t, "req-1", p.RequestID)
+
+	p = New(req, http.StatusGatewayTimeout, fmt.Errorf("GetAccount: %s: %w", "context deadline exceeded at 10.0.0.8", customErrors.DeadlineExceeded))
+	assert.Equal(t, TypePrefix+"deadline-exceeded", p.Type)
+	assert.NotContains(t, p.Detail, "10.0.0.8")
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NotFound(rec, httptest.NewRequest("GET", "/nowhere", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, map[string]interface{}{
+		"type":     "about:blank",
+		"title":    "Not Found",
+		"status":   float64(http.StatusNotFound),
+		"instance": "/nowhere",
+	}, body)
+}
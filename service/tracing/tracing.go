@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// exporters of OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+type Config struct {
+	// Service is the service.name of the spans
+	Service string
+	// Exporter is otlp, stdout or none
+	Exporter string
+	// Output is where the stdout exporter writes, os.Stdout by default
+	Output io.Writer
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator, the propagator
+// is installed without an exporter too so that the trace of a caller passes through.
+// The otlp exporter takes its endpoint and options from the OTEL_EXPORTER_OTLP_* variables.
+// The returned function flushes the spans left and stops the exporter.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var opts []sdktrace.TracerProviderOption
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err := otlptracegrpc.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	case ExporterStdout:
+		if cfg.Output == nil {
+			cfg.Output = os.Stdout
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(cfg.Output))
+		if err != nil {
+			return nil, err
+		}
+		// spans show up as soon as they end, which is what tests and local runs want
+		opts = append(opts, sdktrace.WithSyncer(exporter))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(append(opts,
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(cfg.Service))))...)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	usercontroller "github.com/stasBigunenko/monorepa/pkg/userGRPC/controller"
+	pb "github.com/stasBigunenko/monorepa/pkg/userGRPC/proto"
+	usergrpcserver "github.com/stasBigunenko/monorepa/pkg/userGRPC/server"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
+	"github.com/stasBigunenko/monorepa/service/user"
+)
+
+// exportedSpan is the part of a span the stdout exporter writes that the test looks at
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		SpanID string
+	}
+}
+
+func TestSetup_TraceCrossesGRPC(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{Service: "test", Exporter: ExporterStdout, Output: &out})
+	require.NoError(t, err)
+	defer shutdown(context.Background()) //nolint:errcheck
+
+	logger := loggingservice.LoggingService{}
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()))
+	pb.RegisterUserGRPCServiceServer(s, usergrpcserver.NewUsersGRPCServer(user.NewUsrService(newStorage.NewDB(logger), logger), logger))
+	go s.Serve(lis) //nolint:errcheck
+	defer s.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	_, err = usercontroller.New(pb.NewUserGRPCServiceClient(conn), logger).CreateUser(ctx, "bob")
+	root.End()
+	require.NoError(t, err)
+
+	spans := map[string]exportedSpan{}
+	dec := json.NewDecoder(&out)
+	for {
+		var span exportedSpan
+		if err := dec.Decode(&span); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		spans[span.Name] = span
+	}
+
+	rootSpan := spans["root"]
+	client := spans["userGRPC.UserGRPCService/Create"]
+	storage := spans["StorageDB.CreateUser"]
+	require.NotEmpty(t, rootSpan.SpanContext.TraceID)
+	require.NotEmpty(t, storage.SpanContext.SpanID, "spans: %v", spans)
+
+	assert.Equal(t, rootSpan.SpanContext.TraceID, client.SpanContext.TraceID, "the client span continues the trace")
+	assert.Equal(t, rootSpan.SpanContext.TraceID, storage.SpanContext.TraceID, "the trace crosses the grpc call")
+	assert.NotEqual(t, client.SpanContext.SpanID, storage.Parent.SpanID, "the storage span belongs to the server span")
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "zipkin"})
+	assert.Error(t, err)
+}
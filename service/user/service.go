@@ -3,101 +3,167 @@ package user
 import (
 	"context"
 	"errors"
+
 	"github.com/google/uuid"
 
+	"github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/model"
 	"github.com/stasBigunenko/monorepa/pkg/storage/newStorage"
+	"github.com/stasBigunenko/monorepa/service/audit"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 )
 
-type LoggingService interface {
-	WriteLog(ctx context.Context, message string)
-}
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
 
 type UsrService struct {
-	storage        newStorage.NewStore
-	loggingService LoggingService
+	storage newStorage.UserRepository
+	logger  loggingservice.Logger
+	audit   *audit.Recorder
 }
 
-func NewUsrService(s newStorage.NewStore, loggingService LoggingService) *UsrService {
-	return &UsrService{
-		storage:        s,
-		loggingService: loggingService,
+type Option func(*UsrService)
+
+// WithAudit records every change of a user in the audit trail
+func WithAudit(recorder *audit.Recorder) Option {
+	return func(u *UsrService) {
+		u.audit = recorder
 	}
 }
 
-func (u *UsrService) Get(c context.Context, id uuid.UUID) (model.UserHTTP, error) {
-	u.loggingService.WriteLog(c, "User service: Command Get received...")
-
-	val, err := u.storage.Get(c, id)
-	if err != nil {
-		return model.UserHTTP{}, err
+func NewUsrService(s newStorage.UserRepository, logger loggingservice.Logger, opts ...Option) *UsrService {
+	u := &UsrService{
+		storage: s,
+		logger:  logger.With("component", "user service"),
 	}
-
-	res, ok := val.(model.UserHTTP)
-	if !ok {
-		return model.UserHTTP{}, err
+	for _, opt := range opts {
+		opt(u)
 	}
 
-	return res, nil
+	return u
 }
 
-func (u *UsrService) GetAll(c context.Context) ([]model.UserHTTP, error) {
-	u.loggingService.WriteLog(c, "User service: Command GetAll received...")
+func (u *UsrService) Get(c context.Context, id uuid.UUID) (model.UserHTTP, error) {
+	u.logger.Debug(c, "command received", "method", "Get")
 
-	val, err := u.storage.GetAll(c)
-	if err != nil {
-		return nil, errors.New("not found")
+	return u.storage.GetUser(c, id)
+}
+
+// List returns a page of users, by default ordered by id
+func (u *UsrService) List(c context.Context, filter model.UserFilter) (model.UserPage, error) {
+	u.logger.Debug(c, "command received", "method", "List")
+
+	switch {
+	case filter.PageSize < 0:
+		return model.UserPage{}, customErrors.InvalidArgument
+	case filter.PageSize == 0:
+		filter.PageSize = defaultPageSize
+	case filter.PageSize > maxPageSize:
+		filter.PageSize = maxPageSize
 	}
 
-	res, ok := val.([]model.UserHTTP)
-	if !ok {
-		return []model.UserHTTP{}, err
+	if filter.Sort == "" {
+		filter.Sort = model.SortByID
+	}
+	if field, _ := model.ParseSort(filter.Sort); field != model.SortByID && field != model.SortByName {
+		return model.UserPage{}, customErrors.InvalidArgument
 	}
 
-	return res, nil
+	return u.storage.ListUsers(c, filter)
 }
 
 func (u *UsrService) Create(c context.Context, name string) (model.UserHTTP, error) {
-	u.loggingService.WriteLog(c, "User service: Command Create received...")
+	u.logger.Debug(c, "command received", "method", "Create")
 
 	m := model.UserHTTP{ID: uuid.Nil, Name: name}
 
-	val, err := u.storage.Create(c, m)
+	res, err := u.storage.CreateUser(c, m)
 	if err != nil {
 		return model.UserHTTP{}, err
 	}
-
-	res, ok := val.(model.UserHTTP)
-	if !ok {
-		return model.UserHTTP{}, errors.New("invalid data")
-	}
+	u.audit.Record(c, model.AuditCreate, res.ID, nil, res)
 
 	return res, nil
 }
 
 func (u *UsrService) Update(c context.Context, user model.UserHTTP) (model.UserHTTP, error) {
-	u.loggingService.WriteLog(c, "User service: Command Update received...")
+	u.logger.Debug(c, "command received", "method", "Update")
 
-	val, err := u.storage.Update(c, user)
+	before, err := u.auditedUser(c, user.ID)
 	if err != nil {
 		return model.UserHTTP{}, err
 	}
 
-	res, ok := val.(model.UserHTTP)
-	if !ok {
-		return model.UserHTTP{}, errors.New("invalid data")
+	res, err := u.storage.UpdateUser(c, user)
+	if err != nil {
+		return model.UserHTTP{}, err
 	}
+	u.audit.Record(c, model.AuditUpdate, res.ID, before, res)
 
 	return res, nil
 }
 
 func (u *UsrService) Delete(c context.Context, id uuid.UUID) error {
-	u.loggingService.WriteLog(c, "User service: Command Delete received...")
+	u.logger.Debug(c, "command received", "method", "Delete")
+
+	// soft deleted users are removed for good as well, they are gone already
+	before, err := u.auditedUser(c, id)
+	if err != nil && !errors.Is(err, customErrors.NotFound) {
+		return err
+	}
+
+	err = u.storage.DeleteUser(c, id)
+	if err != nil {
+		return err
+	}
+	u.audit.Record(c, model.AuditDelete, id, before, nil)
+
+	return nil
+}
+
+// SoftDelete hides the user, Restore brings it back
+func (u *UsrService) SoftDelete(c context.Context, id uuid.UUID) error {
+	u.logger.Debug(c, "command received", "method", "SoftDelete")
 
-	err := u.storage.Delete(c, id)
+	before, err := u.auditedUser(c, id)
 	if err != nil {
 		return err
 	}
 
+	if err = u.storage.SoftDeleteUser(c, id); err != nil {
+		return err
+	}
+	u.audit.Record(c, model.AuditSoftDelete, id, before, nil)
+
 	return nil
 }
+
+func (u *UsrService) Restore(c context.Context, id uuid.UUID) error {
+	u.logger.Debug(c, "command received", "method", "Restore")
+
+	if err := u.storage.RestoreUser(c, id); err != nil {
+		return err
+	}
+
+	// the user is back already, a failed read only leaves the entry without it
+	after, _ := u.auditedUser(c, id) //nolint:errcheck
+	u.audit.Record(c, model.AuditRestore, id, nil, after)
+
+	return nil
+}
+
+// auditedUser is the user around a change, it is only read when changes are audited
+func (u *UsrService) auditedUser(c context.Context, id uuid.UUID) (interface{}, error) {
+	if u.audit == nil {
+		return nil, nil
+	}
+
+	user, err := u.storage.GetUser(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -8,8 +8,10 @@ import (
 
 type User interface {
 	Get(context.Context, uuid.UUID) (model.UserHTTP, error)
-	GetAll(context.Context) ([]model.UserHTTP, error)
+	List(context.Context, model.UserFilter) (model.UserPage, error)
 	Create(context.Context, string) (model.UserHTTP, error)
 	Update(context.Context, model.UserHTTP) (model.UserHTTP, error)
 	Delete(context.Context, uuid.UUID) error
+	SoftDelete(context.Context, uuid.UUID) error
+	Restore(context.Context, uuid.UUID) error
 }
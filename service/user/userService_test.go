@@ -10,29 +10,26 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stasBigunenko/monorepa/mocks/pkg/storage/mockNewStore"
+	"github.com/stasBigunenko/monorepa/customErrors"
 	"github.com/stasBigunenko/monorepa/model"
+	loggingservice "github.com/stasBigunenko/monorepa/service/loggingService"
 )
 
-type MockLoggingService struct {
-}
-
-func (s MockLoggingService) WriteLog(ctx context.Context, message string) {}
-
 func Test_Create(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.UserRepository)
 	id := uuid.New()
 	mm := model.UserHTTP{Name: "Andrew"}
 	m := model.UserHTTP{ID: id, Name: "Andrew"}
-	ui.On("Create", mock.Anything, mm).Return(m, nil)
+	ui.On("CreateUser", mock.Anything, mm).Return(m, nil)
 
-	ui2 := new(mockNewStore.NewStore)
-	ui2.On("Create", mock.Anything, mock.Anything).Return(nil, errors.New("invalid data"))
+	ui2 := new(mockNewStore.UserRepository)
+	ui2.On("CreateUser", mock.Anything, mock.Anything).Return(model.UserHTTP{}, errors.New("invalid data"))
 
 	tests := []struct {
 		name    string
 		param   string
-		stor    *mockNewStore.NewStore
+		stor    *mockNewStore.UserRepository
 		want    model.UserHTTP
 		wantErr string
 	}{
@@ -64,18 +61,18 @@ func Test_Create(t *testing.T) {
 }
 
 func Test_Get(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.UserRepository)
 	id := uuid.New()
 	m := model.UserHTTP{ID: id, Name: "Andrew"}
-	ui.On("Get", context.Background(), id).Return(m, nil)
+	ui.On("GetUser", context.Background(), id).Return(m, nil)
 
-	ui2 := new(mockNewStore.NewStore)
-	ui2.On("Get", mock.Anything, mock.Anything).Return(nil, errors.New("not found"))
+	ui2 := new(mockNewStore.UserRepository)
+	ui2.On("GetUser", mock.Anything, mock.Anything).Return(model.UserHTTP{}, errors.New("not found"))
 
 	tests := []struct {
 		name    string
-		stor    *mockNewStore.NewStore
+		stor    *mockNewStore.UserRepository
 		want    model.UserHTTP
 		wantErr string
 	}{
@@ -105,17 +102,17 @@ func Test_Get(t *testing.T) {
 }
 
 func TestUserService_Delete(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.UserRepository)
 	id := uuid.New()
-	ui.On("Delete", context.Background(), id).Return(nil)
+	ui.On("DeleteUser", context.Background(), id).Return(nil)
 
-	ui2 := new(mockNewStore.NewStore)
-	ui2.On("Delete", mock.Anything, mock.Anything).Return(errors.New("not found"))
+	ui2 := new(mockNewStore.UserRepository)
+	ui2.On("DeleteUser", mock.Anything, mock.Anything).Return(errors.New("not found"))
 
 	tests := []struct {
 		name    string
-		stor    *mockNewStore.NewStore
+		stor    *mockNewStore.UserRepository
 		param   uuid.UUID
 		result  []byte
 		wantErr string
@@ -145,55 +142,73 @@ func TestUserService_Delete(t *testing.T) {
 }
 
 //
-func TestUserService_GetAll(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+func TestUserService_List(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.UserRepository)
 	m1 := model.UserHTTP{ID: uuid.New(), Name: "Andrew"}
 	m2 := model.UserHTTP{ID: uuid.New(), Name: "Ivan"}
-	m := []model.UserHTTP{
-		m1,
-		m2,
-	}
-	ui.On("GetAll", context.Background()).Return(m, nil)
+	m := model.UserPage{Users: []model.UserHTTP{m1, m2}}
+	ui.On("ListUsers", context.Background(), model.UserFilter{Sort: model.SortByID, PageSize: 50}).Return(m, nil)
+	ui.On("ListUsers", context.Background(), model.UserFilter{Sort: "-name", PageSize: 500}).Return(m, nil)
 
 	tests := []struct {
 		name    string
-		stor    *mockNewStore.NewStore
-		want    []model.UserHTTP
-		wantErr string
+		stor    *mockNewStore.UserRepository
+		filter  model.UserFilter
+		want    model.UserPage
+		wantErr error
 	}{
 		{
-			name: "Everything ok",
+			name: "Defaults",
 			stor: ui,
 			want: m,
 		},
+		{
+			name:   "Page size capped",
+			stor:   ui,
+			filter: model.UserFilter{Sort: "-name", PageSize: 10000},
+			want:   m,
+		},
+		{
+			name:    "Unknown sort",
+			stor:    ui,
+			filter:  model.UserFilter{Sort: "balance"},
+			wantErr: customErrors.InvalidArgument,
+		},
+		{
+			name:    "Negative page size",
+			stor:    ui,
+			filter:  model.UserFilter{PageSize: -1},
+			wantErr: customErrors.InvalidArgument,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			u := NewUsrService(tc.stor, loggingService)
-			got, err := u.GetAll(context.Background())
-			if (err != nil) && err.Error() != tc.wantErr {
-				t.Errorf("error = %v, wantErr %v", err.Error(), tc.wantErr)
+			got, err := u.List(context.Background(), tc.filter)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
 				return
 			}
+			assert.NoError(t, err)
 			assert.Equal(t, tc.want, got)
 		})
 	}
 }
 
 func TestUserService_Update(t *testing.T) {
-	loggingService := MockLoggingService{}
-	ui := new(mockNewStore.NewStore)
+	loggingService := loggingservice.LoggingService{}
+	ui := new(mockNewStore.UserRepository)
 	id := uuid.New()
 	m := model.UserHTTP{ID: id, Name: "Abdula"}
-	ui.On("Update", context.Background(), m).Return(m, nil)
+	ui.On("UpdateUser", context.Background(), m).Return(m, nil)
 
-	ui2 := new(mockNewStore.NewStore)
-	ui2.On("Update", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("not found in DB"))
+	ui2 := new(mockNewStore.UserRepository)
+	ui2.On("UpdateUser", mock.Anything, mock.Anything).Return(model.UserHTTP{}, errors.New("not found in DB"))
 
 	tests := []struct {
 		name    string
-		stor    *mockNewStore.NewStore
+		stor    *mockNewStore.UserRepository
 		param   model.UserHTTP
 		want    model.UserHTTP
 		wantErr string
@@ -224,3 +239,18 @@ func TestUserService_Update(t *testing.T) {
 		})
 	}
 }
+
+func TestUserService_SoftDelete(t *testing.T) {
+	loggingService := loggingservice.LoggingService{}
+	id := uuid.New()
+
+	ui := new(mockNewStore.UserRepository)
+	ui.On("SoftDeleteUser", context.Background(), id).Return(nil)
+	ui.On("RestoreUser", context.Background(), id).Return(customErrors.NotFound)
+
+	u := NewUsrService(ui, loggingService)
+
+	assert.NoError(t, u.SoftDelete(context.Background(), id))
+	assert.ErrorIs(t, u.Restore(context.Background(), id), customErrors.NotFound)
+	ui.AssertExpectations(t)
+}